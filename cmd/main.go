@@ -1,22 +1,64 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 
 	"sql-optimizer/internal/api"
+	"sql-optimizer/internal/cli"
+	"sql-optimizer/internal/history"
+	"sql-optimizer/internal/monitor"
 )
 
 func main() {
+	// С аргументами работаем как утилита командной строки
+	if len(os.Args) > 1 {
+		os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
+	}
+
 	fmt.Println("SQL Optimizer - Web-сервер запущен")
 	fmt.Println("===================================")
 
-	handler := api.NewHandler()
+	historyDir := os.Getenv("HISTORY_DIR")
+	if historyDir == "" {
+		historyDir = "./data/history"
+	}
+	store, err := history.NewFileStore(historyDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	handler := api.NewHandler(store)
+
+	// Регулярные проверки сохраненных запросов включаются файлом настроек
+	if path := os.Getenv("MONITOR_CONFIG"); path != "" {
+		config, err := monitor.LoadConfig(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go monitor.New(config, store).Run(context.Background())
+	}
 
 	http.HandleFunc("/api/connect", handler.ConnectDB)
 	http.HandleFunc("/api/analyze", handler.AnalyzeQuery)
+	http.HandleFunc("/api/report/html", handler.DownloadHTMLReport)
+	http.HandleFunc("/api/plan/graph", handler.RenderPlanGraph)
+	http.HandleFunc("/api/plan/flamegraph", handler.RenderFlameGraph)
+	http.HandleFunc("/api/plan/timeline", handler.RenderTimeline)
+	http.HandleFunc("/api/plan/experiment", handler.ExperimentPlan)
+	http.HandleFunc("/api/plan/hints", handler.GeneratePlanHints)
+	http.HandleFunc("/api/history", handler.ListHistory)
+	http.HandleFunc("/api/history/", handler.HistoryRecord)
+	http.HandleFunc("/api/trend", handler.QueryTrend)
+	http.HandleFunc("/api/workload", handler.AnalyzeWorkload)
+	http.HandleFunc("/api/indexes/check", handler.CheckIndexes)
+	http.HandleFunc("/api/foreign-keys/check", handler.CheckForeignKeys)
+	http.HandleFunc("/api/health", handler.CheckHealth)
+	http.HandleFunc("/api/tuning", handler.TuneSettings)
+	http.HandleFunc("/api/statistics", handler.AdviseStatistics)
 
 	fs := http.FileServer(http.Dir("./web"))
 	http.Handle("/", fs)
@@ -0,0 +1,93 @@
+// Package analysis дополняет анализ плана сведениями из базы и готовит
+// результат к выводу. Цепочка одна для API и CLI: внешние ключи без индекса,
+// свежесть статистики, карта видимости, уточнение индексов по pg_stats,
+// тексты на нужном языке, отпечатки и пересказ плана.
+package analysis
+
+import (
+	"context"
+	"fmt"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/catalog"
+	"sql-optimizer/internal/fingerprint"
+	"sql-optimizer/internal/statistics"
+)
+
+// Source — база, из которой дополняется анализ; его реализует
+// *postgres.Client
+type Source interface {
+	catalog.ForeignKeySource
+	catalog.VisibilitySource
+	statistics.StaleSource
+	statistics.ColumnSource
+}
+
+// Options — параметры дополнения анализа
+type Options struct {
+	// Locale — язык находок и пересказа плана
+	Locale string
+	// ForeignKeys — внешние ключи без индекса, общие для нескольких
+	// запросов к одной базе; nil — ключи читаются для каждого плана
+	ForeignKeys *ForeignKeyCache
+}
+
+// ForeignKeyCache хранит внешние ключи без индекса, прочитанные один раз
+type ForeignKeyCache struct {
+	report *catalog.ForeignKeyReport
+}
+
+// load читает внешние ключи при первом обращении. Ошибка возвращается
+// один раз, дальше ключи считаются проверенными.
+func (c *ForeignKeyCache) load(ctx context.Context, src catalog.ForeignKeySource) (*catalog.ForeignKeyReport, error) {
+	if c.report != nil {
+		return c.report, nil
+	}
+	report, err := catalog.LoadForeignKeyReport(ctx, src)
+	if err != nil {
+		c.report = &catalog.ForeignKeyReport{}
+		return nil, err
+	}
+	c.report = report
+	return report, nil
+}
+
+// Enrich дополняет результат анализа плана запроса query находками, для
+// которых нужна база, и формирует тексты на языке opts.Locale, отпечатки
+// и пересказ плана. Проверки по базе необязательны: их ошибки не прерывают
+// Enrich, а возвращаются, чтобы вызывающий их показал.
+func Enrich(ctx context.Context, src Source, query string, plan []analyzer.PlanNode, result *analyzer.AnalysisResult, opts Options) []error {
+	var errs []error
+
+	if catalog.NeedsForeignKeys(plan) {
+		cache := opts.ForeignKeys
+		if cache == nil {
+			cache = &ForeignKeyCache{}
+		}
+		if report, err := cache.load(ctx, src); err != nil {
+			errs = append(errs, fmt.Errorf("не удалось проверить внешние ключи: %v", err))
+		} else {
+			catalog.LinkForeignKeys(plan, result, report.Missing)
+		}
+	}
+	if statistics.NeedsStaleCheck(plan) {
+		if report, err := statistics.LoadStaleReport(ctx, src, plan); err != nil {
+			errs = append(errs, fmt.Errorf("не удалось проверить свежесть статистики: %v", err))
+		} else {
+			statistics.LinkStale(result, report)
+		}
+	}
+	if catalog.NeedsVisibility(plan) {
+		if err := catalog.LinkVisibility(ctx, src, plan, result); err != nil {
+			errs = append(errs, fmt.Errorf("не удалось проверить карту видимости: %v", err))
+		}
+	}
+	if err := statistics.RefineIndexes(ctx, src, result); err != nil {
+		errs = append(errs, fmt.Errorf("не удалось уточнить индексы по pg_stats: %v", err))
+	}
+
+	result.Localize(opts.Locale)
+	fingerprint.Apply(result, query, plan)
+	result.Explanation = analyzer.Narrate(plan, opts.Locale)
+	return errs
+}
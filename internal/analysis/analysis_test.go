@@ -0,0 +1,96 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/postgres"
+)
+
+type fakeSource struct {
+	keys     []postgres.ForeignKey
+	keyReads int
+	err      error
+}
+
+func (f *fakeSource) ForeignKeys(ctx context.Context) ([]postgres.ForeignKey, error) {
+	f.keyReads++
+	return f.keys, f.err
+}
+
+func (f *fakeSource) Indexes(ctx context.Context) ([]postgres.Index, error) {
+	return nil, f.err
+}
+
+func (f *fakeSource) Tables(ctx context.Context) ([]postgres.Table, error) {
+	return []postgres.Table{{Schema: "public", Name: "orders", Rows: 500000}}, f.err
+}
+
+func (f *fakeSource) AnalyzeStates(ctx context.Context, tables []string) ([]postgres.AnalyzeState, error) {
+	return nil, f.err
+}
+
+func (f *fakeSource) ExplainWithStatistics(ctx context.Context, query string, ddl []string, analyze []postgres.AnalyzeTarget, timeout time.Duration) (string, error) {
+	return "", errors.New("не ожидали перепланирования")
+}
+
+func (f *fakeSource) ColumnStats(ctx context.Context, table string, columns []string) ([]postgres.ColumnStats, error) {
+	return nil, f.err
+}
+
+func joinPlan() []analyzer.PlanNode {
+	return []analyzer.PlanNode{{NodeType: "Hash Join", TotalCost: 3000, Plans: []analyzer.PlanNode{
+		{NodeType: "Seq Scan", RelationName: "orders", TotalCost: 2500},
+		{NodeType: "Hash", Plans: []analyzer.PlanNode{{NodeType: "Index Scan", RelationName: "users", TotalCost: 8}}},
+	}}}
+}
+
+func TestEnrich(t *testing.T) {
+	src := &fakeSource{keys: []postgres.ForeignKey{{Schema: "public", Name: "orders_user_id_fkey", Table: "orders",
+		Columns: []string{"user_id"}, ReferencedSchema: "public", ReferencedTable: "users"}}}
+	cache := &ForeignKeyCache{}
+	query := "SELECT * FROM orders JOIN users ON users.id = orders.user_id"
+
+	for i := 0; i < 2; i++ {
+		plan := joinPlan()
+		result := analyzer.AnalyzeNodes(plan)
+		if errs := Enrich(context.Background(), src, query, plan, result, Options{Locale: "en", ForeignKeys: cache}); len(errs) != 0 {
+			t.Fatalf("Неожиданные ошибки: %v", errs)
+		}
+
+		var linked bool
+		for _, problem := range result.ProblematicOperations {
+			linked = linked || problem.ForeignKey != nil && problem.ForeignKey.Constraint == "orders_user_id_fkey"
+		}
+		if !linked {
+			t.Errorf("Seq Scan по orders должен ссылаться на внешний ключ: %+v", result.ProblematicOperations)
+		}
+		if result.QueryFingerprint == "" || len(result.Explanation) == 0 {
+			t.Errorf("Нет отпечатка или пересказа плана: %+v", result)
+		}
+		if !strings.Contains(strings.Join(result.Recommendations, "\n"), "Create an index for foreign key") {
+			t.Errorf("Рекомендации не на английском: %v", result.Recommendations)
+		}
+	}
+	if src.keyReads != 1 {
+		t.Errorf("Внешние ключи прочитаны %d раз, ожидали один", src.keyReads)
+	}
+}
+
+func TestEnrichReportsFailures(t *testing.T) {
+	src := &fakeSource{err: errors.New("нет соединения")}
+	plan := joinPlan()
+	result := analyzer.AnalyzeNodes(plan)
+
+	errs := Enrich(context.Background(), src, "SELECT 1", plan, result, Options{Locale: "ru"})
+	if len(errs) == 0 || !strings.Contains(errs[0].Error(), "не удалось проверить внешние ключи: нет соединения") {
+		t.Errorf("Ожидали ошибку проверки внешних ключей: %v", errs)
+	}
+	if len(result.Explanation) == 0 || result.PlanFingerprint == "" {
+		t.Error("Ошибка проверки не должна мешать подготовке результата")
+	}
+}
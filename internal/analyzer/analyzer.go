@@ -2,6 +2,9 @@ package analyzer
 
 import (
 	"fmt"
+	"time"
+
+	"sql-optimizer/internal/i18n"
 )
 
 // AnalyzePlan анализирует план выполнения из JSON
@@ -11,21 +14,25 @@ func AnalyzePlan(planJSON string) (*AnalysisResult, error) {
 		return nil, err
 	}
 
+	return AnalyzeNodes(planNodes), nil
+}
+
+// AnalyzeNodes анализирует уже разобранные узлы плана. Тексты сообщений
+// формируются на языке по умолчанию, другой язык выбирается через Localize.
+func AnalyzeNodes(planNodes []PlanNode) *AnalysisResult {
 	result := &AnalysisResult{
-		ProblematicOperations: []ProblematicOperation{},
-		Recommendations:       []string{},
-		Warnings:              []string{},
+		ProblematicOperations:  []ProblematicOperation{},
+		RecommendationMessages: []i18n.Message{},
+		WarningMessages:        []i18n.Message{},
 	}
 
 	// Анализируем все узлы плана
 	for _, plan := range planNodes {
 		analyzeNode(plan, result)
 	}
+	result.Localize(i18n.DefaultLocale)
 
-	fmt.Printf("📊 Результат анализа: TotalCost=%.2f, Problems=%d\n", 
-		result.TotalCost, len(result.ProblematicOperations))
-
-	return result, nil
+	return result
 }
 
 // analyzeNode рекурсивно анализирует узлы плана
@@ -54,28 +61,34 @@ func analyzeSingleNode(node PlanNode, result *AnalysisResult) {
 	switch node.NodeType {
 	case "Seq Scan":
 		if node.TotalCost > 1.0 { // Понизим порог для теста
+			index, recommendation := suggestIndex(node)
 			problem := ProblematicOperation{
-				NodeType:      "Seq Scan",
-				Cost:          node.TotalCost,
-				ActualTime:    node.ActualTotalTime,
-				Description:   fmt.Sprintf("Sequential Scan на таблице %s", node.RelationName),
-				Recommendation: "Добавить индекс на используемые в WHERE поля",
-				Severity:      "high",
+				RuleID:                RuleSeqScan,
+				NodeType:              "Seq Scan",
+				Relation:              node.RelationName,
+				Filter:                node.Filter,
+				Cost:                  node.TotalCost,
+				ActualTime:            node.ActualTotalTime,
+				DescriptionMessage:    i18n.New(i18n.ProblemSeqScan, "table", node.RelationName),
+				RecommendationMessage: recommendation,
+				Severity:              "high",
+				Index:                 index,
 			}
 			result.ProblematicOperations = append(result.ProblematicOperations, problem)
-			result.Recommendations = append(result.Recommendations, 
-				fmt.Sprintf("Создать индекс для таблицы %s", node.RelationName))
+			result.RecommendationMessages = append(result.RecommendationMessages,
+				i18n.New(i18n.RecommendCreateIndex, "table", node.RelationName))
 		}
 
 	case "Sort":
 		if node.TotalCost > 0.5 {
 			problem := ProblematicOperation{
-				NodeType:      "Sort",
-				Cost:          node.TotalCost,
-				ActualTime:    node.ActualTotalTime,
-				Description:   "Операция сортировки",
-				Recommendation: "Использовать индексы для предварительной сортировки",
-				Severity:      "medium",
+				RuleID:                RuleSort,
+				NodeType:              "Sort",
+				Cost:                  node.TotalCost,
+				ActualTime:            node.ActualTotalTime,
+				DescriptionMessage:    i18n.New(i18n.ProblemSort),
+				RecommendationMessage: i18n.New(i18n.ProblemSortRecommendation),
+				Severity:              "medium",
 			}
 			result.ProblematicOperations = append(result.ProblematicOperations, problem)
 		}
@@ -83,12 +96,13 @@ func analyzeSingleNode(node PlanNode, result *AnalysisResult) {
 	case "Hash Join", "Nested Loop":
 		if node.TotalCost > 2.0 {
 			problem := ProblematicOperation{
-				NodeType:      node.NodeType,
-				Cost:          node.TotalCost,
-				ActualTime:    node.ActualTotalTime,
-				Description:   fmt.Sprintf("Операция соединения %s", node.NodeType),
-				Recommendation: "Проверить индексы на полях соединения",
-				Severity:      "medium",
+				RuleID:                RuleExpensiveJoin,
+				NodeType:              node.NodeType,
+				Cost:                  node.TotalCost,
+				ActualTime:            node.ActualTotalTime,
+				DescriptionMessage:    i18n.New(i18n.ProblemJoin, "node_type", node.NodeType),
+				RecommendationMessage: i18n.New(i18n.ProblemJoinRecommendation),
+				Severity:              "medium",
 			}
 			result.ProblematicOperations = append(result.ProblematicOperations, problem)
 		}
@@ -96,27 +110,112 @@ func analyzeSingleNode(node PlanNode, result *AnalysisResult) {
 
 	// Дополнительные проверки
 	if node.PlanRows > 1000 && node.ActualRows != nil && *node.ActualRows < node.PlanRows/10 {
-		result.Warnings = append(result.Warnings, 
-			fmt.Sprintf("Плохая оценка строк: планировалось %d, фактически %d", 
-				node.PlanRows, *node.ActualRows))
+		result.WarningMessages = append(result.WarningMessages,
+			i18n.New(i18n.WarningRowMisestimate,
+				"planned", fmt.Sprint(node.PlanRows), "actual", fmt.Sprint(*node.ActualRows)))
 	}
 }
 
 // AnalysisResult представляет результат анализа запроса
 type AnalysisResult struct {
-	TotalCost             float64                 `json:"total_cost"`
-	TotalActualTime       *float64                `json:"total_actual_time,omitempty"`
-	ProblematicOperations []ProblematicOperation  `json:"problematic_operations"`
-	Recommendations       []string                `json:"recommendations"`
-	Warnings              []string                `json:"warnings"`
+	TotalCost             float64                `json:"total_cost"`
+	TotalActualTime       *float64               `json:"total_actual_time,omitempty"`
+	ProblematicOperations []ProblematicOperation `json:"problematic_operations"`
+	Recommendations       []string               `json:"recommendations"`
+	Warnings              []string               `json:"warnings"`
+	Explanation           []string               `json:"explanation,omitempty"`
+
+	// Отпечатки для группировки повторных анализов одного запроса,
+	// заполняются пакетом fingerprint
+	NormalizedQuery  string `json:"normalized_query,omitempty"`
+	QueryFingerprint string `json:"query_fingerprint,omitempty"`
+	PlanFingerprint  string `json:"plan_fingerprint,omitempty"`
+
+	// Исходные сообщения, из которых Localize формирует тексты выше
+	RecommendationMessages []i18n.Message `json:"recommendation_messages"`
+	WarningMessages        []i18n.Message `json:"warning_messages"`
 }
 
+// Localize формирует тексты находок, рекомендаций и предупреждений
+// на языке locale
+func (r *AnalysisResult) Localize(locale string) {
+	for i := range r.ProblematicOperations {
+		problem := &r.ProblematicOperations[i]
+		problem.Description = problem.DescriptionMessage.Format(locale)
+		problem.Recommendation = problem.RecommendationMessage.Format(locale)
+	}
+	r.Recommendations = i18n.FormatAll(locale, r.RecommendationMessages)
+	r.Warnings = i18n.FormatAll(locale, r.WarningMessages)
+}
+
+// Идентификаторы правил, по которым фиксируются проблемные операции
+const (
+	RuleSeqScan       = "seq-scan"
+	RuleSort          = "sort"
+	RuleExpensiveJoin = "expensive-join"
+	// RuleUnindexedForeignKey — изменение строк родительской таблицы
+	// проверяет внешний ключ без индекса; находку добавляет пакет catalog
+	RuleUnindexedForeignKey = "unindexed-foreign-key"
+	// RuleStaleStatistics — оценка строк ошиблась, а статистика таблицы
+	// устарела; находку добавляет пакет statistics
+	RuleStaleStatistics = "stale-statistics"
+	// RuleHeapFetches — Index Only Scan обращается к таблице из-за
+	// отстающей карты видимости; находку добавляет пакет catalog
+	RuleHeapFetches = "heap-fetches"
+	// RuleCoveringIndex — Index Scan стал бы Index Only Scan, если добавить
+	// в индекс столбцы INCLUDE; находку добавляет пакет catalog
+	RuleCoveringIndex = "covering-index"
+)
+
 // ProblematicOperation представляет проблемную операцию
 type ProblematicOperation struct {
-	NodeType      string   `json:"node_type"`
-	Cost          float64  `json:"cost"`
-	ActualTime    *float64 `json:"actual_time,omitempty"`
-	Description   string   `json:"description"`
-	Recommendation string   `json:"recommendation"`
-	Severity      string   `json:"severity"` // "high", "medium", "low"
-}
\ No newline at end of file
+	RuleID                string          `json:"rule_id"`
+	NodeType              string          `json:"node_type"`
+	Relation              string          `json:"relation,omitempty"`
+	Filter                string          `json:"filter,omitempty"`
+	Cost                  float64         `json:"cost"`
+	ActualTime            *float64        `json:"actual_time,omitempty"`
+	Description           string          `json:"description"`
+	Recommendation        string          `json:"recommendation"`
+	Severity              string          `json:"severity"` // "high", "medium", "low"
+	DescriptionMessage    i18n.Message    `json:"description_message"`
+	RecommendationMessage i18n.Message    `json:"recommendation_message"`
+	Index                 *IndexCandidate `json:"index,omitempty"`
+	// ForeignKey — внешний ключ без индекса, из-за которого таблица
+	// читается целиком
+	ForeignKey *ForeignKeyLink `json:"foreign_key,omitempty"`
+	// Statistics — устаревшая статистика таблицы, из-за которой ошиблась
+	// оценка строк
+	Statistics *StatisticsLink `json:"statistics,omitempty"`
+	// Visibility — состояние карты видимости таблицы, из-за которого
+	// Index Only Scan читает таблицу
+	Visibility *VisibilityLink `json:"visibility,omitempty"`
+}
+
+// VisibilityLink — карта видимости таблицы, связанная с находкой
+type VisibilityLink struct {
+	Table           string `json:"table"`
+	Index           string `json:"index"`
+	HeapFetches     int    `json:"heap_fetches"`
+	Pages           int64  `json:"relpages"`
+	AllVisiblePages int64  `json:"relallvisible"`
+	Vacuum          string `json:"vacuum"`     // VACUUM таблицы
+	Autovacuum      string `json:"autovacuum"` // настройка автоочистки таблицы
+}
+
+// StatisticsLink — состояние статистики таблицы, связанное с находкой
+type StatisticsLink struct {
+	Table                string     `json:"table"`
+	LastAnalyze          *time.Time `json:"last_analyze,omitempty"` // ANALYZE или autoanalyze, что позже
+	ModifiedSinceAnalyze int64      `json:"n_mod_since_analyze"`
+	LiveTuples           int64      `json:"n_live_tup"`
+	Command              string     `json:"command"` // ANALYZE таблицы
+}
+
+// ForeignKeyLink — внешний ключ без индекса, связанный с находкой
+type ForeignKeyLink struct {
+	Constraint      string   `json:"constraint"`
+	Table           string   `json:"table"`
+	Columns         []string `json:"columns"`
+	ReferencedTable string   `json:"referenced_table"`
+}
@@ -3,6 +3,8 @@ package analyzer
 import (
     "reflect"
     "testing"
+
+    "sql-optimizer/internal/i18n"
 )
 
 // type AnalysisResult struct {
@@ -48,16 +50,18 @@ func TestAnalyzePlan(t *testing.T) {
                 TotalActualTime: float64Ptr(25.3),
                 ProblematicOperations: []ProblematicOperation{
                     {
+                        RuleID:        RuleSeqScan,
                         NodeType:      "Seq Scan",
+                        Relation:      "users",
                         Cost:          150.5,
                         ActualTime:    float64Ptr(25.3),
-                        Description:   "Sequential Scan на таблице users",
-                        Recommendation: "Добавить индекс на используемые в WHERE поля",
+                        DescriptionMessage:    i18n.New(i18n.ProblemSeqScan, "table", "users"),
+                        RecommendationMessage: i18n.New(i18n.ProblemSeqScanRecommendation),
                         Severity:      "high",
                     },
                 },
-                Recommendations: []string{"Создать индекс для таблицы users"},
-                Warnings:        []string{},
+                RecommendationMessages: []i18n.Message{i18n.New(i18n.RecommendCreateIndex, "table", "users")},
+                WarningMessages:        []i18n.Message{},
             },
             expectError: false,
         },
@@ -76,15 +80,16 @@ func TestAnalyzePlan(t *testing.T) {
                 TotalCost: 10.0,
                 ProblematicOperations: []ProblematicOperation{
                     {
+                        RuleID:        RuleSort,
                         NodeType:      "Sort",
                         Cost:          10.0,
-                        Description:   "Операция сортировки",
-                        Recommendation: "Использовать индексы для предварительной сортировки",
+                        DescriptionMessage:    i18n.New(i18n.ProblemSort),
+                        RecommendationMessage: i18n.New(i18n.ProblemSortRecommendation),
                         Severity:      "medium",
                     },
                 },
-                Recommendations: []string{},
-                Warnings:        []string{},
+                RecommendationMessages: []i18n.Message{},
+                WarningMessages:        []i18n.Message{},
             },
             expectError: false,
         },
@@ -111,13 +116,28 @@ func TestAnalyzePlan(t *testing.T) {
                 t.Fatalf("Неожиданная ошибка: %v", err)
             }
 
-            if !reflect.DeepEqual(result, tc.expected) {
+            // Сравниваем идентификаторы сообщений, а не тексты
+            if !reflect.DeepEqual(withoutText(result), tc.expected) {
                 t.Errorf("Результат не соответствует ожидаемому.\nОжидали: %+v\nПолучили: %+v", tc.expected, result)
             }
         })
     }
 }
 
+// withoutText убирает из результата локализованные тексты
+func withoutText(result *AnalysisResult) *AnalysisResult {
+    stripped := *result
+    stripped.Recommendations = nil
+    stripped.Warnings = nil
+    stripped.ProblematicOperations = make([]ProblematicOperation, 0, len(result.ProblematicOperations))
+    for _, problem := range result.ProblematicOperations {
+        problem.Description = ""
+        problem.Recommendation = ""
+        stripped.ProblematicOperations = append(stripped.ProblematicOperations, problem)
+    }
+    return &stripped
+}
+
 func float64Ptr(v float64) *float64 {
     return &v
 }
\ No newline at end of file
@@ -0,0 +1,140 @@
+package analyzer
+
+import (
+	"sort"
+)
+
+// MisestimateThreshold — во сколько раз должна ошибиться оценка строк,
+// чтобы узел считался неверно оцененным
+const MisestimateThreshold = 10.0
+
+// AnnotatedNode — узел плана с вычисленными показателями: собственным
+// временем без учета дочерних узлов, долей от общего времени и ошибкой
+// оценки числа строк
+type AnnotatedNode struct {
+	Node     *PlanNode
+	Depth    int
+	Children []*AnnotatedNode
+
+	// InclusiveTime — время узла вместе с дочерними с учетом всех циклов, мс
+	InclusiveTime float64
+	// ExclusiveTime — собственное время узла, мс
+	ExclusiveTime float64
+	// ExclusiveCost — собственная стоимость узла по оценке планировщика
+	ExclusiveCost float64
+	// Share — доля собственного времени (или стоимости без ANALYZE) в процентах
+	Share float64
+	// RowsRatio — отношение фактического числа строк к оценке; 0, если
+	// фактические значения неизвестны
+	RowsRatio float64
+}
+
+// Timed сообщает, известно ли фактическое время выполнения узла
+func (n *AnnotatedNode) Timed() bool {
+	return n.Node.ActualTotalTime != nil
+}
+
+// Loops возвращает число циклов выполнения узла
+func (n *AnnotatedNode) Loops() int {
+	if n.Node.ActualLoops == nil || *n.Node.ActualLoops < 1 {
+		return 1
+	}
+	return *n.Node.ActualLoops
+}
+
+// MisestimateFactor возвращает, во сколько раз ошиблась оценка строк
+// (всегда >= 1), и true, если строк оказалось больше ожидаемого
+func (n *AnnotatedNode) MisestimateFactor() (float64, bool) {
+	if n.RowsRatio == 0 {
+		return 1, false
+	}
+	if n.RowsRatio >= 1 {
+		return n.RowsRatio, true
+	}
+	return 1 / n.RowsRatio, false
+}
+
+// Annotate строит дерево аннотированных узлов для каждого корня плана
+func Annotate(planNodes []PlanNode) []*AnnotatedNode {
+	var roots []*AnnotatedNode
+	for i := range planNodes {
+		roots = append(roots, annotateNode(&planNodes[i], 0))
+	}
+
+	// Доли считаются от суммы по всем корням: по времени, если план
+	// получен с ANALYZE, иначе по стоимости
+	var totalTime, totalCost float64
+	timed := true
+	for _, root := range roots {
+		totalTime += root.InclusiveTime
+		totalCost += root.Node.TotalCost
+		timed = timed && root.Timed()
+	}
+	for _, node := range Flatten(roots) {
+		switch {
+		case timed && totalTime > 0:
+			node.Share = node.ExclusiveTime / totalTime * 100
+		case !timed && totalCost > 0:
+			node.Share = node.ExclusiveCost / totalCost * 100
+		}
+	}
+
+	return roots
+}
+
+// annotateNode рекурсивно вычисляет показатели узла и его потомков
+func annotateNode(node *PlanNode, depth int) *AnnotatedNode {
+	annotated := &AnnotatedNode{Node: node, Depth: depth}
+
+	if node.ActualTotalTime != nil {
+		annotated.InclusiveTime = *node.ActualTotalTime * float64(annotated.Loops())
+	}
+	if node.ActualRows != nil {
+		actual := float64(*node.ActualRows)
+		planned := float64(node.PlanRows)
+		annotated.RowsRatio = max(actual, 1) / max(planned, 1)
+	}
+
+	annotated.ExclusiveTime = annotated.InclusiveTime
+	annotated.ExclusiveCost = node.TotalCost
+	for i := range node.Plans {
+		child := annotateNode(&node.Plans[i], depth+1)
+		annotated.Children = append(annotated.Children, child)
+		annotated.ExclusiveTime -= child.InclusiveTime
+		annotated.ExclusiveCost -= child.Node.TotalCost
+	}
+
+	// Из-за округления и параллельных воркеров разность бывает отрицательной
+	annotated.ExclusiveTime = max(annotated.ExclusiveTime, 0)
+	annotated.ExclusiveCost = max(annotated.ExclusiveCost, 0)
+
+	return annotated
+}
+
+// Flatten возвращает все узлы деревьев в порядке обхода сверху вниз
+func Flatten(roots []*AnnotatedNode) []*AnnotatedNode {
+	var nodes []*AnnotatedNode
+	var walk func(node *AnnotatedNode)
+	walk = func(node *AnnotatedNode) {
+		nodes = append(nodes, node)
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	for _, root := range roots {
+		walk(root)
+	}
+	return nodes
+}
+
+// WorstNodes возвращает не более limit узлов с наибольшей долей
+func WorstNodes(roots []*AnnotatedNode, limit int) []*AnnotatedNode {
+	nodes := Flatten(roots)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Share > nodes[j].Share
+	})
+	if len(nodes) > limit {
+		nodes = nodes[:limit]
+	}
+	return nodes
+}
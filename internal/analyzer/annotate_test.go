@@ -0,0 +1,64 @@
+package analyzer
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAnnotate(t *testing.T) {
+	planJSON := `[{"Plan": {
+		"Node Type": "Hash Join", "Total Cost": 300, "Plan Rows": 100,
+		"Actual Total Time": 50, "Actual Rows": 1000, "Actual Loops": 1,
+		"Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "orders", "Total Cost": 200, "Plan Rows": 5000,
+			 "Actual Total Time": 30, "Actual Rows": 50, "Actual Loops": 1},
+			{"Node Type": "Index Scan", "Relation Name": "users", "Total Cost": 8, "Plan Rows": 1,
+			 "Actual Total Time": 0.5, "Actual Rows": 1, "Actual Loops": 20}
+		]
+	}}]`
+
+	planNodes, err := ParseExplainJSON(planJSON)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	roots := Annotate(planNodes)
+	nodes := Flatten(roots)
+	if len(nodes) != 3 {
+		t.Fatalf("Ожидали 3 узла, получили %d", len(nodes))
+	}
+
+	testCases := []struct {
+		name           string
+		node           *AnnotatedNode
+		exclusive      float64
+		share          float64
+		factor         float64
+		underestimated bool
+	}{
+		{"Корень без времени потомков", nodes[0], 10, 20, 10, true},
+		{"Seq Scan с переоценкой строк", nodes[1], 30, 60, 100, false},
+		{"Index Scan с учетом циклов", nodes[2], 10, 20, 1, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			factor, under := tc.node.MisestimateFactor()
+			if !almostEqual(tc.node.ExclusiveTime, tc.exclusive) || !almostEqual(tc.node.Share, tc.share) ||
+				!almostEqual(factor, tc.factor) || under != tc.underestimated {
+				t.Errorf("Ожидали время %.2f, долю %.2f, ошибку x%.1f (%v); получили %.2f, %.2f, x%.1f (%v)",
+					tc.exclusive, tc.share, tc.factor, tc.underestimated,
+					tc.node.ExclusiveTime, tc.node.Share, factor, under)
+			}
+		})
+	}
+
+	worst := WorstNodes(roots, 1)
+	if len(worst) != 1 || worst[0].Node.RelationName != "orders" {
+		t.Errorf("Самым дорогим узлом должен быть Seq Scan on orders, получили %+v", worst)
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
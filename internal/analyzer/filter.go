@@ -0,0 +1,262 @@
+package analyzer
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"sql-optimizer/internal/quote"
+)
+
+// filterKeywords — ключевые слова, которые встречаются в условиях плана,
+// но не являются именами столбцов
+var filterKeywords = map[string]bool{
+	"and": true, "or": true, "not": true, "is": true, "null": true,
+	"true": true, "false": true, "any": true, "all": true, "in": true,
+	"like": true, "ilike": true, "similar": true, "to": true, "between": true,
+	"case": true, "when": true, "then": true, "else": true, "end": true,
+	"array": true, "distinct": true, "from": true,
+}
+
+// FilterColumns извлекает имена столбцов из условия узла плана,
+// например из "((status)::text = 'pending'::text)" получается [status].
+// Приведения типов, вызовы функций, литералы и префиксы таблиц пропускаются.
+func FilterColumns(filter string) []string {
+	var columns []string
+	seen := make(map[string]bool)
+
+	runes := []rune(filter)
+	afterCast := false
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case r == '\'':
+			// Строковый литерал, '' внутри — экранированная кавычка
+			i++
+			for i < len(runes) {
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						i += 2
+						continue
+					}
+					break
+				}
+				i++
+			}
+			i++
+			afterCast = false
+
+		case r == ':' && i+1 < len(runes) && runes[i+1] == ':':
+			i += 2
+			afterCast = true
+
+		case r == '"' || unicode.IsLetter(r) || r == '_':
+			// Имя в кавычках берется как есть: это точное имя столбца,
+			// а "" внутри — экранированная кавычка
+			var name string
+			quoted := r == '"'
+			if quoted {
+				var b strings.Builder
+				for i++; i < len(runes); i++ {
+					if runes[i] == '"' {
+						if i+1 < len(runes) && runes[i+1] == '"' {
+							b.WriteRune('"')
+							i++
+							continue
+						}
+						break
+					}
+					b.WriteRune(runes[i])
+				}
+				name = b.String()
+				i++
+			} else {
+				start := i
+				for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '$') {
+					i++
+				}
+				name = strings.ToLower(string(runes[start:i]))
+			}
+
+			next := i
+			for next < len(runes) && runes[next] == ' ' {
+				next++
+			}
+			qualifier := i < len(runes) && runes[i] == '.'
+			call := next < len(runes) && runes[next] == '('
+
+			// Имя типа после "::" может состоять из нескольких слов
+			// ("timestamp with time zone"), поэтому флаг сбрасывается
+			// только на следующем не-идентификаторе
+			if afterCast || qualifier || call || !quoted && filterKeywords[name] {
+				continue
+			}
+			if !seen[name] {
+				seen[name] = true
+				columns = append(columns, name)
+			}
+
+		default:
+			if r != ' ' {
+				afterCast = false
+			}
+			i++
+		}
+	}
+
+	return columns
+}
+
+// Equality — сравнение столбца с константой или списком констант
+type Equality struct {
+	Column string   `json:"column"`
+	Values []string `json:"values"` // значения без кавычек, как в pg_stats
+	// Predicate — то же условие в виде SQL: status = 'pending',
+	// status IN ('pending', 'failed')
+	Predicate string `json:"predicate"`
+}
+
+var (
+	equalityPattern = regexp.MustCompile(operand + `\s*=\s*(?:'((?:[^']|'')*)'|(-?[0-9]+(?:\.[0-9]+)?))`)
+	anyPattern      = regexp.MustCompile(operand + `\s*=\s*ANY\s*\('((?:[^']|'')*)'::[a-z][a-z ]*\[\]\)`)
+)
+
+// Equalities находит в условии сравнения столбцов с константами:
+// (status)::text = 'pending'::text, priority = 5,
+// status = ANY ('{pending,failed}'::text[]). Учитываются только члены
+// конъюнкции верхнего уровня: сравнение под OR или NOT не ограничивает
+// строки, которые выдает условие. Сравнения столбцов между собой и с
+// параметрами пропускаются.
+func Equalities(filter string) []Equality {
+	var atoms []string
+	for _, part := range conjuncts(filter) {
+		if len(splitTopLevel(part, " OR ")) == 1 && !strings.HasPrefix(part, "NOT ") {
+			atoms = append(atoms, part)
+		}
+	}
+
+	var equalities []Equality
+	for _, m := range findAll(equalityPattern, atoms) {
+		if m[3] != "" {
+			equalities = append(equalities, Equality{Column: m[1], Values: []string{m[3]}, Predicate: m[1] + " = " + m[3]})
+			continue
+		}
+		value := strings.ReplaceAll(m[2], "''", "'")
+		equalities = append(equalities, Equality{Column: m[1], Values: []string{value}, Predicate: m[1] + " = " + quote.Literal(value)})
+	}
+	for _, m := range findAll(anyPattern, atoms) {
+		values := parseArray(strings.ReplaceAll(m[2], "''", "'"))
+		if len(values) == 0 {
+			continue
+		}
+		quoted := make([]string, len(values))
+		for i, value := range values {
+			quoted[i] = quote.Literal(value)
+		}
+		equalities = append(equalities, Equality{Column: m[1], Values: values,
+			Predicate: m[1] + " IN (" + strings.Join(quoted, ", ") + ")"})
+	}
+	return equalities
+}
+
+func findAll(pattern *regexp.Regexp, atoms []string) [][]string {
+	var matches [][]string
+	for _, atom := range atoms {
+		matches = append(matches, pattern.FindAllStringSubmatch(atom, -1)...)
+	}
+	return matches
+}
+
+// conjuncts разбивает условие на члены конъюнкции верхнего уровня:
+// из "((a = 1) AND (b = 2))" получается [a = 1, b = 2]
+func conjuncts(filter string) []string {
+	filter = unwrap(strings.TrimSpace(filter))
+	parts := splitTopLevel(filter, " AND ")
+	if len(parts) == 1 {
+		return parts
+	}
+	var result []string
+	for _, part := range parts {
+		result = append(result, conjuncts(part)...)
+	}
+	return result
+}
+
+// unwrap снимает скобки, в которые заключено все условие
+func unwrap(filter string) string {
+	for strings.HasPrefix(filter, "(") && closing(filter, 0) == len(filter)-1 {
+		filter = strings.TrimSpace(filter[1 : len(filter)-1])
+	}
+	return filter
+}
+
+// closing возвращает позицию скобки, закрывающей скобку open, или -1
+func closing(filter string, open int) int {
+	depth, quoted := 0, false
+	for i := open; i < len(filter); i++ {
+		switch {
+		case filter[i] == '\'':
+			quoted = !quoted
+		case quoted:
+		case filter[i] == '(':
+			depth++
+		case filter[i] == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTopLevel разбивает условие по разделителю sep вне скобок и
+// строковых литералов
+func splitTopLevel(filter, sep string) []string {
+	var parts []string
+	depth, quoted, start := 0, false, 0
+	for i := 0; i < len(filter); i++ {
+		switch {
+		case filter[i] == '\'':
+			quoted = !quoted
+		case quoted:
+		case filter[i] == '(':
+			depth++
+		case filter[i] == ')':
+			depth--
+		case depth == 0 && strings.HasPrefix(filter[i:], sep):
+			parts = append(parts, strings.TrimSpace(filter[start:i]))
+			start = i + len(sep)
+			i = start - 1
+		}
+	}
+	return append(parts, strings.TrimSpace(filter[start:]))
+}
+
+// parseArray разбирает литерал одномерного массива {a,"b c"}
+func parseArray(literal string) []string {
+	if len(literal) < 2 || literal[0] != '{' || literal[len(literal)-1] != '}' {
+		return nil
+	}
+	var values []string
+	var value strings.Builder
+	quoted, escaped := false, false
+	for _, r := range literal[1 : len(literal)-1] {
+		switch {
+		case escaped:
+			value.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			values = append(values, value.String())
+			value.Reset()
+		default:
+			value.WriteRune(r)
+		}
+	}
+	return append(values, value.String())
+}
@@ -0,0 +1,115 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/quote"
+)
+
+// IndexCandidate — предлагаемый индекс для устранения проблемной операции
+type IndexCandidate struct {
+	// Schema — схема таблицы; пустая, если неизвестна, и тогда таблица в
+	// команде не уточняется схемой
+	Schema  string   `json:"schema,omitempty"`
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	// Kind — вид специализированного индекса (Index*); пустой для обычного
+	// B-tree по столбцам
+	Kind string `json:"kind,omitempty"`
+	// Method — метод доступа: gin, gist или brin; пустой — B-tree
+	Method string `json:"method,omitempty"`
+	// Expression — ключ-выражение вместо столбцов: lower(email), to_tsvector(...)
+	Expression string `json:"expression,omitempty"`
+	// OpClass — класс операторов ключа: gin_trgm_ops, jsonb_path_ops
+	OpClass string `json:"opclass,omitempty"`
+	// Extension — расширение, которое предоставляет класс операторов
+	Extension string `json:"extension,omitempty"`
+	// Include — неключевые столбцы INCLUDE
+	Include []string `json:"include,omitempty"`
+	// Where — условие частичного индекса
+	Where string `json:"where,omitempty"`
+}
+
+// Name возвращает имя индекса в принятом в проекте виде
+// idx_<таблица>_<столбцы>, для специализированных индексов — с суффиксом вида
+func (c IndexCandidate) Name() string {
+	name := "idx_" + c.Table + "_" + strings.Join(c.Columns, "_")
+	suffix := indexSuffixes[c.Kind]
+	if c.Kind == IndexExpression {
+		// lower(email) → idx_users_email_lower
+		suffix, _, _ = strings.Cut(c.Expression, "(")
+	}
+	if suffix != "" {
+		name += "_" + suffix
+	}
+	return name
+}
+
+// Plain сообщает, что предлагается обычный B-tree по столбцам без условия,
+// возможно с INCLUDE: только такие индексы можно объединять по префиксу ключа
+func (c IndexCandidate) Plain() bool {
+	return c.Method == "" && c.Expression == "" && c.Where == ""
+}
+
+// DDL возвращает команду создания индекса без блокировки записи в таблицу;
+// если класс операторов дает расширение, перед ней создается расширение
+func (c IndexCandidate) DDL() string {
+	key := quoteAll(c.Columns)
+	if c.Expression != "" {
+		key = "(" + c.Expression + ")"
+	}
+	if c.OpClass != "" {
+		key += " " + c.OpClass
+	}
+	using := ""
+	if c.Method != "" {
+		using = "USING " + c.Method + " "
+	}
+	include := ""
+	if len(c.Include) > 0 {
+		include = " INCLUDE (" + quoteAll(c.Include) + ")"
+	}
+	where := ""
+	if c.Where != "" {
+		where = " WHERE " + c.Where
+	}
+	table := quote.Ident(c.Table)
+	if c.Schema != "" {
+		table = quote.Ident(c.Schema) + "." + table
+	}
+	ddl := fmt.Sprintf("CREATE INDEX CONCURRENTLY %s ON %s %s(%s)%s%s;",
+		quote.Ident(c.Name()), table, using, key, include, where)
+	if c.Extension != "" {
+		ddl = fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s;\n%s", c.Extension, ddl)
+	}
+	return ddl
+}
+
+// quoteAll записывает список столбцов через запятую
+func quoteAll(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quote.Ident(column)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// suggestIndex предлагает индекс по столбцам фильтра сканируемой таблицы
+// и рекомендацию к нему. Если условие не может использовать B-tree,
+// предлагается индекс подходящего вида, а рекомендация объясняет почему.
+func suggestIndex(node PlanNode) (*IndexCandidate, i18n.Message) {
+	recommendation := i18n.New(i18n.ProblemSeqScanRecommendation)
+	if node.RelationName == "" || node.Filter == "" {
+		return nil, recommendation
+	}
+	if index, message := specialIndex(node.RelationName, node.Filter); index != nil {
+		return index, message
+	}
+	columns := FilterColumns(node.Filter)
+	if len(columns) == 0 {
+		return nil, recommendation
+	}
+	return &IndexCandidate{Table: node.RelationName, Columns: columns}, recommendation
+}
@@ -0,0 +1,138 @@
+package analyzer
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"sql-optimizer/internal/i18n"
+)
+
+// Виды специализированных индексов для условий, которым B-tree не помогает
+const (
+	IndexTrigram    = "trigram"    // LIKE/ILIKE с ведущим шаблоном: GIN с gin_trgm_ops
+	IndexJSONB      = "jsonb"      // @>, ?, ?|, ?& по jsonb: GIN
+	IndexFullText   = "fulltext"   // @@: GIN по tsvector
+	IndexExpression = "expression" // lower(col) = …: B-tree по выражению
+	IndexRange      = "range"      // &&, @>, <@ по диапазонам: GiST
+	// IndexBRIN — сравнение по времени на физически упорядоченном столбце;
+	// корреляцию проверяет пакет statistics
+	IndexBRIN = "brin"
+	// IndexPartial — равенство редкому значению: частичный B-tree с WHERE;
+	// частоту значения по pg_stats проверяет пакет statistics
+	IndexPartial = "partial"
+	// IndexCovering — индекс Index Scan с недостающими столбцами в INCLUDE;
+	// предлагает пакет catalog
+	IndexCovering = "covering"
+)
+
+// indexSuffixes — суффиксы имен специализированных индексов
+var indexSuffixes = map[string]string{
+	IndexTrigram:  "trgm",
+	IndexJSONB:    "gin",
+	IndexFullText: "fts",
+	IndexRange:    "gist",
+	IndexBRIN:     "brin",
+	IndexPartial:  "partial",
+	IndexCovering: "covering",
+}
+
+// operand — столбец в условии плана: email, u.email, (email)::text;
+// имя столбца без префикса таблицы попадает в группу. Столбец начинается
+// после скобки или пробела, чтобы имя типа после "::" не сошло за столбец.
+const operand = `(?:^|[\s(])\(?(?:[a-z_][a-z0-9_$]*\.)?([a-z_][a-z0-9_$]*)\)?(?:::[a-z][a-z ]*?)?`
+
+// literal — строковая константа с экранированными кавычками
+const literal = `'(?:[^']|'')*'`
+
+var (
+	likePattern      = regexp.MustCompile(operand + `\s+(~~\*?)\s+'((?:[^']|'')*)'`)
+	jsonbKeysPattern = regexp.MustCompile(operand + `\s+(\?\||\?&|\?)\s+'`)
+	jsonbPathPattern = regexp.MustCompile(operand + `\s+(@>)\s+` + literal + `::jsonb\b`)
+	// to_tsvector с явной конфигурацией: без нее функция не IMMUTABLE
+	// и индекс по выражению построить нельзя
+	fullTextPattern   = regexp.MustCompile(`(to_tsvector\((?:[^()]|\([^()]*\))*\)|` + operand + `)\s+@@\s`)
+	expressionPattern = regexp.MustCompile(`\b(lower|upper)` + operand + `\)\s*=\s`)
+	rangePattern      = regexp.MustCompile(operand + `\s+(&&|@>|<@)\s+` + literal + `::([a-z]+(?: [a-z]+)*)(\[\])?`)
+	timeRangePattern  = regexp.MustCompile(operand + `\s*(?:>=|>|<=|<)\s*(?:` + literal +
+		`::(?:timestamp|date)|\(?now\(\)|(?i:current_date|current_timestamp|localtimestamp))`)
+)
+
+// rangeElements — типы элементов, для которых @> означает «диапазон
+// содержит значение»
+var rangeElements = map[string]bool{
+	"timestamp with time zone": true, "timestamp without time zone": true,
+	"date": true, "integer": true, "bigint": true, "numeric": true,
+}
+
+// specialIndex ищет в условии выражение, которое B-tree обслужить не может,
+// и предлагает индекс подходящего вида с рекомендацией. Если таких
+// выражений нет, возвращает nil.
+func specialIndex(table, filter string) (*IndexCandidate, i18n.Message) {
+	if m := fullTextPattern.FindStringSubmatch(filter); m != nil {
+		if column := m[2]; column != "" {
+			index := &IndexCandidate{Table: table, Columns: []string{column}, Kind: IndexFullText, Method: "gin"}
+			return index, indexMessage(i18n.IndexTypeFullText, index, column, "@@")
+		}
+		if strings.Contains(m[1], "::regconfig") {
+			columns := FilterColumns(m[1])
+			index := &IndexCandidate{Table: table, Columns: columns, Kind: IndexFullText, Method: "gin", Expression: m[1]}
+			return index, indexMessage(i18n.IndexTypeFullText, index, strings.Join(columns, ", "), "@@")
+		}
+	}
+
+	if m := jsonbKeysPattern.FindStringSubmatch(filter); m != nil {
+		// jsonb_path_ops не поддерживает операторы ключей, нужен jsonb_ops
+		// по умолчанию; он обслуживает и @>
+		index := &IndexCandidate{Table: table, Columns: []string{m[1]}, Kind: IndexJSONB, Method: "gin"}
+		return index, indexMessage(i18n.IndexTypeJSONB, index, m[1], m[2])
+	}
+	if m := jsonbPathPattern.FindStringSubmatch(filter); m != nil {
+		index := &IndexCandidate{Table: table, Columns: []string{m[1]}, Kind: IndexJSONB, Method: "gin", OpClass: "jsonb_path_ops"}
+		return index, indexMessage(i18n.IndexTypeJSONB, index, m[1], m[2])
+	}
+
+	for _, m := range likePattern.FindAllStringSubmatch(filter, -1) {
+		if pattern := m[3]; pattern != "" && (pattern[0] == '%' || pattern[0] == '_') {
+			index := &IndexCandidate{Table: table, Columns: []string{m[1]}, Kind: IndexTrigram, Method: "gin",
+				OpClass: "gin_trgm_ops", Extension: "pg_trgm"}
+			operator := "LIKE"
+			if m[2] == "~~*" {
+				operator = "ILIKE"
+			}
+			return index, indexMessage(i18n.IndexTypeTrigram, index, m[1], operator)
+		}
+	}
+
+	for _, m := range rangePattern.FindAllStringSubmatch(filter, -1) {
+		valueType, array := m[3], m[4] != ""
+		if array || !(strings.HasSuffix(valueType, "range") || m[2] == "@>" && rangeElements[valueType]) {
+			continue
+		}
+		index := &IndexCandidate{Table: table, Columns: []string{m[1]}, Kind: IndexRange, Method: "gist"}
+		return index, indexMessage(i18n.IndexTypeRange, index, m[1], m[2])
+	}
+
+	if m := expressionPattern.FindStringSubmatch(filter); m != nil {
+		expression := m[1] + "(" + m[2] + ")"
+		index := &IndexCandidate{Table: table, Columns: []string{m[2]}, Kind: IndexExpression, Expression: expression}
+		return index, indexMessage(i18n.IndexTypeExpression, index, m[2], expression)
+	}
+	return nil, i18n.Message{}
+}
+
+// TimeRangeColumns возвращает столбцы, которые условие сравнивает
+// с моментом времени: created_at >= '2024-01-01'::timestamp, created_at > now()
+func TimeRangeColumns(filter string) []string {
+	var columns []string
+	for _, m := range timeRangePattern.FindAllStringSubmatch(filter, -1) {
+		if !slices.Contains(columns, m[1]) {
+			columns = append(columns, m[1])
+		}
+	}
+	return columns
+}
+
+func indexMessage(id string, index *IndexCandidate, column, operator string) i18n.Message {
+	return i18n.New(id, "table", index.Table, "column", column, "operator", operator, "ddl", index.DDL())
+}
@@ -0,0 +1,151 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"sql-optimizer/internal/i18n"
+)
+
+func TestSpecialIndex(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		ddl    string // пусто, если B-tree подходит
+	}{
+		{
+			name:   "LIKE с ведущим %",
+			filter: "((name)::text ~~ '%ivan%'::text)",
+			ddl:    "CREATE EXTENSION IF NOT EXISTS pg_trgm;\nCREATE INDEX CONCURRENTLY idx_users_name_trgm ON users USING gin (name gin_trgm_ops);",
+		},
+		{
+			name:   "ILIKE с ведущим _",
+			filter: "((u.email)::text ~~* '_van@%'::text)",
+			ddl:    "CREATE EXTENSION IF NOT EXISTS pg_trgm;\nCREATE INDEX CONCURRENTLY idx_users_email_trgm ON users USING gin (email gin_trgm_ops);",
+		},
+		{
+			name:   "LIKE по префиксу",
+			filter: "((name)::text ~~ 'ivan%'::text)",
+		},
+		{
+			name:   "jsonb @>",
+			filter: `(data @> '{"status": "new"}'::jsonb)`,
+			ddl:    "CREATE INDEX CONCURRENTLY idx_users_data_gin ON users USING gin (data jsonb_path_ops);",
+		},
+		{
+			name:   "jsonb ?",
+			filter: "((data ? 'phone'::text) AND (data @> '{}'::jsonb))",
+			ddl:    "CREATE INDEX CONCURRENTLY idx_users_data_gin ON users USING gin (data);",
+		},
+		{
+			name:   "полнотекстовый поиск по выражению",
+			filter: "(to_tsvector('russian'::regconfig, bio) @@ '''повар'''::tsquery)",
+			ddl:    "CREATE INDEX CONCURRENTLY idx_users_bio_fts ON users USING gin ((to_tsvector('russian'::regconfig, bio)));",
+		},
+		{
+			name:   "полнотекстовый поиск по столбцу tsvector",
+			filter: "(search @@ '''повар'''::tsquery)",
+			ddl:    "CREATE INDEX CONCURRENTLY idx_users_search_fts ON users USING gin (search);",
+		},
+		{
+			name:   "to_tsvector без конфигурации",
+			filter: "(to_tsvector(bio) @@ '''повар'''::tsquery)",
+		},
+		{
+			name:   "lower(col) =",
+			filter: "(lower((email)::text) = 'ivan@example.com'::text)",
+			ddl:    "CREATE INDEX CONCURRENTLY idx_users_email_lower ON users ((lower(email)));",
+		},
+		{
+			name:   "пересечение диапазонов",
+			filter: `(during && '["2024-01-01 00:00:00+00","2024-02-01 00:00:00+00")'::tstzrange)`,
+			ddl:    "CREATE INDEX CONCURRENTLY idx_users_during_gist ON users USING gist (during);",
+		},
+		{
+			name:   "диапазон содержит момент",
+			filter: "(during @> '2024-01-05 00:00:00+00'::timestamp with time zone)",
+			ddl:    "CREATE INDEX CONCURRENTLY idx_users_during_gist ON users USING gist (during);",
+		},
+		{
+			name:   "пересечение массивов",
+			filter: "(tags && '{go,sql}'::text[])",
+		},
+		{
+			name:   "LIKE по выражению",
+			filter: "(lower((name)::text) ~~ '%ivan%'::text)",
+		},
+		{
+			name:   "равенство",
+			filter: "((status)::text = 'pending'::text)",
+		},
+	}
+	for _, tt := range tests {
+		index, message := specialIndex("users", tt.filter)
+		switch {
+		case tt.ddl == "" && index != nil:
+			t.Errorf("%s: предложен индекс %s", tt.name, index.DDL())
+		case tt.ddl != "" && index == nil:
+			t.Errorf("%s: индекс не предложен", tt.name)
+		case index != nil && index.DDL() != tt.ddl:
+			t.Errorf("%s: DDL\n%s\nожидалось\n%s", tt.name, index.DDL(), tt.ddl)
+		case index != nil && message.Args["ddl"] != tt.ddl:
+			t.Errorf("%s: рекомендация %+v", tt.name, message)
+		}
+	}
+}
+
+func TestSeqScanSpecialIndex(t *testing.T) {
+	result := AnalyzeNodes([]PlanNode{{
+		NodeType: "Seq Scan", RelationName: "users", TotalCost: 100,
+		Filter: "((name)::text ~~* '%ivan%'::text)",
+	}})
+	problem := result.ProblematicOperations[0]
+	if problem.Index == nil || problem.Index.Kind != IndexTrigram || problem.Index.Plain() {
+		t.Fatalf("индекс: %+v", problem.Index)
+	}
+	if problem.RecommendationMessage.ID != i18n.IndexTypeTrigram || problem.RecommendationMessage.Args["operator"] != "ILIKE" {
+		t.Errorf("рекомендация: %+v", problem.RecommendationMessage)
+	}
+}
+
+func TestTimeRangeColumns(t *testing.T) {
+	filter := "((created_at >= '2024-01-01 00:00:00'::timestamp without time zone) AND (o.shipped_at < now()) AND (amount > '10'::numeric))"
+	if got := TimeRangeColumns(filter); !reflect.DeepEqual(got, []string{"created_at", "shipped_at"}) {
+		t.Errorf("TimeRangeColumns = %v", got)
+	}
+}
+
+func TestEqualities(t *testing.T) {
+	filter := `(((status)::text = ANY ('{pending,"on hold"}'::text[])) AND (priority = 5) AND ((note)::text = 'O''Brien'::text) AND (o.id = c.order_id))`
+	want := []Equality{
+		{Column: "priority", Values: []string{"5"}, Predicate: "priority = 5"},
+		{Column: "note", Values: []string{"O'Brien"}, Predicate: "note = 'O''Brien'"},
+		{Column: "status", Values: []string{"pending", "on hold"}, Predicate: "status IN ('pending', 'on hold')"},
+	}
+	if got := Equalities(filter); !reflect.DeepEqual(got, want) {
+		t.Errorf("Equalities =\n%+v\nожидалось\n%+v", got, want)
+	}
+
+	// сравнения под OR и NOT не ограничивают выдаваемые строки
+	for _, filter := range []string{
+		`(((status)::text = 'pending'::text) OR (priority = 5))`,
+		`(NOT ((status)::text = 'pending'::text))`,
+		`((((status)::text = 'pending'::text) AND (priority = 5)) OR (note IS NULL))`,
+	} {
+		if got := Equalities(filter); len(got) != 0 {
+			t.Errorf("Equalities(%s) = %+v, ожидалось пусто", filter, got)
+		}
+	}
+	got := Equalities(`(((status)::text = 'pending'::text) AND ((priority = 5) OR (priority = 7)))`)
+	if len(got) != 1 || got[0].Column != "status" {
+		t.Errorf("Ожидали только status из конъюнкции: %+v", got)
+	}
+}
+
+func TestPartialIndexDDL(t *testing.T) {
+	index := IndexCandidate{Table: "orders", Columns: []string{"created_at"}, Kind: IndexPartial, Where: "status = 'pending'"}
+	want := "CREATE INDEX CONCURRENTLY idx_orders_created_at_partial ON orders (created_at) WHERE status = 'pending';"
+	if index.DDL() != want || index.Plain() {
+		t.Errorf("DDL: %s", index.DDL())
+	}
+}
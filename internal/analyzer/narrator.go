@@ -0,0 +1,162 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"sql-optimizer/internal/i18n"
+)
+
+// narrator формирует фразы объяснения на одном языке по шаблонам каталога
+type narrator struct {
+	locale string
+}
+
+// text возвращает фразу из каталога с параметрами, переданными парами
+func (n narrator) text(id string, args ...string) string {
+	return i18n.New(id, args...).Format(n.locale)
+}
+
+// Narrate объясняет план простыми словами: шаги перечисляются в порядке
+// движения данных, от листьев дерева к корню. Фразы берутся из каталога
+// сообщений на языке locale.
+func Narrate(planNodes []PlanNode, locale string) []string {
+	n := narrator{locale: locale}
+
+	var steps []string
+	var walk func(node *AnnotatedNode)
+	walk = func(node *AnnotatedNode) {
+		for _, child := range node.Children {
+			walk(child)
+		}
+		if step := n.describe(node); step != "" {
+			if node.Loops() > 1 {
+				step += n.text(i18n.NarrateLoops, "loops", fmt.Sprint(node.Loops()))
+			}
+			steps = append(steps, step)
+		}
+	}
+	for _, root := range Annotate(planNodes) {
+		walk(root)
+	}
+
+	return steps
+}
+
+// describe возвращает фразу для одного узла
+func (n narrator) describe(node *AnnotatedNode) string {
+	plan := node.Node
+	rows := n.rows(node)
+
+	switch plan.NodeType {
+	case "Seq Scan":
+		if plan.Filter != "" && plan.ActualRows != nil && plan.RowsRemovedByFilter != nil {
+			kept := *plan.ActualRows
+			total := kept + *plan.RowsRemovedByFilter
+			percent := 100.0
+			if total > 0 {
+				percent = float64(kept) / float64(total) * 100
+			}
+			return n.text(i18n.NarrateSeqScanFilter,
+				"total", n.number(float64(total)),
+				"table", plan.RelationName,
+				"columns", strings.Join(FilterColumns(plan.Filter), ", "),
+				"kept", n.number(float64(kept)),
+				"percent", n.percent(percent))
+		}
+		return n.text(i18n.NarrateSeqScan, "rows", rows, "table", plan.RelationName)
+
+	case "Index Scan":
+		return n.text(i18n.NarrateIndexScan, "rows", rows, "table", plan.RelationName, "index", plan.IndexName)
+	case "Index Only Scan":
+		return n.text(i18n.NarrateIndexOnlyScan, "rows", rows, "index", plan.IndexName)
+	case "Bitmap Index Scan":
+		return n.text(i18n.NarrateBitmapIndex, "index", plan.IndexName)
+	case "Bitmap Heap Scan":
+		return n.text(i18n.NarrateBitmapHeap, "rows", rows, "table", plan.RelationName)
+	case "Hash":
+		return n.text(i18n.NarrateHash, "rows", rows)
+
+	case "Hash Join", "Merge Join", "Nested Loop":
+		other := n.text(i18n.NarrateIntermediate)
+		if len(node.Children) > 1 {
+			if relation := firstRelation(node.Children[1]); relation != "" {
+				other = relation
+			}
+		}
+		return n.text(i18n.NarrateJoin, "other", other, "method", plan.NodeType, "rows", rows)
+
+	case "Sort", "Incremental Sort":
+		id := i18n.NarrateSort
+		if plan.SortSpaceType == "Disk" {
+			id = i18n.NarrateSortDisk
+		}
+		return n.text(id, "rows", rows, "key", strings.Join(plan.SortKey, ", "))
+
+	case "Aggregate", "HashAggregate", "GroupAggregate":
+		if len(plan.GroupKey) > 0 {
+			return n.text(i18n.NarrateAggregate, "rows", rows, "key", strings.Join(plan.GroupKey, ", "))
+		}
+		return n.text(i18n.NarrateAggregateAll, "rows", rows)
+
+	case "Limit":
+		return n.text(i18n.NarrateLimit, "rows", rows)
+
+	case "Gather", "Gather Merge":
+		launched := plan.WorkersPlanned
+		if plan.WorkersLaunched != nil {
+			launched = *plan.WorkersLaunched
+		}
+		return n.text(i18n.NarrateGather, "launched", fmt.Sprint(launched), "planned", fmt.Sprint(plan.WorkersPlanned))
+	}
+
+	return n.text(i18n.NarrateOther, "operation", plan.NodeType, "rows", rows)
+}
+
+// rows возвращает число строк на выходе узла: фактическое за все циклы
+// или, без ANALYZE, оценку планировщика
+func (n narrator) rows(node *AnnotatedNode) string {
+	if node.Node.ActualRows == nil {
+		return n.text(i18n.NarrateApprox) + n.number(float64(node.Node.PlanRows))
+	}
+	return n.number(float64(*node.Node.ActualRows) * float64(node.Loops()))
+}
+
+// number сокращает большие числа: 1200000 превращается в "1.2M"
+func (n narrator) number(v float64) string {
+	switch {
+	case v >= 1e6:
+		return n.fraction(v/1e6) + n.text(i18n.NumberMillion)
+	case v >= 1e4:
+		return n.fraction(v/1e3) + n.text(i18n.NumberThousand)
+	default:
+		return fmt.Sprintf("%.0f", v)
+	}
+}
+
+// percent форматирует процент с точностью, достаточной для малых долей
+func (n narrator) percent(v float64) string {
+	if v < 1 && v > 0 {
+		return strings.Replace(fmt.Sprintf("%.2f", v), ".", n.text(i18n.NumberDecimal), 1)
+	}
+	return fmt.Sprintf("%.0f", v)
+}
+
+// fraction выводит число с одним знаком после запятой, если он не ноль
+func (n narrator) fraction(v float64) string {
+	s := strings.TrimSuffix(fmt.Sprintf("%.1f", v), ".0")
+	return strings.Replace(s, ".", n.text(i18n.NumberDecimal), 1)
+}
+
+// firstRelation возвращает первую таблицу в поддереве узла
+func firstRelation(node *AnnotatedNode) string {
+	if node.Node.RelationName != "" {
+		return node.Node.RelationName
+	}
+	for _, child := range node.Children {
+		if relation := firstRelation(child); relation != "" {
+			return relation
+		}
+	}
+	return ""
+}
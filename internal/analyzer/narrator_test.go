@@ -0,0 +1,62 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNarrate(t *testing.T) {
+	planJSON := `[{"Plan": {
+		"Node Type": "Hash Join", "Total Cost": 3000, "Plan Rows": 300,
+		"Actual Total Time": 800, "Actual Rows": 36000, "Actual Loops": 1,
+		"Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "orders", "Filter": "((status)::text = 'new'::text)",
+			 "Total Cost": 2500, "Plan Rows": 300, "Actual Total Time": 700, "Actual Rows": 36000,
+			 "Rows Removed by Filter": 1164000, "Actual Loops": 1},
+			{"Node Type": "Hash", "Total Cost": 200, "Plan Rows": 10000,
+			 "Actual Total Time": 50, "Actual Rows": 10000, "Actual Loops": 1,
+			 "Plans": [
+				{"Node Type": "Seq Scan", "Relation Name": "users", "Total Cost": 180, "Plan Rows": 10000,
+				 "Actual Total Time": 20, "Actual Rows": 10000, "Actual Loops": 1}
+			 ]}
+		]
+	}}]`
+
+	planNodes, err := ParseExplainJSON(planJSON)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	testCases := []struct {
+		lang     string
+		expected []string
+	}{
+		{
+			lang: "ru",
+			expected: []string{
+				"PostgreSQL читает все 1,2 млн строк таблицы orders и после фильтра по status оставляет 36 тыс. (3%).",
+				"PostgreSQL читает все 10 тыс. строк таблицы users.",
+				"Строит в памяти хеш-таблицу из 10 тыс. строк.",
+				"Затем соединяет их с users методом Hash Join и получает 36 тыс. строк.",
+			},
+		},
+		{
+			lang: "en",
+			expected: []string{
+				"PostgreSQL reads all 1.2M rows of orders and keeps 36K (3%) after filtering on status.",
+				"PostgreSQL reads all 10K rows of users.",
+				"It builds an in-memory hash table from 10K rows.",
+				"Then it joins them with users using Hash Join, producing 36K rows.",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.lang, func(t *testing.T) {
+			steps := Narrate(planNodes, tc.lang)
+			if !reflect.DeepEqual(steps, tc.expected) {
+				t.Errorf("Объяснение не соответствует ожидаемому.\nОжидали: %q\nПолучили: %q", tc.expected, steps)
+			}
+		})
+	}
+}
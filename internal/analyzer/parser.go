@@ -18,7 +18,10 @@ type ExplainResult struct {
 type PlanNode struct {
 	NodeType          string    `json:"Node Type"`
 	RelationName      string    `json:"Relation Name,omitempty"`
+	Schema            string    `json:"Schema,omitempty"` // схема таблицы, только с VERBOSE
 	Alias             string    `json:"Alias,omitempty"`
+	Operation         string    `json:"Operation,omitempty"` // Insert, Update, Delete у ModifyTable
+	ParentRelationship string   `json:"Parent Relationship,omitempty"` // Outer, Inner, SubPlan, InitPlan...
 	StartupCost       float64   `json:"Startup Cost"`
 	TotalCost         float64   `json:"Total Cost"`
 	PlanRows          int       `json:"Plan Rows"`
@@ -28,20 +31,34 @@ type PlanNode struct {
 	ActualRows        *int      `json:"Actual Rows,omitempty"`
 	ActualLoops       *int      `json:"Actual Loops,omitempty"`
 	Filter            string    `json:"Filter,omitempty"`
+	RowsRemovedByFilter *int    `json:"Rows Removed by Filter,omitempty"`
+	HeapFetches       *int      `json:"Heap Fetches,omitempty"` // Index Only Scan: обращения к таблице
+	Output            []string  `json:"Output,omitempty"`       // выходные столбцы, только с VERBOSE
 	JoinType          string    `json:"Join Type,omitempty"`
 	IndexName         string    `json:"Index Name,omitempty"`
-	HashCondition     string    `json:"Hash Condition,omitempty"`
+	IndexCondition    string    `json:"Index Cond,omitempty"`
+	RecheckCondition  string    `json:"Recheck Cond,omitempty"`
+	HashCondition     string    `json:"Hash Cond,omitempty"`
+	SharedHitBlocks   int       `json:"Shared Hit Blocks,omitempty"`
+	SharedReadBlocks  int       `json:"Shared Read Blocks,omitempty"`
+	TempReadBlocks    int       `json:"Temp Read Blocks,omitempty"`
+	SortKey           []string  `json:"Sort Key,omitempty"`
+	SortMethod        string    `json:"Sort Method,omitempty"`
+	SortSpaceType     string    `json:"Sort Space Type,omitempty"`
+	SortSpaceUsed     int       `json:"Sort Space Used,omitempty"` // КБ
+	HashBatches       int       `json:"Hash Batches,omitempty"`
+	PeakMemoryUsage   int       `json:"Peak Memory Usage,omitempty"` // КБ
+	GroupKey          []string  `json:"Group Key,omitempty"`
+	WorkersPlanned    int       `json:"Workers Planned,omitempty"`
+	WorkersLaunched   *int      `json:"Workers Launched,omitempty"`
 	Plans             []PlanNode `json:"Plans,omitempty"`
 }
 
 // ParseExplainJSON парсит JSON вывод EXPLAIN
 func ParseExplainJSON(planJSON string) ([]PlanNode, error) {
-	fmt.Printf("🔍 Парсим JSON...\n")
-	
 	// Пробуем парсить как массив ExplainResult
 	var explainResults []ExplainResult
 	if err := json.Unmarshal([]byte(planJSON), &explainResults); err == nil {
-		fmt.Printf("✅ Распаршено как массив ExplainResult: %d элементов\n", len(explainResults))
 		var planNodes []PlanNode
 		for _, result := range explainResults {
 			planNodes = append(planNodes, result.Plan)
@@ -52,21 +69,18 @@ func ParseExplainJSON(planJSON string) ([]PlanNode, error) {
 	// Пробуем парсить как одиночный ExplainResult
 	var singleExplainResult ExplainResult
 	if err := json.Unmarshal([]byte(planJSON), &singleExplainResult); err == nil {
-		fmt.Printf("✅ Распаршено как одиночный ExplainResult\n")
 		return []PlanNode{singleExplainResult.Plan}, nil
 	}
 
 	// Пробуем парсить как массив PlanNode (старый формат)
 	var planNodes []PlanNode
 	if err := json.Unmarshal([]byte(planJSON), &planNodes); err == nil {
-		fmt.Printf("✅ Распаршено как массив PlanNode: %d элементов\n", len(planNodes))
 		return planNodes, nil
 	}
 
 	// Пробуем парсить как одиночный PlanNode
 	var singlePlan PlanNode
 	if err := json.Unmarshal([]byte(planJSON), &singlePlan); err == nil {
-		fmt.Printf("✅ Распаршено как одиночный PlanNode\n")
 		return []PlanNode{singlePlan}, nil
 	}
 
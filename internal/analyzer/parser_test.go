@@ -0,0 +1,33 @@
+package analyzer
+
+import "testing"
+
+func TestParseExplainJSONConditions(t *testing.T) {
+	planJSON := `[{"Plan": {
+		"Node Type": "Hash Join", "Total Cost": 300, "Plan Rows": 100,
+		"Hash Cond": "(o.user_id = u.id)",
+		"Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "orders", "Alias": "o", "Total Cost": 200, "Plan Rows": 5000,
+			 "Filter": "((status)::text = 'pending'::text)"},
+			{"Node Type": "Hash", "Total Cost": 8, "Plan Rows": 1, "Plans": [
+				{"Node Type": "Index Scan", "Relation Name": "users", "Alias": "u", "Index Name": "users_pkey",
+				 "Total Cost": 8, "Plan Rows": 1, "Index Cond": "(id = 42)"}
+			]}
+		]
+	}}]`
+
+	planNodes, err := ParseExplainJSON(planJSON)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	join := planNodes[0]
+	if join.HashCondition != "(o.user_id = u.id)" {
+		t.Errorf("Условие хеш-соединения не прочитано: %q", join.HashCondition)
+	}
+	if join.Plans[0].Filter != "((status)::text = 'pending'::text)" {
+		t.Errorf("Фильтр не прочитан: %q", join.Plans[0].Filter)
+	}
+	if index := join.Plans[1].Plans[0]; index.IndexCondition != "(id = 42)" {
+		t.Errorf("Условие индекса не прочитано: %q", index.IndexCondition)
+	}
+}
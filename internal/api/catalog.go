@@ -0,0 +1,227 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"sql-optimizer/internal/catalog"
+	"sql-optimizer/internal/health"
+	"sql-optimizer/internal/postgres"
+	"sql-optimizer/internal/recommendation"
+	"sql-optimizer/internal/tuning"
+)
+
+// CatalogRequest — запрос на проверку схемы базы
+type CatalogRequest struct {
+	DBConfig
+	Locale string `json:"locale,omitempty"`
+}
+
+// IndexCheckRequest — запрос на проверку индексов базы
+type IndexCheckRequest struct {
+	DBConfig
+	// RareScans — порог редкого использования; если не задан,
+	// берется catalog.DefaultRareScans
+	RareScans *int64 `json:"rare_scans,omitempty"`
+	Locale    string `json:"locale,omitempty"`
+}
+
+// CheckIndexes находит неиспользуемые, дублирующиеся, избыточные и
+// недействительные индексы и возвращает команды для их удаления
+func (h *Handler) CheckIndexes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req IndexCheckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Ошибка парсинга JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	locale, err := resolveLocale(r, req.Locale)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts := catalog.IndexOptions{RareScans: catalog.DefaultRareScans}
+	if req.RareScans != nil {
+		opts.RareScans = *req.RareScans
+	}
+
+	pgClient, err := postgres.NewClient(req.DBConfig.ConnectionString())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Ошибка подключения к БД: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer pgClient.Close()
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	report, err := catalog.LoadIndexReport(ctx, pgClient, opts)
+	if err != nil {
+		http.Error(w, "Ошибка проверки индексов: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	report.Localize(locale)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// CheckForeignKeys находит внешние ключи без индекса и возвращает команды
+// создания индексов, упорядоченные по оценке влияния
+func (h *Handler) CheckForeignKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CatalogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Ошибка парсинга JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	locale, err := resolveLocale(r, req.Locale)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pgClient, err := postgres.NewClient(req.DBConfig.ConnectionString())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Ошибка подключения к БД: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer pgClient.Close()
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	report, err := catalog.LoadForeignKeyReport(ctx, pgClient)
+	if err != nil {
+		http.Error(w, "Ошибка проверки внешних ключей: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	report.Localize(locale)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// HealthRequest — запрос на проверку здоровья базы
+type HealthRequest struct {
+	DBConfig
+	// LongTransactionSeconds — порог долгой транзакции, по умолчанию
+	// health.DefaultLongTransaction
+	LongTransactionSeconds int    `json:"long_transaction_seconds,omitempty"`
+	Locale                 string `json:"locale,omitempty"`
+}
+
+// CheckHealth проверяет здоровье базы и возвращает отчет с оценкой
+func (h *Handler) CheckHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req HealthRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Ошибка парсинга JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	locale, err := resolveLocale(r, req.Locale)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pgClient, err := postgres.NewClient(req.DBConfig.ConnectionString())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Ошибка подключения к БД: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer pgClient.Close()
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	report, err := health.Run(ctx, pgClient, health.Options{
+		LongTransaction: time.Duration(req.LongTransactionSeconds) * time.Second,
+	})
+	if err != nil {
+		http.Error(w, "Ошибка проверки здоровья базы: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	report.Localize(locale)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// TuningRequest — запрос рекомендаций по параметрам сервера
+type TuningRequest struct {
+	DBConfig
+	Hardware recommendation.Hardware `json:"hardware"`
+	Locale   string                  `json:"locale,omitempty"`
+}
+
+// TuneSettings рекомендует значения параметров сервера по pg_settings,
+// сведениям о железе и планам этой базы из истории анализов
+func (h *Handler) TuneSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req TuningRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Ошибка парсинга JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	locale, err := resolveLocale(r, req.Locale)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch req.Hardware.Storage {
+	case "", recommendation.StorageSSD, recommendation.StorageHDD:
+	default:
+		http.Error(w, "Неизвестный тип хранилища: "+req.Hardware.Storage, http.StatusBadRequest)
+		return
+	}
+
+	opts := tuning.Options{Hardware: req.Hardware}
+	if h.history != nil {
+		plans, err := tuning.HistoryPlans(h.history, req.DBConfig.Database())
+		if err != nil {
+			log.Printf("Не удалось прочитать планы из истории: %v", err)
+		}
+		opts.Plans = plans
+	}
+
+	pgClient, err := postgres.NewClient(req.DBConfig.ConnectionString())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Ошибка подключения к БД: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer pgClient.Close()
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	report, err := tuning.Advise(ctx, pgClient, opts)
+	if err != nil {
+		http.Error(w, "Ошибка чтения параметров сервера: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	report.Localize(locale)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
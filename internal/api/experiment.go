@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"sql-optimizer/internal/experiment"
+	"sql-optimizer/internal/postgres"
+)
+
+// ExperimentRequest — запрос на эксперимент с планировщиком
+type ExperimentRequest struct {
+	DBConfig
+	Query string `json:"query"`
+	// Matrix — параметры и их значения; если не задана, каждый
+	// переключатель по умолчанию проверяется отдельно
+	Matrix []experiment.Dimension `json:"matrix,omitempty"`
+	// Analyze — выполнять запрос в каждом варианте, чтобы сравнить
+	// стоимость с фактическим временем
+	Analyze bool `json:"analyze,omitempty"`
+	// TimeoutMs — statement_timeout одного варианта в миллисекундах
+	TimeoutMs int    `json:"timeout_ms,omitempty"`
+	Locale    string `json:"locale,omitempty"`
+}
+
+// ExperimentPlan строит план запроса под разными параметрами планировщика
+// и показывает альтернативные планы и ошибку модели стоимости
+func (h *Handler) ExperimentPlan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ExperimentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Ошибка парсинга JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	locale, err := resolveLocale(r, req.Locale)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Query == "" {
+		http.Error(w, "Не указан запрос", http.StatusBadRequest)
+		return
+	}
+	opts := experiment.Options{
+		Matrix:  req.Matrix,
+		Analyze: req.Analyze,
+		Timeout: time.Duration(req.TimeoutMs) * time.Millisecond,
+	}
+	if _, err := experiment.Variants(opts); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pgClient, err := postgres.NewClient(req.DBConfig.ConnectionString())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Ошибка подключения к БД: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer pgClient.Close()
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+	defer cancel()
+
+	report, err := experiment.Run(ctx, pgClient, req.Query, opts)
+	if err != nil {
+		http.Error(w, "Ошибка эксперимента: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	report.Localize(locale)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
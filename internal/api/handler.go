@@ -1,13 +1,25 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http" // Add this line
+	"sql-optimizer/internal/analysis"
 	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/history"
+	"sql-optimizer/internal/i18n"
 	"sql-optimizer/internal/postgres" // Add this line
+	"sql-optimizer/internal/render"
+	"sql-optimizer/internal/report"
+	"sql-optimizer/internal/workload"
+	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/lib/pq"
@@ -22,26 +34,41 @@ type DBConfig struct {
 	DBName   string `json:"db_name"`
 }
 
+// ConnectionString возвращает строку подключения для драйвера lib/pq
+func (c DBConfig) ConnectionString() string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		c.Host, c.Port, c.User, c.Password, c.DBName)
+}
+
+// Database возвращает адрес БД без учетных данных — под ним анализ
+// сохраняется в истории
+func (c DBConfig) Database() string {
+	return fmt.Sprintf("%s:%s/%s", c.Host, c.Port, c.DBName)
+}
+
 // AnalyzeRequest представляет структуру запроса для анализа
 type AnalyzeRequest struct {
 	DBConfig
 	Query string `json:"query"`
+	// Locale — язык находок и объяснений; если не задан, берется
+	// из заголовка Accept-Language
+	Locale string `json:"locale,omitempty"`
 }
 
 type Handler struct {
+	history history.Store
 }
 
-func NewHandler() *Handler {
-	return &Handler{}
+// NewHandler создает обработчики API; если store равен nil,
+// история анализов не сохраняется
+func NewHandler(store history.Store) *Handler {
+	return &Handler{history: store}
 }
 
 // getDB открывает и настраивает соединение с БД.
 // Важно: эта функция не закрывает соединение, вызывающий код должен это сделать.
 func getDB(config DBConfig) (*sql.DB, error) {
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		config.Host, config.Port, config.User, config.Password, config.DBName)
-
-	db, err := sql.Open("postgres", connStr)
+	db, err := sql.Open("postgres", config.ConnectionString())
 	if err != nil {
 		return nil, fmt.Errorf("ошибка при вызове sql.Open: %w", err)
 	}
@@ -84,48 +111,405 @@ func (h *Handler) ConnectDB(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Успешное подключение к базе данных!"})
 }
 
-// AnalyzeQuery открывает соединение, анализирует запрос и закрывает соединение.
+// AnalyzeQuery анализирует запрос и возвращает результат в формате JSON.
 func (h *Handler) AnalyzeQuery(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeAnalyzeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	plan, analysisResult, err := runAnalysis(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Ошибка сохранения в историю не должна лишать пользователя результата
+	if h.history != nil {
+		record, err := h.history.Save(history.NewRecord(req.Database(), req.Query, plan, analysisResult))
+		if err != nil {
+			log.Printf("Не удалось сохранить анализ в историю: %v", err)
+		} else {
+			w.Header().Set("X-History-ID", record.ID)
+		}
+	}
+
+	// Возвращаем полный результат анализа в формате JSON
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(analysisResult); err != nil {
+		http.Error(w, "Ошибка кодирования JSON: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// DownloadHTMLReport анализирует запрос и отдает результат в виде
+// самодостаточного HTML-файла для тех, кто не работает с веб-интерфейсом.
+func (h *Handler) DownloadHTMLReport(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeAnalyzeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	plan, analysisResult, err := runAnalysis(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Рендерим в буфер, чтобы при ошибке шаблона не отдать обрезанный файл
+	var buf bytes.Buffer
+	analysis := report.Analysis{Query: req.Query, Plan: plan, Result: analysisResult}
+	if err := report.HTML(&buf, []report.Analysis{analysis}, req.Locale); err != nil {
+		http.Error(w, "Ошибка формирования отчета: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("sql-optimizer-%s.html", time.Now().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Write(buf.Bytes())
+}
+
+// ListHistory возвращает прошлые анализы, новые первыми. Параметры
+// fingerprint, database, since и until (RFC 3339) и limit сужают выборку.
+// Планы в списке не передаются, их можно получить по идентификатору.
+func (h *Handler) ListHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.history == nil {
+		http.Error(w, "История анализов отключена", http.StatusNotFound)
+		return
+	}
+
+	filter, err := parseHistoryFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	records, err := h.history.List(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for i := range records {
+		records[i].Plan = nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(records)
+}
+
+// HistoryRecord отдает (GET) или удаляет (DELETE) анализ из истории
+// по адресу /api/history/{id}
+func (h *Handler) HistoryRecord(w http.ResponseWriter, r *http.Request) {
+	if h.history == nil {
+		http.Error(w, "История анализов отключена", http.StatusNotFound)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/history/")
+
+	switch r.Method {
+	case http.MethodGet:
+		record, err := h.history.Get(id)
+		if err != nil {
+			writeHistoryError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(record)
+
+	case http.MethodDelete:
+		if err := h.history.Delete(id); err != nil {
+			writeHistoryError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+	}
+}
+
+// QueryTrend возвращает ряд запусков одного запроса (параметр fingerprint)
+// на одной БД (параметр database) со временем выполнения, стоимостью,
+// строками и формой плана, а также найденные регрессии и смены плана.
+// Параметры window и threshold задают скользящую базовую линию, остальные
+// совпадают с ListHistory.
+func (h *Handler) QueryTrend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.history == nil {
+		http.Error(w, "История анализов отключена", http.StatusNotFound)
+		return
+	}
+
+	filter, err := parseHistoryFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.QueryFingerprint == "" {
+		http.Error(w, "Не указан параметр fingerprint", http.StatusBadRequest)
+		return
+	}
+	// Запуски на разных БД нельзя сводить в одну базовую линию: данные
+	// и планы там разные
+	if filter.Database == "" {
+		http.Error(w, "Не указан параметр database", http.StatusBadRequest)
+		return
+	}
+
+	opts := history.DefaultTrendOptions
+	params := r.URL.Query()
+	if raw := params.Get("window"); raw != "" {
+		window, err := strconv.Atoi(raw)
+		if err != nil || window < 1 {
+			http.Error(w, fmt.Sprintf("Неверное значение window: %s", raw), http.StatusBadRequest)
+			return
+		}
+		opts.Window = window
+		opts.MinSamples = min(opts.MinSamples, window)
+	}
+	if raw := params.Get("threshold"); raw != "" {
+		threshold, err := strconv.ParseFloat(raw, 64)
+		if err != nil || threshold <= 1 {
+			http.Error(w, fmt.Sprintf("Неверное значение threshold (должно быть больше 1): %s", raw), http.StatusBadRequest)
+			return
+		}
+		opts.Threshold = threshold
+	}
+
+	trend, err := history.LoadTrend(h.history, filter, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trend)
+}
+
+// parseHistoryFilter разбирает параметры выборки из истории
+func parseHistoryFilter(r *http.Request) (history.Filter, error) {
+	params := r.URL.Query()
+	filter := history.Filter{
+		QueryFingerprint: params.Get("fingerprint"),
+		Database:         params.Get("database"),
+	}
+
+	for _, p := range []struct {
+		name  string
+		value *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		if raw := params.Get(p.name); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				return filter, fmt.Errorf("Неверное время в параметре %s: %v", p.name, err)
+			}
+			*p.value = t
+		}
+	}
+
+	if raw := params.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 0 {
+			return filter, fmt.Errorf("Неверное значение limit: %s", raw)
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+// writeHistoryError отвечает 404 для отсутствующей записи и 500 для прочих ошибок
+func writeHistoryError(w http.ResponseWriter, err error) {
+	if errors.Is(err, history.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// WorkloadRequest — запрос на анализ нагрузки из pg_stat_statements
+type WorkloadRequest struct {
+	DBConfig
+	OrderBy string `json:"order_by,omitempty"` // total_time, mean_time, calls, shared_blks_read
+	TopN    int    `json:"top_n,omitempty"`
+	Locale  string `json:"locale,omitempty"`
+}
+
+// AnalyzeWorkload ранжирует операторы из pg_stat_statements, анализирует
+// планы самых тяжелых и возвращает сводные находки и индексы
+func (h *Handler) AnalyzeWorkload(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
 		return
 	}
 
-	var req AnalyzeRequest
+	var req WorkloadRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, fmt.Sprintf("Ошибка парсинга JSON: %v", err), http.StatusBadRequest)
 		return
 	}
+	locale, err := resolveLocale(r, req.Locale)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	// Создаем клиент к БД, который будет закрыт в конце
-	pgClient, err := postgres.NewClient(fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		req.DBConfig.Host, req.DBConfig.Port, req.DBConfig.User, req.DBConfig.Password, req.DBConfig.DBName))
+	pgClient, err := postgres.NewClient(req.DBConfig.ConnectionString())
 	if err != nil {
-		http.Error(w, "Ошибка подключения к БД: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Ошибка подключения к БД: %v", err), http.StatusInternalServerError)
 		return
 	}
 	defer pgClient.Close()
 
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+	defer cancel()
+
+	result, err := workload.Analyze(ctx, pgClient, workload.Options{OrderBy: req.OrderBy, TopN: req.TopN, Locale: locale})
+	if errors.Is(err, postgres.ErrNoStatStatements) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Ошибка анализа нагрузки: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// planFormat — формат, в котором можно получить изображение плана
+type planFormat struct {
+	contentType string
+	render      func(io.Writer, []analyzer.PlanNode) error
+}
+
+// RenderPlanGraph анализирует запрос и отдает схему плана: SVG
+// (по умолчанию) или исходник Graphviz при ?format=dot.
+func (h *Handler) RenderPlanGraph(w http.ResponseWriter, r *http.Request) {
+	servePlan(w, r, map[string]planFormat{
+		"svg": {"image/svg+xml", render.SVG},
+		"dot": {"text/vnd.graphviz; charset=utf-8", render.DOT},
+	})
+}
+
+// RenderFlameGraph анализирует запрос и отдает flame graph собственного
+// времени узлов: SVG (по умолчанию) или свернутые стеки при ?format=folded.
+func (h *Handler) RenderFlameGraph(w http.ResponseWriter, r *http.Request) {
+	servePlan(w, r, map[string]planFormat{
+		"svg":    {"image/svg+xml", render.FlameGraph},
+		"folded": {"text/plain; charset=utf-8", render.Folded},
+	})
+}
+
+// RenderTimeline анализирует запрос и отдает временную диаграмму узлов в SVG.
+func (h *Handler) RenderTimeline(w http.ResponseWriter, r *http.Request) {
+	servePlan(w, r, map[string]planFormat{
+		"svg": {"image/svg+xml", render.Timeline},
+	})
+}
+
+// servePlan анализирует запрос и отдает план в формате из параметра
+// ?format=; без параметра используется SVG.
+func servePlan(w http.ResponseWriter, r *http.Request, formats map[string]planFormat) {
+	req, ok := decodeAnalyzeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = "svg"
+	}
+	format, ok := formats[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Неизвестный формат: %s", name), http.StatusBadRequest)
+		return
+	}
+
+	plan, _, err := runAnalysis(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := format.render(&buf, plan); err != nil {
+		http.Error(w, "Ошибка построения изображения плана: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.contentType)
+	w.Write(buf.Bytes())
+}
+
+// decodeAnalyzeRequest проверяет метод и разбирает тело запроса на анализ.
+// При ошибке ответ уже записан и возвращается false.
+func decodeAnalyzeRequest(w http.ResponseWriter, r *http.Request) (AnalyzeRequest, bool) {
+	var req AnalyzeRequest
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+		return req, false
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Ошибка парсинга JSON: %v", err), http.StatusBadRequest)
+		return req, false
+	}
+
+	locale, err := resolveLocale(r, req.Locale)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return req, false
+	}
+	req.Locale = locale
+	return req, true
+}
+
+// resolveLocale выбирает язык ответа: явно запрошенный или, если он не
+// задан, по заголовку Accept-Language
+func resolveLocale(r *http.Request, requested string) (string, error) {
+	if requested == "" {
+		return i18n.FromAcceptLanguage(r.Header.Get("Accept-Language")), nil
+	}
+	if locale := i18n.Match(requested); locale != "" {
+		return locale, nil
+	}
+	return "", fmt.Errorf("Неподдерживаемый язык: %s", requested)
+}
+
+// runAnalysis открывает соединение, получает план выполнения запроса,
+// анализирует его и закрывает соединение.
+func runAnalysis(req AnalyzeRequest) ([]analyzer.PlanNode, *analyzer.AnalysisResult, error) {
+	pgClient, err := postgres.NewClient(req.DBConfig.ConnectionString())
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка подключения к БД: %v", err)
+	}
+	defer pgClient.Close()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	// Получаем JSON план выполнения
 	planJSON, err := pgClient.GetExplainPlan(ctx, req.Query)
 	if err != nil {
-		http.Error(w, "Ошибка получения плана EXPLAIN: "+err.Error(), http.StatusInternalServerError)
-		return
+		return nil, nil, fmt.Errorf("ошибка получения плана EXPLAIN: %v", err)
 	}
 
 	// Анализируем план и получаем результат
-	analysisResult, err := analyzer.AnalyzePlan(planJSON)
+	plan, err := analyzer.ParseExplainJSON(planJSON)
 	if err != nil {
-		http.Error(w, "Ошибка анализа плана: "+err.Error(), http.StatusInternalServerError)
-		return
+		return nil, nil, fmt.Errorf("ошибка анализа плана: %v", err)
 	}
 
-	// Возвращаем полный результат анализа в формате JSON
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(analysisResult); err != nil {
-		http.Error(w, "Ошибка кодирования JSON: "+err.Error(), http.StatusInternalServerError)
+	result := analyzer.AnalyzeNodes(plan)
+	for _, err := range analysis.Enrich(ctx, pgClient, req.Query, plan, result, analysis.Options{Locale: req.Locale}) {
+		log.Println(err)
 	}
+	return plan, result, nil
 }
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/hints"
+	"sql-optimizer/internal/postgres"
+)
+
+// HintsRequest — запрос на подсказки pg_hint_plan для целевого плана
+type HintsRequest struct {
+	DBConfig
+	Query string `json:"query"`
+	// Plan — целевой план в формате EXPLAIN (FORMAT JSON)
+	Plan json.RawMessage `json:"plan"`
+	// Settings — параметры, под которыми получен план; кроме enable_*,
+	// они передаются подсказками Set
+	Settings []postgres.PlannerToggle `json:"settings,omitempty"`
+	// Verify — построить план с подсказками и сравнить его с целевым
+	Verify bool `json:"verify,omitempty"`
+}
+
+// GeneratePlanHints составляет подсказки pg_hint_plan, которые заставляют
+// планировщик выбрать переданный план, и по запросу проверяет их
+func (h *Handler) GeneratePlanHints(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req HintsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Ошибка парсинга JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.Query == "" || len(req.Plan) == 0 {
+		http.Error(w, "Не указан запрос или целевой план", http.StatusBadRequest)
+		return
+	}
+	target, err := analyzer.ParseExplainJSON(string(req.Plan))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Ошибка парсинга плана: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	var src hints.Source
+	if req.Verify {
+		pgClient, err := postgres.NewClient(req.DBConfig.ConnectionString())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Ошибка подключения к БД: %v", err), http.StatusInternalServerError)
+			return
+		}
+		defer pgClient.Close()
+		src = pgClient
+	}
+	result := hints.Suggest(ctx, src, req.Query, target, req.Settings)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
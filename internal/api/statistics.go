@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/postgres"
+	"sql-optimizer/internal/statistics"
+)
+
+// StatisticsRequest — запрос на поиск ошибок оценок, которые исправляет
+// статистика
+type StatisticsRequest struct {
+	DBConfig
+	Query string `json:"query"`
+	// Verify — проверить рекомендации в откатываемой транзакции; откат не
+	// отменяет обновления last_analyze и n_live_tup при ANALYZE
+	Verify bool   `json:"verify,omitempty"`
+	Locale string `json:"locale,omitempty"`
+}
+
+// AdviseStatistics выполняет запрос в безопасном режиме и предлагает
+// статистику, которая исправит ошибки оценок его плана
+func (h *Handler) AdviseStatistics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не разрешен", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req StatisticsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Ошибка парсинга JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+	locale, err := resolveLocale(r, req.Locale)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Query == "" {
+		http.Error(w, "Не указан запрос", http.StatusBadRequest)
+		return
+	}
+
+	pgClient, err := postgres.NewClient(req.DBConfig.ConnectionString())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Ошибка подключения к БД: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer pgClient.Close()
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+	defer cancel()
+
+	planJSON, err := pgClient.GetExplainPlanSafe(ctx, req.Query, statistics.DefaultTimeout)
+	if err != nil {
+		http.Error(w, "Ошибка получения плана: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	plan, err := analyzer.ParseExplainJSON(planJSON)
+	if err != nil {
+		http.Error(w, "Ошибка парсинга плана: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	report, err := statistics.Advise(ctx, pgClient, req.Query, plan, statistics.Options{Verify: req.Verify})
+	if err != nil {
+		http.Error(w, "Ошибка чтения статистики: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	report.Localize(locale)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,123 @@
+// Package baseline хранит базовую линию — уже известные находки, которые
+// повторный анализ не показывает, чтобы в CI падали только новые. Находка
+// узнается по отпечатку запроса, правилу и таблице: другие константы,
+// пробелы и комментарии в запросе не делают ее новой.
+package baseline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"sql-optimizer/internal/analyzer"
+)
+
+// Version — версия формата файла базовой линии
+const Version = 1
+
+// Finding — известная находка
+type Finding struct {
+	QueryFingerprint string `json:"query_fingerprint"`
+	RuleID           string `json:"rule_id"`
+	Relation         string `json:"relation,omitempty"`
+	// Query — нормализованный текст запроса, чтобы файл можно было читать
+	Query string `json:"query,omitempty"`
+	// Note — почему находку можно не исправлять; заполняется вручную
+	// и сохраняется при обновлении базовой линии
+	Note string `json:"note,omitempty"`
+}
+
+// key — то, по чему находки сравниваются
+func (f Finding) key() string {
+	return f.QueryFingerprint + "|" + f.RuleID + "|" + f.Relation
+}
+
+// Baseline — файл известных находок
+type Baseline struct {
+	Version  int       `json:"version"`
+	Findings []Finding `json:"findings"`
+}
+
+// Load читает базовую линию из файла
+func Load(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения базовой линии %s: %v", path, err)
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("ошибка разбора базовой линии %s: %v", path, err)
+	}
+	if b.Version != Version {
+		return nil, fmt.Errorf("неподдерживаемая версия базовой линии %s: %d", path, b.Version)
+	}
+	return &b, nil
+}
+
+// Build составляет базовую линию из находок результатов анализа.
+// Пояснения из previous сохраняются у тех находок, что остались.
+func Build(results []*analyzer.AnalysisResult, previous *Baseline) *Baseline {
+	notes := make(map[string]string)
+	if previous != nil {
+		for _, f := range previous.Findings {
+			notes[f.key()] = f.Note
+		}
+	}
+
+	b := &Baseline{Version: Version, Findings: []Finding{}}
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if result == nil || result.QueryFingerprint == "" {
+			continue
+		}
+		for _, problem := range result.ProblematicOperations {
+			f := newFinding(result, problem)
+			if seen[f.key()] {
+				continue
+			}
+			seen[f.key()] = true
+			f.Query, f.Note = result.NormalizedQuery, notes[f.key()]
+			b.Findings = append(b.Findings, f)
+		}
+	}
+	slices.SortFunc(b.Findings, func(x, y Finding) int { return strings.Compare(x.key(), y.key()) })
+	return b
+}
+
+// Write записывает базовую линию в JSON с отступами, удобном для review
+func (b *Baseline) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(b)
+}
+
+// Suppress убирает из результата известные находки и возвращает, сколько
+// их было. Рекомендации результата остаются: они не привязаны к находкам.
+func (b *Baseline) Suppress(result *analyzer.AnalysisResult) int {
+	if result == nil || result.QueryFingerprint == "" || len(b.Findings) == 0 {
+		return 0
+	}
+	known := make(map[string]bool, len(b.Findings))
+	for _, f := range b.Findings {
+		known[f.key()] = true
+	}
+
+	kept := result.ProblematicOperations[:0]
+	for _, problem := range result.ProblematicOperations {
+		if !known[newFinding(result, problem).key()] {
+			kept = append(kept, problem)
+		}
+	}
+	suppressed := len(result.ProblematicOperations) - len(kept)
+	result.ProblematicOperations = kept
+	return suppressed
+}
+
+// newFinding — находка базовой линии для проблемной операции
+func newFinding(result *analyzer.AnalysisResult, problem analyzer.ProblematicOperation) Finding {
+	return Finding{QueryFingerprint: result.QueryFingerprint, RuleID: problem.RuleID, Relation: problem.Relation}
+}
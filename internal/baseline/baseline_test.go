@@ -0,0 +1,78 @@
+package baseline
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sql-optimizer/internal/analyzer"
+)
+
+func testResult() *analyzer.AnalysisResult {
+	return &analyzer.AnalysisResult{
+		QueryFingerprint: "0123456789abcdef",
+		NormalizedQuery:  "SELECT * FROM orders WHERE status = $1",
+		ProblematicOperations: []analyzer.ProblematicOperation{
+			{RuleID: analyzer.RuleSeqScan, Relation: "orders"},
+			{RuleID: analyzer.RuleSeqScan, Relation: "users"},
+		},
+	}
+}
+
+func TestBuildAndSuppress(t *testing.T) {
+	previous := &Baseline{Version: Version, Findings: []Finding{
+		{QueryFingerprint: "0123456789abcdef", RuleID: analyzer.RuleSeqScan, Relation: "orders", Note: "orders маленькая"},
+		{QueryFingerprint: "fedcba9876543210", RuleID: analyzer.RuleSeqScan, Note: "запрос удален"},
+	}}
+	// Та же находка дважды записывается один раз
+	b := Build([]*analyzer.AnalysisResult{testResult(), testResult(), nil}, previous)
+	if len(b.Findings) != 2 || b.Findings[0].Relation != "orders" || b.Findings[0].Note != "orders маленькая" ||
+		b.Findings[1].Note != "" || b.Findings[1].Query != "SELECT * FROM orders WHERE status = $1" {
+		t.Fatalf("Неверная базовая линия: %+v", b.Findings)
+	}
+
+	// Известна только находка по orders: по users она новая
+	b.Findings = b.Findings[:1]
+	result := testResult()
+	if n := b.Suppress(result); n != 1 {
+		t.Errorf("Пропущено %d находок, ожидали одну", n)
+	}
+	if len(result.ProblematicOperations) != 1 || result.ProblematicOperations[0].Relation != "users" {
+		t.Errorf("Должна остаться находка по users: %+v", result.ProblematicOperations)
+	}
+
+	// Без отпечатка находку не узнать
+	result = testResult()
+	result.QueryFingerprint = ""
+	if n := b.Suppress(result); n != 0 || len(result.ProblematicOperations) != 2 {
+		t.Errorf("Результат без отпечатка не должен меняться: %+v", result.ProblematicOperations)
+	}
+}
+
+func TestWriteAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Build([]*analyzer.AnalysisResult{testResult()}, nil).Write(file); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	file.Close()
+
+	b, err := Load(path)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if len(b.Findings) != 2 || b.Findings[0].QueryFingerprint != "0123456789abcdef" {
+		t.Errorf("Неверно прочитана базовая линия: %+v", b.Findings)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 2, "findings": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "неподдерживаемая версия") {
+		t.Errorf("Ожидали ошибку о версии, получили %v", err)
+	}
+}
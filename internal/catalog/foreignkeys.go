@@ -0,0 +1,280 @@
+package catalog
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+)
+
+// SmallTableRows — таблицы меньше этого числа строк читаются целиком
+// быстро, и индекс внешнего ключа для них мало что дает
+const SmallTableRows = 1000
+
+// ForeignKeySource — источник внешних ключей, индексов и статистики
+// таблиц; его реализует *postgres.Client
+type ForeignKeySource interface {
+	ForeignKeys(ctx context.Context) ([]postgres.ForeignKey, error)
+	Indexes(ctx context.Context) ([]postgres.Index, error)
+	Tables(ctx context.Context) ([]postgres.Table, error)
+}
+
+// MissingForeignKeyIndex — внешний ключ, столбцы которого не являются
+// ведущими столбцами ни одного индекса
+type MissingForeignKeyIndex struct {
+	postgres.ForeignKey
+	TableRows  int64 `json:"table_rows"`
+	TableBytes int64 `json:"table_bytes"`
+	// SeqScans — последовательные сканирования ссылающейся таблицы
+	SeqScans int64 `json:"seq_scans"`
+	// ParentWrites — удаления и изменения строк родительской таблицы:
+	// каждое проверяет ключ чтением ссылающейся таблицы
+	ParentWrites int64  `json:"parent_writes"`
+	Severity     string `json:"severity"` // "high", "medium", "low"
+	// Score — грубая оценка числа строк, которые читаются из-за отсутствия
+	// индекса; по ней упорядочен отчет
+	Score       float64                 `json:"score"`
+	Index       analyzer.IndexCandidate `json:"index"`
+	DDL         string                  `json:"ddl"`
+	Message     i18n.Message            `json:"message"`
+	Description string                  `json:"description"`
+}
+
+// ForeignKeyReport — результат проверки индексов внешних ключей
+type ForeignKeyReport struct {
+	Missing []MissingForeignKeyIndex `json:"missing"`
+}
+
+// LoadForeignKeyReport читает каталог из src и проверяет внешние ключи
+func LoadForeignKeyReport(ctx context.Context, src ForeignKeySource) (*ForeignKeyReport, error) {
+	keys, err := src.ForeignKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := src.Indexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := src.Tables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return CheckForeignKeys(keys, indexes, tables), nil
+}
+
+// CheckForeignKeys находит внешние ключи без индекса. Ключ считается
+// проиндексированным, если его столбцы в любом порядке образуют ведущие
+// столбцы действительного B-tree индекса без условия. Серьезность зависит
+// от размера ссылающейся таблицы и от того, меняются ли строки родительской
+// таблицы и читается ли ссылающаяся таблица последовательно.
+func CheckForeignKeys(keys []postgres.ForeignKey, indexes []postgres.Index, tables []postgres.Table) *ForeignKeyReport {
+	report := &ForeignKeyReport{Missing: []MissingForeignKeyIndex{}}
+	// Одноименные таблицы могут быть в разных схемах
+	stats := make(map[string]postgres.Table, len(tables))
+	for _, table := range tables {
+		stats[table.Schema+"."+table.Name] = table
+	}
+
+	for _, key := range keys {
+		if indexedForeignKey(key, indexes) {
+			continue
+		}
+		child, parent := stats[key.Schema+"."+key.Table], stats[key.ReferencedSchema+"."+key.ReferencedTable]
+		missing := MissingForeignKeyIndex{
+			ForeignKey:   key,
+			TableRows:    child.Rows,
+			TableBytes:   child.SizeBytes,
+			SeqScans:     child.SeqScans,
+			ParentWrites: parent.Deletes + parent.Updates,
+			Index:        analyzer.IndexCandidate{Schema: key.Schema, Table: key.Table, Columns: key.Columns},
+			Message: i18n.New(i18n.ForeignKeyUnindexed, "constraint", key.Name, "table", key.Table,
+				"columns", strings.Join(key.Columns, ", "), "referenced", key.ReferencedTable),
+		}
+		missing.DDL = missing.Index.DDL()
+		missing.Score = float64(child.Rows) * float64(missing.ParentWrites+child.SeqScans+1)
+		switch {
+		case child.Rows < SmallTableRows:
+			missing.Severity = "low"
+		case missing.ParentWrites > 0 || child.SeqScans > 0:
+			missing.Severity = "high"
+		default:
+			missing.Severity = "medium"
+		}
+		report.Missing = append(report.Missing, missing)
+	}
+
+	sort.SliceStable(report.Missing, func(i, j int) bool { return report.Missing[i].Score > report.Missing[j].Score })
+	report.Localize(i18n.DefaultLocale)
+	return report
+}
+
+// Localize формирует тексты на языке locale
+func (r *ForeignKeyReport) Localize(locale string) {
+	for i := range r.Missing {
+		r.Missing[i].Description = r.Missing[i].Message.Format(locale)
+	}
+}
+
+// indexedForeignKey сообщает, есть ли индекс, которым PostgreSQL найдет
+// ссылающиеся строки по значению ключа
+func indexedForeignKey(key postgres.ForeignKey, indexes []postgres.Index) bool {
+	for _, index := range indexes {
+		if index.Schema != key.Schema || index.Table != key.Table || !index.Valid ||
+			index.Method != "btree" || index.Predicate != "" || len(index.Columns) < len(key.Columns) {
+			continue
+		}
+		leading := true
+		for _, column := range index.Columns[:len(key.Columns)] {
+			if !slices.Contains(key.Columns, column) {
+				leading = false
+				break
+			}
+		}
+		if leading {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsForeignKeys сообщает, есть ли в плане узлы, которые стоит связать
+// с внешними ключами без индекса: Seq Scan внутри соединения или удаление
+// и изменение строк. По нему вызывающий код решает, читать ли каталог.
+func NeedsForeignKeys(plan []analyzer.PlanNode) bool {
+	var walk func(node analyzer.PlanNode, inJoin bool) bool
+	walk = func(node analyzer.PlanNode, inJoin bool) bool {
+		if node.NodeType == "Seq Scan" && inJoin || node.NodeType == "ModifyTable" && modifiesKeys(node) {
+			return true
+		}
+		inJoin = inJoin || isJoin(node)
+		for _, child := range node.Plans {
+			if walk(child, inJoin) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, node := range plan {
+		if walk(node, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// LinkForeignKeys связывает находки плана с внешними ключами без индекса.
+// Seq Scan ссылающейся таблицы в соединении с родительской получает ссылку
+// на ключ и, если индекс по фильтру не предложен, индекс по столбцам ключа.
+// Для удаления и изменения строк родительской таблицы добавляется находка
+// RuleUnindexedForeignKey: проверка ключа читает ссылающуюся таблицу
+// целиком, хотя в плане этого не видно. Тексты нужно сформировать заново
+// через Localize результата.
+func LinkForeignKeys(plan []analyzer.PlanNode, result *analyzer.AnalysisResult, missing []MissingForeignKeyIndex) {
+	if len(missing) == 0 {
+		return
+	}
+
+	var walk func(node analyzer.PlanNode, joined []string)
+	walk = func(node analyzer.PlanNode, joined []string) {
+		switch {
+		case node.NodeType == "Seq Scan":
+			for _, key := range missing {
+				if key.Table == node.RelationName && slices.Contains(joined, key.ReferencedTable) {
+					linkSeqScan(result, key)
+				}
+			}
+		case node.NodeType == "ModifyTable" && modifiesKeys(node):
+			for _, key := range missing {
+				if key.ReferencedTable == node.RelationName {
+					addCascade(result, key)
+				}
+			}
+		}
+		if isJoin(node) {
+			joined = append(append([]string(nil), joined...), relations(node)...)
+		}
+		for _, child := range node.Plans {
+			walk(child, joined)
+		}
+	}
+	for _, node := range plan {
+		walk(node, nil)
+	}
+}
+
+// linkSeqScan отмечает находку Seq Scan ссылающейся таблицы ключом key
+func linkSeqScan(result *analyzer.AnalysisResult, key MissingForeignKeyIndex) {
+	for i := range result.ProblematicOperations {
+		problem := &result.ProblematicOperations[i]
+		if problem.RuleID != analyzer.RuleSeqScan || problem.Relation != key.Table || problem.ForeignKey != nil {
+			continue
+		}
+		problem.ForeignKey = foreignKeyLink(key)
+		if problem.Index == nil {
+			index := key.Index
+			problem.Index = &index
+		}
+		result.RecommendationMessages = append(result.RecommendationMessages,
+			i18n.New(i18n.RecommendForeignKeyIndex, "constraint", key.Name, "ddl", key.DDL))
+		return
+	}
+}
+
+// addCascade добавляет находку о проверке ключа при изменении родительской таблицы
+func addCascade(result *analyzer.AnalysisResult, key MissingForeignKeyIndex) {
+	for _, problem := range result.ProblematicOperations {
+		if problem.ForeignKey != nil && problem.ForeignKey.Constraint == key.Name && problem.RuleID == analyzer.RuleUnindexedForeignKey {
+			return
+		}
+	}
+	index := key.Index
+	result.ProblematicOperations = append(result.ProblematicOperations, analyzer.ProblematicOperation{
+		RuleID:   analyzer.RuleUnindexedForeignKey,
+		NodeType: "ModifyTable",
+		Relation: key.Table,
+		DescriptionMessage: i18n.New(i18n.ProblemForeignKeyCascade,
+			"referenced", key.ReferencedTable, "constraint", key.Name, "table", key.Table),
+		RecommendationMessage: i18n.New(i18n.ProblemForeignKeyRecommendation, "ddl", key.DDL),
+		Severity:              key.Severity,
+		Index:                 &index,
+		ForeignKey:            foreignKeyLink(key),
+	})
+	result.RecommendationMessages = append(result.RecommendationMessages,
+		i18n.New(i18n.RecommendForeignKeyIndex, "constraint", key.Name, "ddl", key.DDL))
+}
+
+func foreignKeyLink(key MissingForeignKeyIndex) *analyzer.ForeignKeyLink {
+	return &analyzer.ForeignKeyLink{
+		Constraint: key.Name, Table: key.Table, Columns: key.Columns, ReferencedTable: key.ReferencedTable,
+	}
+}
+
+// modifiesKeys сообщает, удаляет или изменяет ли узел ModifyTable строки:
+// только тогда проверяются ссылающиеся на таблицу внешние ключи
+func modifiesKeys(node analyzer.PlanNode) bool {
+	return node.Operation == "Delete" || node.Operation == "Update"
+}
+
+func isJoin(node analyzer.PlanNode) bool {
+	switch node.NodeType {
+	case "Hash Join", "Nested Loop", "Merge Join":
+		return true
+	}
+	return false
+}
+
+// relations перечисляет таблицы, которые читает поддерево узла
+func relations(node analyzer.PlanNode) []string {
+	var names []string
+	if node.RelationName != "" {
+		names = append(names, node.RelationName)
+	}
+	for _, child := range node.Plans {
+		names = append(names, relations(child)...)
+	}
+	return names
+}
@@ -0,0 +1,130 @@
+package catalog
+
+import (
+	"testing"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/postgres"
+)
+
+func TestCheckForeignKeys(t *testing.T) {
+	keys := []postgres.ForeignKey{
+		{Schema: "public", Name: "orders_user_id_fkey", Table: "orders", Columns: []string{"user_id"}, ReferencedSchema: "public", ReferencedTable: "users"},
+		{Schema: "public", Name: "items_order_fkey", Table: "items", Columns: []string{"order_id", "shop_id"}, ReferencedSchema: "public", ReferencedTable: "orders"},
+		{Schema: "public", Name: "items_product_fkey", Table: "items", Columns: []string{"product_id"}, ReferencedSchema: "public", ReferencedTable: "products"},
+		{Schema: "public", Name: "notes_user_fkey", Table: "notes", Columns: []string{"user_id"}, ReferencedSchema: "public", ReferencedTable: "users"},
+	}
+	indexes := []postgres.Index{
+		// Столбцы ключа в другом порядке тоже подходят
+		{Schema: "public", Name: "items_shop_order_idx", Table: "items", Method: "btree", Valid: true,
+			Columns: []string{"shop_id", "order_id", "created_at"}},
+		// Частичный индекс и индекс, где user_id не ведущий, — не подходят
+		{Schema: "public", Name: "orders_paid_user_idx", Table: "orders", Method: "btree", Valid: true,
+			Columns: []string{"user_id"}, Predicate: "paid"},
+		{Schema: "public", Name: "orders_status_user_idx", Table: "orders", Method: "btree", Valid: true,
+			Columns: []string{"status", "user_id"}},
+	}
+	tables := []postgres.Table{
+		{Schema: "public", Name: "orders", Rows: 500000, SizeBytes: 64 << 20, SeqScans: 3},
+		{Schema: "public", Name: "users", Rows: 1000, Deletes: 20, Updates: 5},
+		{Schema: "public", Name: "items", Rows: 2000000},
+		{Schema: "public", Name: "notes", Rows: 100},
+		// Одноименная таблица в другой схеме не влияет на оценку
+		{Schema: "archive", Name: "notes", Rows: 5000000, SeqScans: 10},
+		{Schema: "archive", Name: "users", Deletes: 100000},
+	}
+
+	report := CheckForeignKeys(keys, indexes, tables)
+	if len(report.Missing) != 3 {
+		t.Fatalf("Ожидали 3 ключа без индекса, получили %+v", report.Missing)
+	}
+
+	orders := report.Missing[0]
+	if orders.Name != "orders_user_id_fkey" || orders.Severity != "high" || orders.ParentWrites != 25 ||
+		orders.DDL != "CREATE INDEX CONCURRENTLY idx_orders_user_id ON public.orders (user_id);" {
+		t.Errorf("Неверная находка для orders: %+v", orders)
+	}
+	if report.Missing[1].Name != "items_product_fkey" || report.Missing[1].Severity != "medium" {
+		t.Errorf("Ожидали items_product_fkey средней серьезности: %+v", report.Missing[1])
+	}
+	if report.Missing[2].Name != "notes_user_fkey" || report.Missing[2].Severity != "low" {
+		t.Errorf("Маленькая таблица должна давать низкую серьезность: %+v", report.Missing[2])
+	}
+
+	report.Localize("en")
+	if want := "Foreign key orders_user_id_fkey has no index on orders (user_id): deleting or updating rows of users and joins with it read orders in full"; report.Missing[0].Description != want {
+		t.Errorf("Неверное описание: %q", report.Missing[0].Description)
+	}
+}
+
+func TestCheckForeignKeysQuotedColumns(t *testing.T) {
+	keys := []postgres.ForeignKey{
+		{Schema: "public", Name: "payments_user_fkey", Table: "payments", Columns: []string{"userId"}, ReferencedSchema: "public", ReferencedTable: "users"},
+		{Schema: "public", Name: "payments_order_fkey", Table: "payments", Columns: []string{"order"}, ReferencedSchema: "public", ReferencedTable: "orders"},
+	}
+	// Имена столбцов индекса приходят из pg_attribute без кавычек, как и в
+	// ключе; индекс по выражению от столбца ключ не обслуживает
+	indexes := []postgres.Index{
+		{Schema: "public", Name: "payments_user_idx", Table: "payments", Method: "btree", Valid: true,
+			Columns: []string{"userId"}, Definition: `CREATE INDEX payments_user_idx ON public.payments USING btree ("userId")`},
+		{Schema: "public", Name: "payments_order_idx", Table: "payments", Method: "btree", Valid: true,
+			Columns: []string{`(("order" + 0))`}},
+	}
+
+	report := CheckForeignKeys(keys, indexes, []postgres.Table{{Schema: "public", Name: "payments", Rows: 100000}})
+	if len(report.Missing) != 1 || report.Missing[0].Name != "payments_order_fkey" {
+		t.Fatalf("Ожидали только payments_order_fkey без индекса: %+v", report.Missing)
+	}
+	if want := `CREATE INDEX CONCURRENTLY idx_payments_order ON public.payments ("order");`; report.Missing[0].DDL != want {
+		t.Errorf("Неверный DDL: %s", report.Missing[0].DDL)
+	}
+}
+
+func TestLinkForeignKeys(t *testing.T) {
+	missing := CheckForeignKeys([]postgres.ForeignKey{{Schema: "public", Name: "orders_user_id_fkey",
+		Table: "orders", Columns: []string{"user_id"}, ReferencedSchema: "public", ReferencedTable: "users"}},
+		nil, []postgres.Table{{Schema: "public", Name: "orders", Rows: 500000}}).Missing
+
+	join := []analyzer.PlanNode{{NodeType: "Hash Join", TotalCost: 3000, Plans: []analyzer.PlanNode{
+		{NodeType: "Seq Scan", RelationName: "orders", TotalCost: 2500},
+		{NodeType: "Hash", Plans: []analyzer.PlanNode{{NodeType: "Index Scan", RelationName: "users", TotalCost: 8}}},
+	}}}
+	if !NeedsForeignKeys(join) {
+		t.Fatal("Seq Scan в соединении требует проверки внешних ключей")
+	}
+	result := analyzer.AnalyzeNodes(join)
+	LinkForeignKeys(join, result, missing)
+	result.Localize("en")
+
+	var scan *analyzer.ProblematicOperation
+	for i := range result.ProblematicOperations {
+		if result.ProblematicOperations[i].RuleID == analyzer.RuleSeqScan {
+			scan = &result.ProblematicOperations[i]
+		}
+	}
+	if scan == nil || scan.ForeignKey == nil || scan.ForeignKey.Constraint != "orders_user_id_fkey" ||
+		scan.Index == nil || scan.Index.Name() != "idx_orders_user_id" {
+		t.Fatalf("Seq Scan по orders должен ссылаться на внешний ключ: %+v", scan)
+	}
+	want := "Create an index for foreign key orders_user_id_fkey: CREATE INDEX CONCURRENTLY idx_orders_user_id ON public.orders (user_id);"
+	if last := result.Recommendations[len(result.Recommendations)-1]; last != want {
+		t.Errorf("Неверная рекомендация: %q", last)
+	}
+
+	// Удаление пользователей проверяет ключ чтением orders
+	remove := []analyzer.PlanNode{{NodeType: "ModifyTable", Operation: "Delete", RelationName: "users",
+		Plans: []analyzer.PlanNode{{NodeType: "Index Scan", RelationName: "users"}}}}
+	result = analyzer.AnalyzeNodes(remove)
+	LinkForeignKeys(remove, result, missing)
+	if len(result.ProblematicOperations) != 1 || result.ProblematicOperations[0].RuleID != analyzer.RuleUnindexedForeignKey ||
+		result.ProblematicOperations[0].Relation != "orders" || result.ProblematicOperations[0].Severity != "medium" {
+		t.Errorf("Ожидали находку о проверке ключа при удалении: %+v", result.ProblematicOperations)
+	}
+
+	// Вставка и чтение без соединения каталог не требуют
+	insert := []analyzer.PlanNode{{NodeType: "ModifyTable", Operation: "Insert", RelationName: "orders",
+		Plans: []analyzer.PlanNode{{NodeType: "Seq Scan", RelationName: "orders"}}}}
+	if NeedsForeignKeys(insert) {
+		t.Error("Вставка и одиночный Seq Scan не требуют проверки внешних ключей")
+	}
+}
@@ -0,0 +1,255 @@
+// Package catalog проверяет схему базы по системному каталогу и
+// статистике: лишние индексы, недостающие индексы внешних ключей и т. п.
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+	"sql-optimizer/internal/quote"
+)
+
+// Виды проблем с индексами
+const (
+	IssueInvalid    = "invalid"
+	IssueDuplicate  = "duplicate"
+	IssueRedundant  = "redundant"
+	IssueUnused     = "unused"
+	IssueRarelyUsed = "rarely_used"
+)
+
+// DefaultRareScans — сколько сканирований с момента сброса статистики
+// считается почти нулевым использованием
+const DefaultRareScans = 50
+
+// IndexOptions — параметры проверки индексов
+type IndexOptions struct {
+	// RareScans — индексы с числом сканирований не больше этого считаются
+	// редко используемыми; 0 — только неиспользуемые
+	RareScans int64
+}
+
+// IndexIssue — индекс, который можно удалить
+type IndexIssue struct {
+	Kind       string `json:"kind"`
+	Schema     string `json:"schema"`
+	Index      string `json:"index"`
+	Table      string `json:"table"`
+	SizeBytes  int64  `json:"size_bytes"`
+	Scans      int64  `json:"scans"`
+	Definition string `json:"definition"`
+	// Other — индекс, из-за которого этот стал лишним
+	Other       string       `json:"other,omitempty"`
+	Message     i18n.Message `json:"message"`
+	Description string       `json:"description"`
+	DDL         string       `json:"ddl"`
+}
+
+// ProtectedIndex — индекс, который подошел бы под удаление, но на него
+// опираются ограничения или он обеспечивает уникальность
+type ProtectedIndex struct {
+	Index       string   `json:"index"`
+	Table       string   `json:"table"`
+	Kind        string   `json:"kind"` // проблема, которая была бы найдена
+	Constraints []string `json:"constraints,omitempty"`
+	Unique      bool     `json:"unique"`
+}
+
+// IndexReport — результат проверки индексов
+type IndexReport struct {
+	// StatsReset — с какого момента накоплены счетчики сканирований
+	StatsReset       *time.Time       `json:"stats_reset,omitempty"`
+	Issues           []IndexIssue     `json:"issues"`
+	Protected        []ProtectedIndex `json:"protected"`
+	ReclaimableBytes int64            `json:"reclaimable_bytes"`
+}
+
+// IndexSource — источник индексов и времени сброса статистики; его
+// реализует *postgres.Client
+type IndexSource interface {
+	Indexes(ctx context.Context) ([]postgres.Index, error)
+	StatsReset(ctx context.Context) (*time.Time, error)
+}
+
+// LoadIndexReport читает индексы из src и проверяет их
+func LoadIndexReport(ctx context.Context, src IndexSource, opts IndexOptions) (*IndexReport, error) {
+	indexes, err := src.Indexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reset, err := src.StatsReset(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := CheckIndexes(indexes, opts)
+	report.StatsReset = reset
+	return report, nil
+}
+
+// CheckIndexes находит недействительные, дублирующиеся, избыточные по
+// левому префиксу и неиспользуемые индексы. Каждый индекс попадает в отчет
+// один раз, по самой веской причине. Индексы, на которые опираются
+// ограничения (первичный ключ, UNIQUE, EXCLUDE, внешние ключи), и
+// уникальные индексы никогда не предлагаются к удалению — они
+// перечисляются в Protected.
+func CheckIndexes(indexes []postgres.Index, opts IndexOptions) *IndexReport {
+	report := &IndexReport{Issues: []IndexIssue{}, Protected: []ProtectedIndex{}}
+	reported := make(map[string]bool)
+	dropped := make(map[string]bool)
+
+	add := func(index postgres.Index, kind, other string, message i18n.Message) {
+		key := index.Schema + "." + index.Name
+		if reported[key] {
+			return
+		}
+		reported[key] = true
+
+		if len(index.Constraints) > 0 || (index.Unique && kind != IssueInvalid) {
+			report.Protected = append(report.Protected, ProtectedIndex{
+				Index: index.Name, Table: index.Table, Kind: kind,
+				Constraints: index.Constraints, Unique: index.Unique,
+			})
+			return
+		}
+		report.Issues = append(report.Issues, IndexIssue{
+			Kind:       kind,
+			Schema:     index.Schema,
+			Index:      index.Name,
+			Table:      index.Table,
+			SizeBytes:  index.SizeBytes,
+			Scans:      index.Scans,
+			Definition: index.Definition,
+			Other:      other,
+			Message:    message,
+			DDL:        fmt.Sprintf("DROP INDEX CONCURRENTLY %s.%s;", quote.Ident(index.Schema), quote.Ident(index.Name)),
+		})
+		report.ReclaimableBytes += index.SizeBytes
+		dropped[key] = true
+	}
+
+	for _, index := range indexes {
+		if !index.Valid {
+			add(index, IssueInvalid, "", i18n.New(i18n.IndexInvalid, "index", index.Name, "table", index.Table))
+		}
+	}
+
+	// Одинаковые индексы: остается тот, что обслуживает ограничение,
+	// уникальный или самый используемый
+	groups := make(map[string][]postgres.Index)
+	var order []string
+	for _, index := range indexes {
+		if !index.Valid {
+			continue
+		}
+		signature := strings.Join([]string{index.Schema, index.Table, index.Method,
+			strings.Join(index.Columns, ","), strings.Join(index.Include, ","), index.Predicate}, "|")
+		if _, ok := groups[signature]; !ok {
+			order = append(order, signature)
+		}
+		groups[signature] = append(groups[signature], index)
+	}
+	for _, signature := range order {
+		group := groups[signature]
+		if len(group) < 2 {
+			continue
+		}
+		sort.SliceStable(group, func(i, j int) bool { return keepRank(group[i]) > keepRank(group[j]) })
+		for _, index := range group[1:] {
+			add(index, IssueDuplicate, group[0].Name,
+				i18n.New(i18n.IndexDuplicate, "index", index.Name, "other", group[0].Name))
+		}
+	}
+
+	// Сначала проверяются длинные ключи: если избыточен и сам покрывающий
+	// индекс, короткий сошлется на тот, что останется. Индекс, который сам
+	// будет предложен к удалению как неиспользуемый, короткий не заменяет
+	byLength := append([]postgres.Index(nil), indexes...)
+	sort.SliceStable(byLength, func(i, j int) bool { return len(byLength[i].Columns) > len(byLength[j].Columns) })
+	for _, index := range byLength {
+		if !index.Valid || index.Method != "btree" || index.Unique {
+			continue
+		}
+		for _, other := range byLength {
+			if other.Name != index.Name && other.Valid && !dropped[other.Schema+"."+other.Name] &&
+				!unused(other, opts) && coversPrefix(other, index) {
+				add(index, IssueRedundant, other.Name,
+					i18n.New(i18n.IndexRedundant, "index", index.Name, "other", other.Name))
+				break
+			}
+		}
+	}
+
+	// Индекс, который остается вместо дубликата или избыточного, удалять
+	// нельзя, как бы редко он ни использовался
+	kept := make(map[string]bool)
+	for _, issue := range report.Issues {
+		if issue.Other != "" {
+			kept[issue.Schema+"."+issue.Other] = true
+		}
+	}
+	for _, index := range indexes {
+		switch {
+		case !index.Valid || kept[index.Schema+"."+index.Name]:
+		case index.Scans == 0:
+			add(index, IssueUnused, "", i18n.New(i18n.IndexUnused, "index", index.Name, "table", index.Table))
+		case index.Scans <= opts.RareScans:
+			add(index, IssueRarelyUsed, "", i18n.New(i18n.IndexRarelyUsed,
+				"index", index.Name, "table", index.Table, "scans", fmt.Sprint(index.Scans)))
+		}
+	}
+
+	report.Localize(i18n.DefaultLocale)
+	return report
+}
+
+// Localize формирует тексты проблем на языке locale
+func (r *IndexReport) Localize(locale string) {
+	for i := range r.Issues {
+		r.Issues[i].Description = r.Issues[i].Message.Format(locale)
+	}
+}
+
+// unused сообщает, будет ли индекс предложен к удалению как неиспользуемый
+// или редко используемый
+func unused(index postgres.Index, opts IndexOptions) bool {
+	return index.Scans <= opts.RareScans && len(index.Constraints) == 0 && !index.Unique
+}
+
+// keepRank — насколько важно сохранить индекс из группы одинаковых
+func keepRank(index postgres.Index) int64 {
+	rank := index.Scans
+	if index.Unique {
+		rank += 1 << 40
+	}
+	if len(index.Constraints) > 0 {
+		rank += 1 << 50
+	}
+	return rank
+}
+
+// coversPrefix сообщает, может ли B-tree индекс other заменить index:
+// ключ index — левый префикс ключа other или совпадает с ним, условие
+// частичного индекса то же, а столбцы INCLUDE есть в other
+func coversPrefix(other, index postgres.Index) bool {
+	if other.Schema != index.Schema || other.Table != index.Table || other.Method != "btree" ||
+		other.Predicate != index.Predicate || len(index.Columns) > len(other.Columns) {
+		return false
+	}
+	for i, column := range index.Columns {
+		if other.Columns[i] != column {
+			return false
+		}
+	}
+	for _, column := range index.Include {
+		if !slices.Contains(other.Columns, column) && !slices.Contains(other.Include, column) {
+			return false
+		}
+	}
+	return true
+}
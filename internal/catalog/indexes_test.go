@@ -0,0 +1,133 @@
+package catalog
+
+import (
+	"testing"
+
+	"sql-optimizer/internal/postgres"
+)
+
+func btree(name string, columns ...string) postgres.Index {
+	return postgres.Index{Schema: "public", Name: name, Table: "orders", Method: "btree",
+		Columns: columns, Valid: true, Scans: 1000, SizeBytes: 8192}
+}
+
+func TestCheckIndexes(t *testing.T) {
+	pkey := btree("orders_pkey", "id")
+	pkey.Unique, pkey.Primary, pkey.Constraints = true, true, []string{"orders_pkey"}
+
+	dupe := btree("orders_id_idx", "id")
+	dupe.Scans = 5000
+
+	userStatus := btree("orders_user_status_idx", "user_id", "status")
+	user := btree("orders_user_idx", "user_id") // левый префикс orders_user_status_idx
+
+	covering := btree("orders_user_created_idx", "user_id", "created_at")
+	covering.Include = []string{"total"}
+	withInclude := btree("orders_user_incl_idx", "user_id")
+	withInclude.Include = []string{"created_at"}
+
+	partial := btree("orders_user_paid_idx", "user_id")
+	partial.Predicate = "paid"
+
+	invalid := btree("Orders Broken", "total")
+	invalid.Valid = false
+
+	unused := btree("orders_note_idx", "note")
+	unused.Scans = 0
+	rare := btree("orders_total_idx", "total")
+	rare.Scans = 7
+
+	uniqueUnused := btree("orders_number_key", "number")
+	uniqueUnused.Unique, uniqueUnused.Scans = true, 0
+
+	gin := btree("orders_tags_idx", "tags")
+	gin.Method = "gin"
+	ginPrefix := btree("orders_tags_only_idx", "tags")
+	ginPrefix.Method, ginPrefix.Columns = "gin", []string{"tags", "labels"}
+
+	report := CheckIndexes([]postgres.Index{pkey, dupe, user, userStatus, withInclude, covering,
+		partial, invalid, unused, rare, uniqueUnused, gin, ginPrefix}, IndexOptions{RareScans: 10})
+
+	want := map[string]string{
+		"orders_id_idx":        IssueDuplicate,
+		"orders_user_idx":      IssueRedundant,
+		"orders_user_incl_idx": IssueRedundant,
+		"Orders Broken":        IssueInvalid,
+		"orders_note_idx":      IssueUnused,
+		"orders_total_idx":     IssueRarelyUsed,
+	}
+	if len(report.Issues) != len(want) {
+		t.Fatalf("Ожидали %d проблем, получили %+v", len(want), report.Issues)
+	}
+	for _, issue := range report.Issues {
+		if want[issue.Index] != issue.Kind {
+			t.Errorf("Индекс %s: ожидали %q, получили %q", issue.Index, want[issue.Index], issue.Kind)
+		}
+	}
+	if report.ReclaimableBytes != 6*8192 {
+		t.Errorf("Неверный освобождаемый объем: %d", report.ReclaimableBytes)
+	}
+
+	byName := make(map[string]IndexIssue)
+	for _, issue := range report.Issues {
+		byName[issue.Index] = issue
+	}
+	if byName["orders_id_idx"].Other != "orders_pkey" {
+		t.Errorf("Из дубликатов должен остаться индекс первичного ключа: %+v", byName["orders_id_idx"])
+	}
+	if other := byName["orders_user_idx"].Other; other != "orders_user_status_idx" && other != "orders_user_created_idx" {
+		t.Errorf("Неверный покрывающий индекс: %s", other)
+	}
+	if other := byName["orders_user_incl_idx"].Other; other != "orders_user_created_idx" {
+		t.Errorf("INCLUDE (created_at) покрывает только orders_user_created_idx, получили %s", other)
+	}
+	if ddl := byName["Orders Broken"].DDL; ddl != `DROP INDEX CONCURRENTLY public."Orders Broken";` {
+		t.Errorf("Неверный DDL: %s", ddl)
+	}
+	if byName["orders_note_idx"].Description != "Индекс orders_note_idx на таблице orders ни разу не использовался с момента сброса статистики" {
+		t.Errorf("Неверное описание: %q", byName["orders_note_idx"].Description)
+	}
+
+	if len(report.Protected) != 1 || report.Protected[0].Index != "orders_number_key" || report.Protected[0].Kind != IssueUnused {
+		t.Errorf("Уникальный индекс должен быть защищен от удаления: %+v", report.Protected)
+	}
+
+	report.Localize("en")
+	for _, issue := range report.Issues {
+		if issue.Index == "orders_total_idx" && issue.Description != "Index orders_total_idx on table orders has been used only 7 times since the statistics were reset" {
+			t.Errorf("Неверное описание на английском: %q", issue.Description)
+		}
+	}
+}
+
+func TestCheckIndexesKeepsOneOfMutualDuplicates(t *testing.T) {
+	a, b := btree("a_idx", "user_id"), btree("b_idx", "user_id")
+	b.Scans = 0
+
+	report := CheckIndexes([]postgres.Index{a, b}, IndexOptions{})
+	if len(report.Issues) != 1 || report.Issues[0].Index != "b_idx" || report.Issues[0].Kind != IssueDuplicate {
+		t.Errorf("Ожидали удаление только b_idx как дубликата: %+v", report.Issues)
+	}
+}
+
+func TestCheckIndexesUnusedCoveringIndex(t *testing.T) {
+	user := btree("o_user", "user_id")
+	user.Scans = 100000
+	userStatus := btree("o_user_status", "user_id", "status")
+	userStatus.Scans = 0
+
+	report := CheckIndexes([]postgres.Index{user, userStatus}, IndexOptions{RareScans: 10})
+	if len(report.Issues) != 1 || report.Issues[0].Index != "o_user_status" || report.Issues[0].Kind != IssueUnused {
+		t.Errorf("Ожидали удаление только неиспользуемого o_user_status: %+v", report.Issues)
+	}
+}
+
+func TestCheckIndexesKeepsUnusedDuplicate(t *testing.T) {
+	a, b := btree("a_idx", "user_id"), btree("b_idx", "user_id")
+	a.Scans, b.Scans = 0, 0
+
+	report := CheckIndexes([]postgres.Index{a, b}, IndexOptions{})
+	if len(report.Issues) != 1 || report.Issues[0].Kind != IssueDuplicate {
+		t.Errorf("Один из неиспользуемых дубликатов должен остаться: %+v", report.Issues)
+	}
+}
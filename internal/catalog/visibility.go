@@ -0,0 +1,248 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+)
+
+// Пороги находок по карте видимости
+const (
+	// HeapFetchesThreshold — начиная с этого числа обращений к таблице
+	// Index Only Scan мало отличается от обычного Index Scan
+	HeapFetchesThreshold = 1000
+	// HeapFetchesShare — и обращений должно быть не меньше этой доли
+	// выданных строк
+	HeapFetchesShare = 0.1
+	// CoveringMinRows — сколько строк должен выдать Index Scan, чтобы
+	// рост индекса из-за INCLUDE окупился
+	CoveringMinRows = 1000
+	// MaxIncludeColumns — больше столбцов в INCLUDE не предлагается:
+	// индекс разрастается почти до размера таблицы
+	MaxIncludeColumns = 3
+)
+
+// systemColumns — системные столбцы, которых не бывает в индексе
+var systemColumns = map[string]bool{
+	"ctid": true, "xmin": true, "xmax": true, "cmin": true, "cmax": true, "tableoid": true,
+}
+
+// VisibilitySource — источник индексов и статистики таблиц; его реализует
+// *postgres.Client
+type VisibilitySource interface {
+	Indexes(ctx context.Context) ([]postgres.Index, error)
+	Tables(ctx context.Context) ([]postgres.Table, error)
+}
+
+// NeedsVisibility сообщает, есть ли в плане Index Only Scan с обращениями
+// к таблице или Index Scan с известными выходными столбцами
+func NeedsVisibility(plan []analyzer.PlanNode) bool {
+	for _, node := range plan {
+		if node.NodeType == "Index Only Scan" && node.HeapFetches != nil && *node.HeapFetches > 0 ||
+			node.NodeType == "Index Scan" && len(node.Output) > 0 ||
+			NeedsVisibility(node.Plans) {
+			return true
+		}
+	}
+	return false
+}
+
+// LinkVisibility читает индексы и таблицы из src и добавляет находки
+// CheckVisibility. Тексты нужно сформировать заново через Localize результата.
+func LinkVisibility(ctx context.Context, src VisibilitySource, plan []analyzer.PlanNode, result *analyzer.AnalysisResult) error {
+	indexes, err := src.Indexes(ctx)
+	if err != nil {
+		return err
+	}
+	tables, err := src.Tables(ctx)
+	if err != nil {
+		return err
+	}
+	CheckVisibility(plan, result, indexes, tables)
+	return nil
+}
+
+// CheckVisibility добавляет в результат анализа находки по сканированиям
+// индекса:
+//   - RuleHeapFetches — Index Only Scan с тысячами Heap Fetches: страницы
+//     таблицы не отмечены в карте видимости (relallvisible меньше relpages),
+//     и видимость строк проверяется чтением таблицы;
+//   - RuleCoveringIndex — Index Scan читает из таблицы несколько столбцов,
+//     которых нет в индексе; с ними в INCLUDE он стал бы Index Only Scan.
+//     Выходные столбцы узла есть только в плане с VERBOSE.
+//
+// Схема таблицы узла тоже есть только в плане с VERBOSE; без нее таблица
+// находится по имени, если оно не повторяется в других схемах.
+func CheckVisibility(plan []analyzer.PlanNode, result *analyzer.AnalysisResult, indexes []postgres.Index, tables []postgres.Table) {
+	byTable := make(map[string]postgres.Table)
+	schemas := make(map[string][]string)
+	for _, table := range tables {
+		byTable[table.Schema+"."+table.Name] = table
+		schemas[table.Name] = append(schemas[table.Name], table.Schema)
+	}
+	schemaOf := func(node analyzer.PlanNode) string {
+		if node.Schema == "" && len(schemas[node.RelationName]) == 1 {
+			return schemas[node.RelationName][0]
+		}
+		return node.Schema
+	}
+	linked := make(map[string]bool)
+
+	var walk func(node analyzer.PlanNode)
+	walk = func(node analyzer.PlanNode) {
+		schema := schemaOf(node)
+		key := node.NodeType + "/" + schema + "." + node.IndexName
+		switch {
+		case linked[key]:
+		case node.NodeType == "Index Only Scan" && node.HeapFetches != nil:
+			if table, ok := byTable[schema+"."+node.RelationName]; ok {
+				linked[key] = addHeapFetches(result, node, table)
+			}
+		case node.NodeType == "Index Scan" && len(node.Output) > 0:
+			if index, ok := findIndex(indexes, schema, node.IndexName, node.RelationName); ok {
+				linked[key] = addCovering(result, node, index)
+			}
+		}
+		for _, child := range node.Plans {
+			walk(child)
+		}
+	}
+	for _, node := range plan {
+		walk(node)
+	}
+}
+
+// addHeapFetches добавляет находку RuleHeapFetches, если Index Only Scan
+// обращался к таблице слишком часто
+func addHeapFetches(result *analyzer.AnalysisResult, node analyzer.PlanNode, table postgres.Table) bool {
+	fetches, rows := *node.HeapFetches, totalRows(node)
+	if fetches < HeapFetchesThreshold || float64(fetches) < HeapFetchesShare*float64(rows) || table.Pages == 0 {
+		return false
+	}
+
+	name := table.QualifiedName()
+	link := &analyzer.VisibilityLink{
+		Table:           table.Name,
+		Index:           node.IndexName,
+		HeapFetches:     fetches,
+		Pages:           table.Pages,
+		AllVisiblePages: table.AllVisible,
+		Vacuum:          fmt.Sprintf("VACUUM %s;", name),
+		Autovacuum: fmt.Sprintf("ALTER TABLE %s SET (autovacuum_vacuum_scale_factor = 0.01, autovacuum_vacuum_insert_scale_factor = 0.01);",
+			name),
+	}
+	severity := "medium"
+	if fetches*2 >= rows {
+		severity = "high"
+	}
+	visible := float64(min(table.AllVisible, table.Pages)) / float64(table.Pages) * 100
+	result.ProblematicOperations = append(result.ProblematicOperations, analyzer.ProblematicOperation{
+		RuleID:     analyzer.RuleHeapFetches,
+		NodeType:   node.NodeType,
+		Relation:   table.Name,
+		Cost:       node.TotalCost,
+		ActualTime: node.ActualTotalTime,
+		Severity:   severity,
+		DescriptionMessage: i18n.New(i18n.ProblemHeapFetches,
+			"index", node.IndexName, "table", table.Name,
+			"fetches", strconv.Itoa(fetches), "rows", strconv.Itoa(rows),
+			"visible", strconv.FormatFloat(visible, 'f', 0, 64),
+			"all_visible", strconv.FormatInt(table.AllVisible, 10), "pages", strconv.FormatInt(table.Pages, 10)),
+		RecommendationMessage: i18n.New(i18n.ProblemHeapFetchesRecommendation,
+			"vacuum", link.Vacuum, "autovacuum", link.Autovacuum),
+		Visibility: link,
+	})
+	result.RecommendationMessages = append(result.RecommendationMessages,
+		i18n.New(i18n.RecommendVacuum, "table", table.Name, "vacuum", link.Vacuum))
+	return true
+}
+
+// addCovering добавляет находку RuleCoveringIndex, если Index Scan
+// читает из таблицы не больше MaxIncludeColumns столбцов, которых нет
+// в индексе
+func addCovering(result *analyzer.AnalysisResult, node analyzer.PlanNode, index postgres.Index) bool {
+	if totalRows(node) < CoveringMinRows || index.Method != "btree" || !index.Valid {
+		return false
+	}
+	if slices.ContainsFunc(index.Columns, postgres.IsExpression) {
+		return false // индекс по выражению
+	}
+
+	// Имена из плана совпадают с именами pg_attribute: FilterColumns
+	// снимает кавычки и приводит к нижнему регистру только имена без них
+	var needed []string
+	for _, output := range append(append([]string(nil), node.Output...), node.Filter) {
+		if strings.HasSuffix(output, ".*") {
+			return false // вся строка таблицы
+		}
+		for _, column := range analyzer.FilterColumns(output) {
+			if systemColumns[column] {
+				return false
+			}
+			if !slices.Contains(needed, column) {
+				needed = append(needed, column)
+			}
+		}
+	}
+	var missing []string
+	for _, column := range needed {
+		if !slices.Contains(index.Columns, column) && !slices.Contains(index.Include, column) {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) == 0 || len(missing) > MaxIncludeColumns {
+		return false
+	}
+
+	candidate := analyzer.IndexCandidate{
+		Schema:  index.Schema,
+		Table:   index.Table,
+		Columns: index.Columns,
+		Include: append(append([]string(nil), index.Include...), missing...),
+		Kind:    analyzer.IndexCovering,
+		Where:   index.Predicate,
+	}
+	result.ProblematicOperations = append(result.ProblematicOperations, analyzer.ProblematicOperation{
+		RuleID:     analyzer.RuleCoveringIndex,
+		NodeType:   node.NodeType,
+		Relation:   index.Table,
+		Filter:     node.Filter,
+		Cost:       node.TotalCost,
+		ActualTime: node.ActualTotalTime,
+		Severity:   "low",
+		DescriptionMessage: i18n.New(i18n.ProblemCoveringIndex,
+			"index", index.Name, "table", index.Table, "columns", strings.Join(missing, ", ")),
+		RecommendationMessage: i18n.New(i18n.ProblemCoveringIndexRecommendation,
+			"index", index.Name, "ddl", candidate.DDL()),
+		Index: &candidate,
+	})
+	return true
+}
+
+// findIndex ищет индекс узла плана по схеме, имени и таблице
+func findIndex(indexes []postgres.Index, schema, name, table string) (postgres.Index, bool) {
+	for _, index := range indexes {
+		if index.Schema == schema && index.Name == name && index.Table == table {
+			return index, true
+		}
+	}
+	return postgres.Index{}, false
+}
+
+// totalRows — строки, выданные узлом за все циклы; без ANALYZE — оценка
+func totalRows(node analyzer.PlanNode) int {
+	if node.ActualRows == nil {
+		return node.PlanRows
+	}
+	loops := 1
+	if node.ActualLoops != nil && *node.ActualLoops > 0 {
+		loops = *node.ActualLoops
+	}
+	return *node.ActualRows * loops
+}
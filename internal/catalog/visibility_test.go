@@ -0,0 +1,123 @@
+package catalog
+
+import (
+	"strings"
+	"testing"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+)
+
+func intPtr(i int) *int { return &i }
+
+func visibilityPlan() []analyzer.PlanNode {
+	return []analyzer.PlanNode{{
+		NodeType: "Nested Loop", TotalCost: 900, PlanRows: 5000, ActualRows: intPtr(5000), ActualLoops: intPtr(1),
+		Plans: []analyzer.PlanNode{
+			{NodeType: "Index Only Scan", RelationName: "events", Schema: "public", Alias: "e", IndexName: "events_user_id_idx",
+				TotalCost: 400, PlanRows: 5000, ActualRows: intPtr(5000), ActualLoops: intPtr(1), HeapFetches: intPtr(4200),
+				Output: []string{"e.user_id"}},
+			{NodeType: "Index Scan", RelationName: "orders", Schema: "public", Alias: "o", IndexName: "orders_customer_id_idx",
+				TotalCost: 450, PlanRows: 1, ActualRows: intPtr(2), ActualLoops: intPtr(5000),
+				Output: []string{"o.customer_id", "o.amount", "o.created_at"}, Filter: "((o.status)::text = 'paid'::text)"},
+		},
+	}}
+}
+
+func visibilityCatalog() ([]postgres.Index, []postgres.Table) {
+	indexes := []postgres.Index{
+		{Schema: "public", Name: "events_user_id_idx", Table: "events", Method: "btree", Columns: []string{"user_id"}, Valid: true},
+		{Schema: "public", Name: "orders_customer_id_idx", Table: "orders", Method: "btree", Columns: []string{"customer_id"},
+			Include: []string{"amount"}, Valid: true},
+		// Одноименные таблица и индекс в другой схеме не мешают поиску
+		{Schema: "archive", Name: "orders_customer_id_idx", Table: "orders", Method: "btree",
+			Columns: []string{"customer_id", "amount", "created_at", "status"}, Valid: true},
+	}
+	tables := []postgres.Table{
+		{Schema: "public", Name: "events", Pages: 10000, AllVisible: 1200},
+		{Schema: "public", Name: "orders", Pages: 5000, AllVisible: 5000},
+		{Schema: "archive", Name: "orders", Pages: 100, AllVisible: 0},
+	}
+	return indexes, tables
+}
+
+func TestCheckVisibility(t *testing.T) {
+	plan := visibilityPlan()
+	if !NeedsVisibility(plan) {
+		t.Fatal("NeedsVisibility = false")
+	}
+	result := &analyzer.AnalysisResult{}
+	indexes, tables := visibilityCatalog()
+	CheckVisibility(plan, result, indexes, tables)
+	result.Localize(i18n.DefaultLocale)
+
+	if len(result.ProblematicOperations) != 2 {
+		t.Fatalf("находок %d: %+v", len(result.ProblematicOperations), result.ProblematicOperations)
+	}
+	fetches := result.ProblematicOperations[0]
+	if fetches.RuleID != analyzer.RuleHeapFetches || fetches.Severity != "high" || fetches.Visibility == nil {
+		t.Fatalf("heap fetches: %+v", fetches)
+	}
+	if fetches.Visibility.Vacuum != "VACUUM public.events;" || !strings.Contains(fetches.Description, "12%") {
+		t.Errorf("описание: %s, %+v", fetches.Description, fetches.Visibility)
+	}
+	if len(result.RecommendationMessages) != 1 {
+		t.Errorf("рекомендаций %d", len(result.RecommendationMessages))
+	}
+
+	covering := result.ProblematicOperations[1]
+	want := "CREATE INDEX CONCURRENTLY idx_orders_customer_id_covering ON public.orders (customer_id) INCLUDE (amount, created_at, status);"
+	if covering.RuleID != analyzer.RuleCoveringIndex || covering.Index == nil || covering.Index.DDL() != want {
+		t.Errorf("покрывающий индекс: %+v", covering.Index)
+	}
+	if !strings.Contains(covering.Description, "created_at, status") {
+		t.Errorf("описание: %s", covering.Description)
+	}
+}
+
+func TestCheckVisibilitySkips(t *testing.T) {
+	plan := visibilityPlan()
+	// Обращений к таблице мало, а выходные столбцы включают всю строку
+	plan[0].Plans[0].HeapFetches = intPtr(300)
+	plan[0].Plans[1].Output = []string{"o.*"}
+
+	result := &analyzer.AnalysisResult{}
+	indexes, tables := visibilityCatalog()
+	CheckVisibility(plan, result, indexes, tables)
+	if len(result.ProblematicOperations) != 0 {
+		t.Errorf("находки: %+v", result.ProblematicOperations)
+	}
+}
+
+func TestCheckVisibilityQuotedColumns(t *testing.T) {
+	// Без VERBOSE-схемы таблица находится по имени, если оно одно
+	plan := []analyzer.PlanNode{{
+		NodeType: "Index Scan", RelationName: "Payments", IndexName: "payments_user_idx",
+		TotalCost: 300, PlanRows: 5000, ActualRows: intPtr(5000), ActualLoops: intPtr(1),
+		Output: []string{`p."userId"`, `p."order"`, "p.amount"},
+	}}
+	indexes := []postgres.Index{
+		{Schema: "billing", Name: "payments_user_idx", Table: "Payments", Method: "btree",
+			Columns: []string{"userId"}, Include: []string{"order"}, Valid: true},
+	}
+	tables := []postgres.Table{{Schema: "billing", Name: "Payments"}}
+
+	result := &analyzer.AnalysisResult{}
+	CheckVisibility(plan, result, indexes, tables)
+	if len(result.ProblematicOperations) != 1 || result.ProblematicOperations[0].Index == nil {
+		t.Fatalf("находки: %+v", result.ProblematicOperations)
+	}
+	want := `CREATE INDEX CONCURRENTLY "idx_Payments_userId_covering" ON billing."Payments" ("userId") INCLUDE ("order", amount);`
+	if ddl := result.ProblematicOperations[0].Index.DDL(); ddl != want {
+		t.Errorf("DDL: %s", ddl)
+	}
+
+	// Индекс по выражению покрывающим не становится
+	indexes[0].Columns = []string{`(lower("userId"))`}
+	result = &analyzer.AnalysisResult{}
+	CheckVisibility(plan, result, indexes, tables)
+	if len(result.ProblematicOperations) != 0 {
+		t.Errorf("находки для индекса по выражению: %+v", result.ProblematicOperations)
+	}
+}
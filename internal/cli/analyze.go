@@ -0,0 +1,306 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sql-optimizer/internal/analysis"
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/baseline"
+	"sql-optimizer/internal/history"
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+	"sql-optimizer/internal/render"
+	"sql-optimizer/internal/report"
+	"sql-optimizer/internal/sarif"
+	"sql-optimizer/internal/sqlsource"
+)
+
+// analyzeOptions — флаги команды analyze
+type analyzeOptions struct {
+	dsn      string
+	sarif    string
+	markdown string
+	junit    string
+	html     string
+	dot      string
+	svg      string
+	flame    string
+	folded   string
+	timeline string
+	noColor  bool
+	explain  bool
+	execute  bool
+	lang     string
+	history  string
+	baseline string
+	update   bool // записать находки в базовую линию вместо того, чтобы их пропускать
+	timeout  time.Duration
+}
+
+// output — файл отчета и функция, которая его записывает
+type output struct {
+	path  string
+	write func(io.Writer) error
+}
+
+// runAnalyze получает планы для всех операторов из SQL-файлов,
+// анализирует их и записывает отчеты в запрошенных форматах
+func runAnalyze(args []string, stdout, stderr io.Writer) int {
+	var opts analyzeOptions
+	flags := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&opts.dsn, "dsn", os.Getenv("DATABASE_URL"), "строка подключения к PostgreSQL (по умолчанию $DATABASE_URL)")
+	flags.StringVar(&opts.sarif, "sarif", "", "записать находки в SARIF-файл")
+	flags.StringVar(&opts.markdown, "md", "", "записать отчет в формате Markdown")
+	flags.StringVar(&opts.junit, "junit", "", "записать отчет в формате JUnit XML")
+	flags.StringVar(&opts.html, "html", "", "записать самодостаточный HTML-отчет")
+	flags.StringVar(&opts.dot, "dot", "", "записать схему плана в формате Graphviz DOT")
+	flags.StringVar(&opts.svg, "svg", "", "записать схему плана в формате SVG")
+	flags.StringVar(&opts.flame, "flame", "", "записать flame graph собственного времени узлов в SVG")
+	flags.StringVar(&opts.folded, "folded", "", "записать свернутые стеки для flamegraph.pl и speedscope")
+	flags.StringVar(&opts.timeline, "timeline", "", "записать временную диаграмму узлов в SVG")
+	flags.BoolVar(&opts.explain, "explain", false, "объяснить план простыми словами")
+	flags.StringVar(&opts.lang, "lang", defaultLocale(), "язык находок и объяснений: ru или en (по умолчанию из $LANG)")
+	flags.StringVar(&opts.history, "history", "", "сохранять анализы в историю в указанном каталоге")
+	flags.StringVar(&opts.baseline, "baseline", "", "не показывать находки из файла базовой линии")
+	flags.BoolVar(&opts.update, "update-baseline", false, "записать все находки в файл -baseline")
+	flags.BoolVar(&opts.noColor, "no-color", false, "не подсвечивать дерево плана цветом")
+	flags.DurationVar(&opts.timeout, "timeout", 30*time.Second, "таймаут анализа одного запроса")
+	flags.BoolVar(&opts.execute, "execute", false, "выполнять запросы вне транзакции только для чтения: изменяющие запросы и DDL изменят базу")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "Не указаны SQL-файлы для анализа")
+		return 2
+	}
+	if !i18n.Supported(opts.lang) {
+		fmt.Fprintf(stderr, "Неподдерживаемый язык %q\n", opts.lang)
+		return 2
+	}
+	if opts.dsn == "" {
+		fmt.Fprintln(stderr, "Не указана строка подключения: используйте -dsn или $DATABASE_URL")
+		return 2
+	}
+	if opts.update && opts.baseline == "" {
+		fmt.Fprintln(stderr, "Для -update-baseline нужен файл -baseline")
+		return 2
+	}
+
+	var known *baseline.Baseline
+	if opts.baseline != "" {
+		// Файла может еще не быть, если базовая линия только создается
+		if _, err := os.Stat(opts.baseline); err == nil || !opts.update {
+			if known, err = baseline.Load(opts.baseline); err != nil {
+				fmt.Fprintln(stderr, err)
+				return 1
+			}
+		}
+	}
+
+	var statements []sqlsource.Statement
+	for _, path := range flags.Args() {
+		parsed, err := sqlsource.ParseFile(path)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		statements = append(statements, parsed...)
+	}
+
+	client, err := postgres.NewClient(opts.dsn)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer client.Close()
+
+	var store history.Store
+	if opts.history != "" {
+		fileStore, err := history.NewFileStore(opts.history)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		store = fileStore
+	}
+
+	textOptions := render.TextOptions{Color: !opts.noColor && render.IsTerminal(stdout)}
+
+	// Внешние ключи читаются один раз и только если план этого требует
+	foreignKeys := &analysis.ForeignKeyCache{}
+
+	exitCode := 0
+	var analyses []report.Analysis
+	var entries []sarif.Entry
+	var found []*analyzer.AnalysisResult
+	for _, statement := range statements {
+		name := fmt.Sprintf("%s:%d:%d", statement.File, statement.Line, statement.Column)
+		plan, result, err := analyzeStatement(client, statement.Text, opts.timeout, opts.execute)
+		if result != nil {
+			for _, enrichErr := range enrich(client, statement.Text, plan, result, opts, foreignKeys) {
+				fmt.Fprintf(stderr, "%s: %v\n", name, enrichErr)
+			}
+		}
+		// История хранит все находки, отчеты — только новые
+		if result != nil && store != nil {
+			if _, err := store.Save(history.NewRecord(postgres.DatabaseName(opts.dsn), statement.Text, plan, result)); err != nil {
+				fmt.Fprintf(stderr, "%s: не удалось сохранить в историю: %v\n", name, err)
+			}
+		}
+		suppressed := 0
+		if result != nil && opts.update {
+			found = append(found, result)
+		} else if result != nil && known != nil {
+			suppressed = known.Suppress(result)
+		}
+		analyses = append(analyses, report.Analysis{
+			Name:   name,
+			Query:  statement.Text,
+			Plan:   plan,
+			Result: result,
+			Err:    err,
+		})
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", name, err)
+			exitCode = 1
+			continue
+		}
+
+		fmt.Fprintf(stdout, "%s: проблемных операций: %d", name, len(result.ProblematicOperations))
+		if suppressed > 0 {
+			fmt.Fprintf(stdout, ", известных по базовой линии: %d", suppressed)
+		}
+		fmt.Fprintln(stdout)
+		render.Text(stdout, plan, textOptions)
+		if opts.explain {
+			for i, step := range result.Explanation {
+				fmt.Fprintf(stdout, "%d. %s\n", i+1, step)
+			}
+		}
+		fmt.Fprintln(stdout)
+		entries = append(entries, sarif.Entry{Statement: statement, Result: result})
+	}
+
+	outputs := []output{
+		{opts.sarif, func(w io.Writer) error { return sarif.Write(w, sarif.Build(entries, opts.lang)) }},
+		{opts.markdown, func(w io.Writer) error { return report.Markdown(w, analyses, opts.lang) }},
+		{opts.junit, func(w io.Writer) error { return report.JUnit(w, analyses, opts.lang) }},
+		{opts.html, func(w io.Writer) error { return report.HTML(w, analyses, opts.lang) }},
+	}
+	if opts.update {
+		outputs = append(outputs, output{opts.baseline, func(w io.Writer) error { return baseline.Build(found, known).Write(w) }})
+	}
+
+	// Изображения плана строятся по одному на запрос
+	for i, a := range analyses {
+		if a.Err != nil {
+			continue
+		}
+		plan := a.Plan
+		for _, image := range []struct {
+			path   string
+			render func(io.Writer, []analyzer.PlanNode) error
+		}{
+			{opts.dot, render.DOT},
+			{opts.svg, render.SVG},
+			{opts.flame, render.FlameGraph},
+			{opts.folded, render.Folded},
+			{opts.timeline, render.Timeline},
+		} {
+			draw := image.render
+			outputs = append(outputs, output{
+				path:  numberedPath(image.path, i, len(analyses)),
+				write: func(w io.Writer) error { return draw(w, plan) },
+			})
+		}
+	}
+
+	for _, out := range outputs {
+		if out.path == "" {
+			continue
+		}
+		if err := writeFile(out.path, out.write); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+
+	return exitCode
+}
+
+// enrich дополняет анализ запроса сведениями из базы в пределах таймаута
+// одного запроса
+func enrich(client *postgres.Client, query string, plan []analyzer.PlanNode, result *analyzer.AnalysisResult,
+	opts analyzeOptions, foreignKeys *analysis.ForeignKeyCache) []error {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+	return analysis.Enrich(ctx, client, query, plan, result, analysis.Options{Locale: opts.lang, ForeignKeys: foreignKeys})
+}
+
+// analyzeStatement получает план выполнения запроса и анализирует его.
+// По умолчанию запрос выполняется в безопасном режиме: в транзакции только
+// для чтения, которая откатывается, поэтому миграции и начальные данные
+// из SQL-файлов не изменят базу, а завершатся ошибкой. С execute запрос
+// выполняется как есть.
+func analyzeStatement(client *postgres.Client, query string, timeout time.Duration, execute bool) ([]analyzer.PlanNode, *analyzer.AnalysisResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var planJSON string
+	var err error
+	if execute {
+		planJSON, err = client.GetExplainPlan(ctx, query)
+	} else {
+		planJSON, err = client.GetExplainPlanSafe(ctx, query, timeout)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	plan, err := analyzer.ParseExplainJSON(planJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plan, analyzer.AnalyzeNodes(plan), nil
+}
+
+// writeFile создает файл и передает его функции записи отчета
+func writeFile(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("ошибка создания файла %s: %v", path, err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("ошибка записи файла %s: %v", path, err)
+	}
+	return file.Close()
+}
+
+// numberedPath добавляет к имени файла номер запроса, если запросов
+// несколько: plan.svg превращается в plan-1.svg, plan-2.svg и т. д.
+func numberedPath(path string, index, total int) string {
+	if path == "" || total == 1 {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), index+1, ext)
+}
+
+// defaultLocale выбирает язык по переменным окружения LC_ALL и LANG
+func defaultLocale() string {
+	for _, name := range []string{"LC_ALL", "LANG"} {
+		if locale := i18n.Match(os.Getenv(name)); locale != "" {
+			return locale
+		}
+	}
+	return i18n.DefaultLocale
+}
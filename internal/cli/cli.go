@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+)
+
+const usage = `Использование:
+  sql-optimizer                          запуск веб-сервера
+  sql-optimizer analyze [флаги] файлы.sql анализ запросов из SQL-файлов
+  sql-optimizer workload [флаги]         анализ нагрузки из pg_stat_statements
+  sql-optimizer indexes [флаги]          поиск лишних индексов
+  sql-optimizer foreign-keys [флаги]     поиск внешних ключей без индекса
+  sql-optimizer doctor [флаги]           проверка здоровья базы
+  sql-optimizer tune [флаги]             рекомендации по параметрам сервера
+  sql-optimizer experiment [флаги] файл.sql
+                                         эксперимент с параметрами планировщика
+  sql-optimizer statistics [флаги] файл.sql
+                                         рекомендации по статистике планировщика
+
+Выполните "sql-optimizer <команда> -h", чтобы увидеть флаги команды.
+`
+
+// Run выполняет подкоманду командной строки и возвращает код завершения
+func Run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "analyze":
+		return runAnalyze(args[1:], stdout, stderr)
+	case "workload":
+		return runWorkload(args[1:], stdout, stderr)
+	case "indexes":
+		return runIndexes(args[1:], stdout, stderr)
+	case "foreign-keys":
+		return runForeignKeys(args[1:], stdout, stderr)
+	case "doctor":
+		return runDoctor(args[1:], stdout, stderr)
+	case "tune":
+		return runTune(args[1:], stdout, stderr)
+	case "experiment":
+		return runExperiment(args[1:], stdout, stderr)
+	case "statistics":
+		return runStatistics(args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "Неизвестная команда %q\n\n%s", args[0], usage)
+		return 2
+	}
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"sql-optimizer/internal/health"
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+)
+
+// runDoctor проверяет здоровье базы и выводит оценку и найденные проблемы
+func runDoctor(args []string, stdout, stderr io.Writer) int {
+	var (
+		dsn, jsonPath, lang string
+		longTx, timeout     time.Duration
+	)
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&dsn, "dsn", os.Getenv("DATABASE_URL"), "строка подключения к PostgreSQL (по умолчанию $DATABASE_URL)")
+	flags.DurationVar(&longTx, "long-tx", health.DefaultLongTransaction, "с какой длительности транзакция считается долгой")
+	flags.StringVar(&jsonPath, "json", "", "записать полный результат в JSON-файл")
+	flags.StringVar(&lang, "lang", defaultLocale(), "язык находок: ru или en (по умолчанию из $LANG)")
+	flags.DurationVar(&timeout, "timeout", time.Minute, "таймаут проверки")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if !i18n.Supported(lang) {
+		fmt.Fprintf(stderr, "Неподдерживаемый язык %q\n", lang)
+		return 2
+	}
+	if dsn == "" {
+		fmt.Fprintln(stderr, "Не указана строка подключения: используйте -dsn или $DATABASE_URL")
+		return 2
+	}
+
+	client, err := postgres.NewClient(dsn)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	report, err := health.Run(ctx, client, health.Options{LongTransaction: longTx})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	report.Localize(lang)
+
+	fmt.Fprintf(stdout, "Оценка здоровья базы: %d из 100\n\n", report.Score)
+	for _, check := range report.Checks {
+		fmt.Fprintf(stdout, "%-18s %s\n", check.ID, check.Status)
+		for _, finding := range check.Findings {
+			fmt.Fprintf(stdout, "  [%s] %s\n", finding.Severity, finding.Description)
+			if finding.Fix != "" {
+				fmt.Fprintf(stdout, "      %s\n", finding.Fix)
+			}
+		}
+	}
+
+	if jsonPath != "" {
+		err := writeFile(jsonPath, func(w io.Writer) error {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		})
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"sql-optimizer/internal/experiment"
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+	"sql-optimizer/internal/sqlsource"
+)
+
+// matrixFlag собирает флаги -set вида имя=значение1,значение2
+type matrixFlag []experiment.Dimension
+
+func (m *matrixFlag) String() string {
+	parts := make([]string, 0, len(*m))
+	for _, d := range *m {
+		parts = append(parts, d.Name+"="+strings.Join(d.Values, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (m *matrixFlag) Set(value string) error {
+	name, values, ok := strings.Cut(value, "=")
+	if !ok || name == "" || values == "" {
+		return fmt.Errorf("ожидается имя=значение1,значение2, получено %q", value)
+	}
+	*m = append(*m, experiment.Dimension{Name: strings.TrimSpace(name), Values: strings.Split(values, ",")})
+	return nil
+}
+
+// runExperiment строит план запроса под разными параметрами планировщика
+func runExperiment(args []string, stdout, stderr io.Writer) int {
+	var (
+		dsn, query, jsonPath, lang string
+		analyze                    bool
+		matrix                     matrixFlag
+		timeout                    time.Duration
+	)
+	flags := flag.NewFlagSet("experiment", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&dsn, "dsn", os.Getenv("DATABASE_URL"), "строка подключения к PostgreSQL (по умолчанию $DATABASE_URL)")
+	flags.StringVar(&query, "query", "", "текст запроса (вместо SQL-файла)")
+	flags.Var(&matrix, "set", "параметр и его значения, например enable_nestloop=on,off (можно повторять)")
+	flags.BoolVar(&analyze, "analyze", false, "выполнять запрос в каждом варианте и сравнивать стоимость с временем")
+	flags.StringVar(&jsonPath, "json", "", "записать полный результат в JSON-файл")
+	flags.StringVar(&lang, "lang", defaultLocale(), "язык выводов: ru или en (по умолчанию из $LANG)")
+	flags.DurationVar(&timeout, "timeout", experiment.DefaultTimeout, "таймаут одного варианта")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if !i18n.Supported(lang) {
+		fmt.Fprintf(stderr, "Неподдерживаемый язык %q\n", lang)
+		return 2
+	}
+	if dsn == "" {
+		fmt.Fprintln(stderr, "Не указана строка подключения: используйте -dsn или $DATABASE_URL")
+		return 2
+	}
+	query, code := singleQuery(query, flags.Args(), stderr)
+	if code != 0 {
+		return code
+	}
+
+	opts := experiment.Options{Matrix: matrix, Analyze: analyze, Timeout: timeout}
+	variants, err := experiment.Variants(opts)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	client, err := postgres.NewClient(dsn)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer client.Close()
+
+	// прогрев, базовый вариант и все остальные
+	ctx, cancel := context.WithTimeout(context.Background(), timeout*time.Duration(len(variants)+2))
+	defer cancel()
+
+	report, err := experiment.Run(ctx, client, query, opts)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	report.Localize(lang)
+
+	for _, v := range report.Variants {
+		name := v.Name
+		if len(v.Settings) == 0 {
+			name += " *"
+		}
+		switch {
+		case v.Error != "":
+			fmt.Fprintf(stdout, "%-45s ошибка: %s\n", name, v.Error)
+		case v.ActualTime != nil:
+			fmt.Fprintf(stdout, "%-45s стоимость %12.2f  время %10.2f мс  план %s\n", name, v.Cost, *v.ActualTime, v.Fingerprint)
+		default:
+			fmt.Fprintf(stdout, "%-45s стоимость %12.2f  план %s\n", name, v.Cost, v.Fingerprint)
+		}
+	}
+	fmt.Fprintf(stdout, "\nНайдено планов: %d\n", len(report.Plans))
+	for _, plan := range report.Plans {
+		marker := " "
+		if plan.Chosen {
+			marker = "*"
+		}
+		fmt.Fprintf(stdout, "%s %s  %s\n", marker, plan.Fingerprint, plan.Shape)
+		if plan.CostError != nil {
+			fmt.Fprintf(stdout, "    ошибка оценки относительно выбранного плана: %.2f\n", *plan.CostError)
+		}
+	}
+	if len(report.Findings) > 0 {
+		fmt.Fprintln(stdout)
+	}
+	for _, f := range report.Findings {
+		fmt.Fprintln(stdout, f.Description)
+	}
+	if h := report.Hints; h != nil {
+		fmt.Fprintf(stdout, "\nПодсказки pg_hint_plan для варианта %s:\n%s\n", report.Fastest, h.Block)
+		switch {
+		case h.Verified != nil && *h.Verified:
+			fmt.Fprintln(stdout, "Проверено: с подсказками планировщик выбирает этот план")
+		case h.Verified != nil:
+			fmt.Fprintf(stdout, "С подсказками получен другой план: %s\n", h.Shape)
+		case h.Error != "":
+			fmt.Fprintf(stdout, "Подсказки не проверены: %s\n", h.Error)
+		}
+	}
+
+	if jsonPath != "" {
+		err := writeFile(jsonPath, func(w io.Writer) error {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		})
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// singleQuery возвращает запрос из флага -query или из единственного
+// SQL-файла в аргументах; при ошибке возвращается код завершения
+func singleQuery(query string, args []string, stderr io.Writer) (string, int) {
+	if query != "" {
+		return query, 0
+	}
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "Укажите запрос флагом -query или один SQL-файл")
+		return "", 2
+	}
+	statements, err := sqlsource.ParseFile(args[0])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return "", 1
+	}
+	if len(statements) != 1 {
+		fmt.Fprintf(stderr, "В файле %s должен быть один запрос, найдено: %d\n", args[0], len(statements))
+		return "", 2
+	}
+	return statements[0].Text, 0
+}
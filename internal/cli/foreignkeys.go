@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"sql-optimizer/internal/catalog"
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+)
+
+// runForeignKeys находит внешние ключи без индекса и выводит команды
+// создания индексов
+func runForeignKeys(args []string, stdout, stderr io.Writer) int {
+	var (
+		dsn, jsonPath, lang string
+		timeout             time.Duration
+	)
+	flags := flag.NewFlagSet("foreign-keys", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&dsn, "dsn", os.Getenv("DATABASE_URL"), "строка подключения к PostgreSQL (по умолчанию $DATABASE_URL)")
+	flags.StringVar(&jsonPath, "json", "", "записать полный результат в JSON-файл")
+	flags.StringVar(&lang, "lang", defaultLocale(), "язык находок: ru или en (по умолчанию из $LANG)")
+	flags.DurationVar(&timeout, "timeout", time.Minute, "таймаут проверки")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if !i18n.Supported(lang) {
+		fmt.Fprintf(stderr, "Неподдерживаемый язык %q\n", lang)
+		return 2
+	}
+	if dsn == "" {
+		fmt.Fprintln(stderr, "Не указана строка подключения: используйте -dsn или $DATABASE_URL")
+		return 2
+	}
+
+	client, err := postgres.NewClient(dsn)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	report, err := catalog.LoadForeignKeyReport(ctx, client)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	report.Localize(lang)
+
+	if len(report.Missing) == 0 {
+		fmt.Fprintln(stdout, "Все внешние ключи проиндексированы")
+	}
+	for _, key := range report.Missing {
+		fmt.Fprintf(stdout, "[%s] %s\n", key.Severity, key.Description)
+		fmt.Fprintf(stdout, "    строк: %d, размер: %s, seq scan: %d, удалений и изменений в %s: %d, ON DELETE %s\n",
+			key.TableRows, formatBytes(key.TableBytes), key.SeqScans, key.ReferencedTable, key.ParentWrites,
+			key.OnDelete)
+		fmt.Fprintf(stdout, "    %s\n", key.DDL)
+	}
+
+	if jsonPath != "" {
+		err := writeFile(jsonPath, func(w io.Writer) error {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		})
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	return 0
+}
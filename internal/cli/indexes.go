@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"sql-optimizer/internal/catalog"
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+)
+
+// runIndexes проверяет индексы базы и выводит те, что можно удалить,
+// с командами DROP INDEX CONCURRENTLY
+func runIndexes(args []string, stdout, stderr io.Writer) int {
+	var (
+		dsn, jsonPath, lang string
+		rare                int64
+		timeout             time.Duration
+	)
+	flags := flag.NewFlagSet("indexes", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&dsn, "dsn", os.Getenv("DATABASE_URL"), "строка подключения к PostgreSQL (по умолчанию $DATABASE_URL)")
+	flags.Int64Var(&rare, "rare-scans", catalog.DefaultRareScans, "индексы с не большим числом сканирований считаются редко используемыми")
+	flags.StringVar(&jsonPath, "json", "", "записать полный результат в JSON-файл")
+	flags.StringVar(&lang, "lang", defaultLocale(), "язык находок: ru или en (по умолчанию из $LANG)")
+	flags.DurationVar(&timeout, "timeout", time.Minute, "таймаут проверки")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if !i18n.Supported(lang) {
+		fmt.Fprintf(stderr, "Неподдерживаемый язык %q\n", lang)
+		return 2
+	}
+	if dsn == "" {
+		fmt.Fprintln(stderr, "Не указана строка подключения: используйте -dsn или $DATABASE_URL")
+		return 2
+	}
+
+	client, err := postgres.NewClient(dsn)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	report, err := catalog.LoadIndexReport(ctx, client, catalog.IndexOptions{RareScans: rare})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	report.Localize(lang)
+
+	if report.StatsReset != nil {
+		fmt.Fprintf(stdout, "Статистика использования накоплена с %s\n\n", report.StatsReset.Format("2006-01-02 15:04"))
+	}
+	if len(report.Issues) == 0 {
+		fmt.Fprintln(stdout, "Лишних индексов не найдено")
+	}
+	for _, issue := range report.Issues {
+		fmt.Fprintf(stdout, "[%s] %s (%s, сканирований: %d)\n", issue.Kind, issue.Description, formatBytes(issue.SizeBytes), issue.Scans)
+		fmt.Fprintf(stdout, "    %s\n", issue.DDL)
+	}
+	if report.ReclaimableBytes > 0 {
+		fmt.Fprintf(stdout, "\nМожно освободить: %s\n", formatBytes(report.ReclaimableBytes))
+	}
+	for _, index := range report.Protected {
+		fmt.Fprintf(stdout, "  %s (%s) не удаляется: на него опираются ограничения или он уникален\n", index.Index, index.Kind)
+	}
+
+	if jsonPath != "" {
+		err := writeFile(jsonPath, func(w io.Writer) error {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		})
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+	"sql-optimizer/internal/statistics"
+)
+
+// runStatistics ищет ошибки оценок запроса, которые исправляет статистика
+func runStatistics(args []string, stdout, stderr io.Writer) int {
+	var (
+		dsn, query, jsonPath, lang string
+		verify                     bool
+		timeout                    time.Duration
+	)
+	flags := flag.NewFlagSet("statistics", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&dsn, "dsn", os.Getenv("DATABASE_URL"), "строка подключения к PostgreSQL (по умолчанию $DATABASE_URL)")
+	flags.StringVar(&query, "query", "", "текст запроса (вместо SQL-файла)")
+	flags.BoolVar(&verify, "verify", false, "проверить рекомендации в откатываемой транзакции (ANALYZE все равно обновит last_analyze таблиц)")
+	flags.StringVar(&jsonPath, "json", "", "записать полный результат в JSON-файл")
+	flags.StringVar(&lang, "lang", defaultLocale(), "язык рекомендаций: ru или en (по умолчанию из $LANG)")
+	flags.DurationVar(&timeout, "timeout", statistics.DefaultTimeout, "таймаут выполнения запроса и каждой проверки")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if !i18n.Supported(lang) {
+		fmt.Fprintf(stderr, "Неподдерживаемый язык %q\n", lang)
+		return 2
+	}
+	if dsn == "" {
+		fmt.Fprintln(stderr, "Не указана строка подключения: используйте -dsn или $DATABASE_URL")
+		return 2
+	}
+	query, code := singleQuery(query, flags.Args(), stderr)
+	if code != 0 {
+		return code
+	}
+
+	client, err := postgres.NewClient(dsn)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	planJSON, err := client.GetExplainPlanSafe(ctx, query, timeout)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	plan, err := analyzer.ParseExplainJSON(planJSON)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	report, err := statistics.Advise(ctx, client, query, plan, statistics.Options{Verify: verify, Timeout: timeout})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	report.Localize(lang)
+
+	for _, e := range report.Stale.Estimates {
+		fmt.Fprintln(stdout, e.Description)
+		if e.Command != "" {
+			fmt.Fprintf(stdout, "    %s\n", e.Command)
+		}
+		if e.ResultDescription != "" {
+			fmt.Fprintf(stdout, "    %s\n", e.ResultDescription)
+		}
+	}
+	if report.Stale.RecheckError != "" {
+		fmt.Fprintf(stdout, "Проверка после ANALYZE не удалась: %s\n", report.Stale.RecheckError)
+	}
+
+	if len(report.Extended.Recommendations) == 0 {
+		fmt.Fprintln(stdout, "Ошибок оценок, которые исправила бы расширенная статистика, не найдено")
+	}
+	for _, rec := range report.Extended.Recommendations {
+		fmt.Fprintf(stdout, "%s\n    %s\n    %s\n", rec.Description, rec.DDL, rec.Analyze)
+		switch {
+		case rec.ResultDescription != "":
+			fmt.Fprintf(stdout, "    %s\n", rec.ResultDescription)
+		case rec.VerifyError != "":
+			fmt.Fprintf(stdout, "    Проверка не удалась: %s\n", rec.VerifyError)
+		}
+	}
+
+	if jsonPath != "" {
+		err := writeFile(jsonPath, func(w io.Writer) error {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		})
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"sql-optimizer/internal/history"
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+	"sql-optimizer/internal/recommendation"
+	"sql-optimizer/internal/tuning"
+)
+
+// runTune рекомендует значения параметров сервера
+func runTune(args []string, stdout, stderr io.Writer) int {
+	var (
+		dsn, memory, storage, historyDir, jsonPath, lang string
+		cpus                                             int
+		timeout                                          time.Duration
+	)
+	flags := flag.NewFlagSet("tune", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&dsn, "dsn", os.Getenv("DATABASE_URL"), "строка подключения к PostgreSQL (по умолчанию $DATABASE_URL)")
+	flags.StringVar(&memory, "memory", "", "память сервера, например 16GB или 512MB")
+	flags.IntVar(&cpus, "cpus", 0, "число ядер сервера")
+	flags.StringVar(&storage, "storage", "", "хранилище данных: ssd или hdd")
+	flags.StringVar(&historyDir, "history", "", "каталог истории анализов: планы этой базы служат доказательствами")
+	flags.StringVar(&jsonPath, "json", "", "записать полный результат в JSON-файл")
+	flags.StringVar(&lang, "lang", defaultLocale(), "язык обоснований: ru или en (по умолчанию из $LANG)")
+	flags.DurationVar(&timeout, "timeout", 30*time.Second, "таймаут чтения параметров")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if !i18n.Supported(lang) {
+		fmt.Fprintf(stderr, "Неподдерживаемый язык %q\n", lang)
+		return 2
+	}
+	if dsn == "" {
+		fmt.Fprintln(stderr, "Не указана строка подключения: используйте -dsn или $DATABASE_URL")
+		return 2
+	}
+	if storage != "" && storage != recommendation.StorageSSD && storage != recommendation.StorageHDD {
+		fmt.Fprintf(stderr, "Неизвестный тип хранилища %q: ожидается ssd или hdd\n", storage)
+		return 2
+	}
+	hardware := recommendation.Hardware{CPUs: cpus, Storage: storage}
+	if memory != "" {
+		mb, err := parseMemoryMB(memory)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		hardware.MemoryMB = mb
+	}
+
+	opts := tuning.Options{Hardware: hardware}
+	if historyDir != "" {
+		store, err := history.NewFileStore(historyDir)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		if opts.Plans, err = tuning.HistoryPlans(store, postgres.DatabaseName(dsn)); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+
+	client, err := postgres.NewClient(dsn)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	report, err := tuning.Advise(ctx, client, opts)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	report.Localize(lang)
+
+	if report.Plans.Plans > 0 {
+		fmt.Fprintf(stdout, "Просмотрено планов: %d (сбросов на диск: %d, Gather без процессов: %d)\n\n",
+			report.Plans.Plans, report.Plans.DiskSorts+report.Plans.HashSpills, report.Plans.ShortOfWorkers)
+	}
+	if len(report.Recommendations) == 0 {
+		fmt.Fprintln(stdout, "Рекомендаций нет: укажите -memory, -cpus и -storage, чтобы получить больше")
+	}
+	for _, rec := range report.Recommendations {
+		fmt.Fprintf(stdout, "%s: %s → %s\n", rec.Name, rec.Current, rec.Recommended)
+		fmt.Fprintf(stdout, "    %s\n", rec.Rationale)
+		fmt.Fprintf(stdout, "    %s\n", rec.Command)
+	}
+
+	if jsonPath != "" {
+		err := writeFile(jsonPath, func(w io.Writer) error {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		})
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// parseMemoryMB разбирает объем памяти вида 16GB, 512MB или 1TB; число
+// без единицы считается мегабайтами
+func parseMemoryMB(value string) (int64, error) {
+	units := map[string]int64{"": 1, "MB": 1, "GB": 1 << 10, "TB": 1 << 20}
+	upper := strings.ToUpper(strings.TrimSpace(value))
+	number := strings.TrimRight(upper, "BGMT")
+	multiplier, ok := units[strings.TrimPrefix(upper, number)]
+	n, err := strconv.ParseInt(strings.TrimSpace(number), 10, 64)
+	if !ok || err != nil || n <= 0 {
+		return 0, fmt.Errorf("неверный объем памяти %q: ожидается, например, 16GB или 512MB", value)
+	}
+	return n * multiplier, nil
+}
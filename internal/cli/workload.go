@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+	"sql-optimizer/internal/workload"
+)
+
+// runWorkload анализирует нагрузку из pg_stat_statements и выводит
+// рейтинг операторов, сводные находки и индексы
+func runWorkload(args []string, stdout, stderr io.Writer) int {
+	var (
+		dsn, order, jsonPath, lang string
+		top                        int
+		timeout                    time.Duration
+	)
+	flags := flag.NewFlagSet("workload", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&dsn, "dsn", os.Getenv("DATABASE_URL"), "строка подключения к PostgreSQL (по умолчанию $DATABASE_URL)")
+	flags.StringVar(&order, "order", workload.OrderTotalTime, "порядок: total_time, mean_time, calls или shared_blks_read")
+	flags.IntVar(&top, "top", 10, "для скольких операторов анализировать планы")
+	flags.StringVar(&jsonPath, "json", "", "записать полный результат в JSON-файл")
+	flags.StringVar(&lang, "lang", defaultLocale(), "язык находок: ru или en (по умолчанию из $LANG)")
+	flags.DurationVar(&timeout, "timeout", 2*time.Minute, "таймаут анализа нагрузки")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if !i18n.Supported(lang) {
+		fmt.Fprintf(stderr, "Неподдерживаемый язык %q\n", lang)
+		return 2
+	}
+	if dsn == "" {
+		fmt.Fprintln(stderr, "Не указана строка подключения: используйте -dsn или $DATABASE_URL")
+		return 2
+	}
+
+	client, err := postgres.NewClient(dsn)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	report, err := workload.Analyze(ctx, client, workload.Options{OrderBy: order, TopN: top, Locale: lang})
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "Общее время операторов: %.0f мс\n\n", report.TotalTime)
+	fmt.Fprintf(stdout, "%3s %6s %12s %10s %10s %12s  %s\n", "#", "доля", "всего, мс", "среднее", "вызовы", "чтения", "запрос")
+	for i, s := range report.Statements {
+		fmt.Fprintf(stdout, "%3d %5.1f%% %12.0f %10.2f %10d %12d  %s\n", i+1, s.Share, s.TotalTime, s.MeanTime,
+			s.Calls, s.SharedBlksRead, truncateQuery(s.Query, 60))
+		if s.PlanError != "" {
+			fmt.Fprintf(stdout, "    план не получен: %s\n", s.PlanError)
+		}
+	}
+
+	if len(report.Findings) > 0 {
+		fmt.Fprintf(stdout, "\nНаходки по нагрузке:\n")
+		for _, f := range report.Findings {
+			fmt.Fprintf(stdout, "  %5.1f%%  [%s] %s (операторов: %d)\n", f.Weight, f.Severity, f.Description, len(f.Statements))
+		}
+	}
+	if len(report.Consolidated.Indexes) > 0 {
+		fmt.Fprintf(stdout, "\nИтоговый набор индексов:\n")
+		for _, index := range report.Consolidated.Indexes {
+			fmt.Fprintf(stdout, "  %5.1f%%  %s\n", index.Weight, index.DDL)
+			fmt.Fprintf(stdout, "          запросов: %d, размер: ~%s, записей в индекс: %d\n",
+				len(index.Queries), formatBytes(index.EstimatedBytes), index.WriteOverhead)
+		}
+	}
+	for _, covered := range report.Consolidated.Covered {
+		fmt.Fprintf(stdout, "  %s уже обслуживает индекс %s\n", covered.Candidate, covered.Index)
+	}
+
+	if jsonPath != "" {
+		err := writeFile(jsonPath, func(w io.Writer) error {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		})
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// truncateQuery сводит запрос в одну строку и обрезает до limit символов
+func truncateQuery(query string, limit int) string {
+	line := strings.Join(strings.Fields(query), " ")
+	if runes := []rune(line); len(runes) > limit {
+		return string(runes[:limit-3]) + "..."
+	}
+	return line
+}
+
+// formatBytes выводит размер в КБ, МБ или ГБ
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f ГБ", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f МБ", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.0f КБ", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d Б", n)
+	}
+}
@@ -0,0 +1,443 @@
+// Package experiment проверяет модель стоимости планировщика: строит план
+// запроса заново под разными параметрами (enable_*, jit, work_mem...),
+// находит альтернативные планы и сравнивает их стоимость с фактическим
+// временем выполнения.
+package experiment
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/fingerprint"
+	"sql-optimizer/internal/hints"
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+)
+
+// MaxVariants — сколько вариантов допускается в одном эксперименте:
+// с ANALYZE каждый вариант выполняет запрос
+const MaxVariants = 64
+
+// DefaultTimeout — statement_timeout одного варианта по умолчанию
+const DefaultTimeout = 30 * time.Second
+
+// MinSpeedup — во сколько раз альтернатива должна быть быстрее плана
+// планировщика, чтобы это не списывалось на погрешность измерения
+const MinSpeedup = 1.2
+
+// disableCost — стоимость, которую PostgreSQL до версии 18 добавляет
+// к запрещенному узлу, если обойтись без него нельзя
+const disableCost = 1e10
+
+// defaultMatrix — переключатели по умолчанию: каждый меняется отдельно
+var defaultMatrix = []Dimension{
+	{Name: "enable_seqscan", Values: []string{"off"}},
+	{Name: "enable_indexscan", Values: []string{"off"}},
+	{Name: "enable_bitmapscan", Values: []string{"off"}},
+	{Name: "enable_nestloop", Values: []string{"off"}},
+	{Name: "enable_hashjoin", Values: []string{"off"}},
+	{Name: "enable_mergejoin", Values: []string{"off"}},
+	{Name: "max_parallel_workers_per_gather", Values: []string{"0"}},
+	{Name: "jit", Values: []string{"off"}},
+	{Name: "work_mem", Values: []string{"256MB"}},
+}
+
+// Source — то, что проводит эксперимент и проверяет подсказки для
+// найденного плана; его реализует *postgres.Client
+type Source interface {
+	Experiment(ctx context.Context, query string, opts postgres.ExperimentOptions) ([]postgres.ExperimentRun, error)
+	hints.Source
+}
+
+// Dimension — параметр и значения, которые он принимает в эксперименте
+type Dimension struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// Options — параметры эксперимента
+type Options struct {
+	// Matrix — параметры, все сочетания значений которых проверяются;
+	// если пусто, каждый переключатель по умолчанию проверяется отдельно
+	Matrix  []Dimension
+	Analyze bool
+	// Timeout — statement_timeout одного варианта, по умолчанию DefaultTimeout
+	Timeout time.Duration
+}
+
+// Variant — результат одного варианта параметров
+type Variant struct {
+	Name        string                   `json:"name"`
+	Settings    []postgres.PlannerToggle `json:"settings"`
+	Fingerprint string                   `json:"plan_fingerprint,omitempty"`
+	Cost        float64                  `json:"cost"`
+	ActualTime  *float64                 `json:"actual_time,omitempty"` // мс
+	// Disabled — в плане остался запрещенный узел, и стоимость включает
+	// штраф за него
+	Disabled bool                `json:"disabled,omitempty"`
+	Plan     []analyzer.PlanNode `json:"plan,omitempty"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// Alternative — отдельный план, найденный в эксперименте
+type Alternative struct {
+	Fingerprint string   `json:"plan_fingerprint"`
+	Shape       string   `json:"shape"` // краткая запись дерева плана
+	Variants    []string `json:"variants"`
+	Chosen      bool     `json:"chosen"` // план, который выбирает планировщик
+	Cost        float64  `json:"cost"`
+	ActualTime  *float64 `json:"actual_time,omitempty"`
+	// CostError — во сколько раз время на единицу стоимости у этого плана
+	// больше, чем у выбранного: больше 1 — планировщик недооценил план,
+	// меньше 1 — переоценил
+	CostError *float64 `json:"cost_error,omitempty"`
+	Disabled  bool     `json:"disabled,omitempty"`
+}
+
+// Finding — вывод эксперимента
+type Finding struct {
+	Message     i18n.Message `json:"message"`
+	Description string       `json:"description"`
+}
+
+// Report — результат эксперимента
+type Report struct {
+	Analyze  bool          `json:"analyze"`
+	Variants []Variant     `json:"variants"`
+	Plans    []Alternative `json:"plans"`
+	// Fastest — вариант с наименьшим фактическим временем (только с ANALYZE)
+	Fastest string `json:"fastest,omitempty"`
+	// Speedup — во сколько раз самый быстрый план быстрее выбранного
+	Speedup *float64 `json:"speedup,omitempty"`
+	// Correlation — ранговая корреляция Спирмена стоимости и времени
+	// по найденным планам; близкая к 1 — стоимость верно упорядочивает планы
+	Correlation *float64 `json:"correlation,omitempty"`
+	// Hints — подсказки pg_hint_plan, закрепляющие самый быстрый план,
+	// если он заметно быстрее выбранного планировщиком
+	Hints    *hints.Result `json:"hints,omitempty"`
+	Findings []Finding     `json:"findings"`
+}
+
+// Matrix возвращает варианты для всех сочетаний значений параметров
+func Matrix(dimensions []Dimension) []postgres.PlanVariant {
+	variants := []postgres.PlanVariant{{}}
+	for _, d := range dimensions {
+		var next []postgres.PlanVariant
+		for _, v := range variants {
+			for _, value := range d.Values {
+				settings := append(append([]postgres.PlannerToggle{}, v.Settings...), postgres.PlannerToggle{Name: d.Name, Value: value})
+				next = append(next, postgres.PlanVariant{Settings: settings})
+			}
+		}
+		variants = next
+	}
+	for i := range variants {
+		variants[i].Name = variantName(variants[i].Settings)
+	}
+	return variants
+}
+
+// DefaultVariants возвращает варианты по умолчанию: каждый переключатель
+// меняется отдельно от остальных
+func DefaultVariants() []postgres.PlanVariant {
+	var variants []postgres.PlanVariant
+	for _, d := range defaultMatrix {
+		variants = append(variants, Matrix([]Dimension{d})...)
+	}
+	return variants
+}
+
+// Variants возвращает варианты эксперимента и проверяет, что параметры
+// допустимы, а вариантов не слишком много
+func Variants(opts Options) ([]postgres.PlanVariant, error) {
+	if len(opts.Matrix) == 0 {
+		return DefaultVariants(), nil
+	}
+	count := 1
+	for _, d := range opts.Matrix {
+		if !postgres.PlannerSettingAllowed(d.Name) {
+			return nil, fmt.Errorf("параметр %s нельзя менять в эксперименте", d.Name)
+		}
+		if len(d.Values) == 0 {
+			return nil, fmt.Errorf("для параметра %s не заданы значения", d.Name)
+		}
+		count *= len(d.Values)
+		if count > MaxVariants {
+			return nil, fmt.Errorf("слишком много сочетаний параметров: больше %d", MaxVariants)
+		}
+	}
+	return Matrix(opts.Matrix), nil
+}
+
+// Run проводит эксперимент с запросом query и оценивает результат
+func Run(ctx context.Context, src Source, query string, opts Options) (*Report, error) {
+	variants, err := Variants(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	runs, err := src.Experiment(ctx, query, postgres.ExperimentOptions{
+		Variants: variants,
+		Analyze:  opts.Analyze,
+		Timeout:  opts.Timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	report := Evaluate(runs, opts.Analyze)
+	if v := report.better(); v != nil {
+		report.Hints = hints.Suggest(ctx, src, query, v.Plan, v.Settings)
+	}
+	return report, nil
+}
+
+// better возвращает самый быстрый вариант, если его план отличается от
+// выбранного планировщиком и быстрее хотя бы в MinSpeedup раз
+func (r *Report) better() *Variant {
+	if r.Fastest == "" || r.Speedup == nil || *r.Speedup < MinSpeedup {
+		return nil
+	}
+	for i := range r.Variants {
+		v := &r.Variants[i]
+		if v.Name == r.Fastest && v.Error == "" && v.Fingerprint != r.Plans[0].Fingerprint {
+			return v
+		}
+	}
+	return nil
+}
+
+// Evaluate группирует варианты по форме плана и сравнивает стоимость
+// планов с их временем. Первым в runs должен идти базовый вариант.
+func Evaluate(runs []postgres.ExperimentRun, analyze bool) *Report {
+	report := &Report{Analyze: analyze, Variants: make([]Variant, 0, len(runs)), Plans: []Alternative{}, Findings: []Finding{}}
+	for _, run := range runs {
+		report.Variants = append(report.Variants, variant(run))
+	}
+
+	byFingerprint := make(map[string]int)
+	for i, v := range report.Variants {
+		if v.Error != "" {
+			continue
+		}
+		idx, ok := byFingerprint[v.Fingerprint]
+		if !ok {
+			idx = len(report.Plans)
+			byFingerprint[v.Fingerprint] = idx
+			report.Plans = append(report.Plans, Alternative{
+				Fingerprint: v.Fingerprint,
+				Shape:       fingerprint.Shape(v.Plan),
+				Cost:        v.Cost,
+				ActualTime:  v.ActualTime,
+				Disabled:    v.Disabled,
+			})
+		}
+		plan := &report.Plans[idx]
+		plan.Variants = append(plan.Variants, v.Name)
+		plan.Chosen = plan.Chosen || i == 0
+		// стоимость плана берется из варианта без штрафа за запрещенный
+		// узел, время — лучшее из измеренных
+		if plan.Disabled && !v.Disabled {
+			plan.Cost, plan.Disabled = v.Cost, false
+		}
+		if v.ActualTime != nil && (plan.ActualTime == nil || *v.ActualTime < *plan.ActualTime) {
+			plan.ActualTime = v.ActualTime
+		}
+	}
+
+	if len(report.Plans) == 0 || !report.Plans[0].Chosen {
+		// базовый вариант не удался: сравнивать не с чем
+		return report
+	}
+	if len(report.Plans) == 1 {
+		report.addFinding(i18n.New(i18n.ExperimentNoAlternatives))
+		report.Localize(i18n.DefaultLocale)
+		return report
+	}
+	if analyze {
+		report.compareTimes()
+	} else {
+		report.compareCosts()
+	}
+	report.Localize(i18n.DefaultLocale)
+	return report
+}
+
+// compareCosts без фактического времени называет ближайшую по стоимости
+// альтернативу
+func (r *Report) compareCosts() {
+	chosen := r.Plans[0]
+	nearest := -1
+	for i, plan := range r.Plans[1:] {
+		if !plan.Disabled && (nearest < 0 || plan.Cost < r.Plans[nearest].Cost) {
+			nearest = i + 1
+		}
+	}
+	if nearest < 0 || chosen.Cost <= 0 {
+		return
+	}
+	r.addFinding(i18n.New(i18n.ExperimentAlternatives,
+		"count", strconv.Itoa(len(r.Plans)-1),
+		"variant", r.Plans[nearest].Variants[0],
+		"factor", formatRatio(r.Plans[nearest].Cost/chosen.Cost)))
+}
+
+// compareTimes сравнивает фактическое время планов с их стоимостью
+func (r *Report) compareTimes() {
+	chosen := &r.Plans[0]
+	if chosen.ActualTime == nil {
+		return
+	}
+
+	fastest := chosen
+	var costs, times []float64
+	maxError := 1.0
+	for i := range r.Plans {
+		plan := &r.Plans[i]
+		if plan.ActualTime == nil {
+			continue
+		}
+		if *plan.ActualTime < *fastest.ActualTime {
+			fastest = plan
+		}
+		if plan.Disabled || plan.Cost <= 0 || chosen.Cost <= 0 || *chosen.ActualTime <= 0 {
+			continue
+		}
+		costError := (*plan.ActualTime / plan.Cost) / (*chosen.ActualTime / chosen.Cost)
+		plan.CostError = &costError
+		maxError = max(maxError, costError, 1/costError)
+		costs = append(costs, plan.Cost)
+		times = append(times, *plan.ActualTime)
+	}
+
+	r.Fastest = fastest.Variants[0]
+	if *fastest.ActualTime > 0 {
+		speedup := *chosen.ActualTime / *fastest.ActualTime
+		r.Speedup = &speedup
+	}
+	if len(costs) >= 3 {
+		correlation := spearman(costs, times)
+		r.Correlation = &correlation
+	}
+
+	switch {
+	case fastest != chosen && r.Speedup != nil && *r.Speedup >= MinSpeedup:
+		r.addFinding(i18n.New(i18n.ExperimentBetterPlan,
+			"variant", fastest.Variants[0],
+			"time", formatMs(*fastest.ActualTime),
+			"factor", formatRatio(*r.Speedup),
+			"baseline", formatMs(*chosen.ActualTime),
+			"cost", formatCost(fastest.Cost),
+			"baseline_cost", formatCost(chosen.Cost)))
+	case fastest == chosen:
+		r.addFinding(i18n.New(i18n.ExperimentConfirmed, "count", strconv.Itoa(len(r.Plans))))
+	}
+	if r.Correlation != nil && *r.Correlation < 0.5 {
+		r.addFinding(i18n.New(i18n.ExperimentCostModel,
+			"count", strconv.Itoa(len(costs)),
+			"correlation", strconv.FormatFloat(*r.Correlation, 'f', 2, 64),
+			"error", formatRatio(maxError)))
+	}
+}
+
+func (r *Report) addFinding(message i18n.Message) {
+	r.Findings = append(r.Findings, Finding{Message: message})
+}
+
+// Localize формирует тексты выводов на языке locale
+func (r *Report) Localize(locale string) {
+	for i := range r.Findings {
+		r.Findings[i].Description = r.Findings[i].Message.Format(locale)
+	}
+}
+
+// variant разбирает план одного варианта
+func variant(run postgres.ExperimentRun) Variant {
+	v := Variant{Name: run.Variant.Name, Settings: run.Variant.Settings, Error: run.Error}
+	if v.Error != "" {
+		return v
+	}
+	plan, err := analyzer.ParseExplainJSON(run.Plan)
+	if err != nil || len(plan) == 0 {
+		v.Error = fmt.Sprintf("не удалось разобрать план: %v", err)
+		return v
+	}
+	v.Plan = plan
+	v.Fingerprint = fingerprint.Plan(plan)
+	v.Cost = plan[0].TotalCost
+	v.ActualTime = plan[0].ActualTotalTime
+	v.Disabled = v.Cost >= disableCost
+	return v
+}
+
+// variantName составляет имя варианта из его параметров
+func variantName(settings []postgres.PlannerToggle) string {
+	parts := make([]string, 0, len(settings))
+	for _, s := range settings {
+		parts = append(parts, s.Name+"="+s.Value)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// spearman возвращает ранговую корреляцию Спирмена двух рядов
+func spearman(x, y []float64) float64 {
+	rx, ry := ranks(x), ranks(y)
+	n := float64(len(x))
+	var meanX, meanY float64
+	for i := range rx {
+		meanX += rx[i] / n
+		meanY += ry[i] / n
+	}
+	var cov, varX, varY float64
+	for i := range rx {
+		cov += (rx[i] - meanX) * (ry[i] - meanY)
+		varX += (rx[i] - meanX) * (rx[i] - meanX)
+		varY += (ry[i] - meanY) * (ry[i] - meanY)
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
+
+// ranks возвращает ранги значений; равным значениям достается средний ранг
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return values[order[a]] < values[order[b]] })
+
+	result := make([]float64, len(values))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && values[order[j+1]] == values[order[i]] {
+			j++
+		}
+		rank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			result[order[k]] = rank
+		}
+		i = j + 1
+	}
+	return result
+}
+
+func formatRatio(f float64) string {
+	return strconv.FormatFloat(f, 'f', 1, 64)
+}
+
+func formatMs(f float64) string {
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
+
+func formatCost(f float64) string {
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
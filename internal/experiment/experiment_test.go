@@ -0,0 +1,109 @@
+package experiment
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+)
+
+// explain возвращает EXPLAIN в формате JSON с корнем node, стоимостью
+// cost и временем ms (отрицательное — без ANALYZE)
+func explain(node string, cost, ms float64) string {
+	actual := ""
+	if ms >= 0 {
+		actual = fmt.Sprintf(`, "Actual Total Time": %g`, ms)
+	}
+	return fmt.Sprintf(`[{"Plan": {"Node Type": %q, "Relation Name": "orders", "Total Cost": %g%s}}]`, node, cost, actual)
+}
+
+func TestMatrix(t *testing.T) {
+	variants := Matrix([]Dimension{
+		{Name: "enable_nestloop", Values: []string{"on", "off"}},
+		{Name: "work_mem", Values: []string{"4MB", "64MB", "256MB"}},
+	})
+	if len(variants) != 6 {
+		t.Fatalf("вариантов %d, ожидалось 6", len(variants))
+	}
+	if got := variants[5].Name; got != "enable_nestloop=off, work_mem=256MB" {
+		t.Errorf("имя последнего варианта %q", got)
+	}
+	if len(variants[0].Settings) != 2 || variants[0].Settings[1].Value != "4MB" {
+		t.Errorf("параметры первого варианта %v", variants[0].Settings)
+	}
+
+	if _, err := Variants(Options{Matrix: []Dimension{{Name: "fsync", Values: []string{"off"}}}}); err == nil {
+		t.Error("параметр fsync не должен допускаться")
+	}
+	if len(DefaultVariants()) != len(defaultMatrix) {
+		t.Errorf("вариантов по умолчанию %d", len(DefaultVariants()))
+	}
+}
+
+func TestEvaluateBetterPlan(t *testing.T) {
+	runs := []postgres.ExperimentRun{
+		{Variant: postgres.PlanVariant{Name: "baseline"}, Plan: explain("Seq Scan", 100, 50)},
+		{Variant: postgres.PlanVariant{Name: "enable_seqscan=off"}, Plan: explain("Index Scan", 300, 10)},
+		{Variant: postgres.PlanVariant{Name: "jit=off"}, Plan: explain("Seq Scan", 100, 48)},
+		{Variant: postgres.PlanVariant{Name: "enable_indexscan=off"}, Plan: explain("Bitmap Heap Scan", 200, 20)},
+		{Variant: postgres.PlanVariant{Name: "work_mem=256MB"}, Error: "canceling statement due to statement timeout"},
+	}
+	report := Evaluate(runs, true)
+
+	if len(report.Plans) != 3 {
+		t.Fatalf("планов %d, ожидалось 3", len(report.Plans))
+	}
+	if !report.Plans[0].Chosen || *report.Plans[0].ActualTime != 48 {
+		t.Errorf("выбранный план %+v", report.Plans[0])
+	}
+	if report.Fastest != "enable_seqscan=off" || *report.Speedup != 4.8 {
+		t.Errorf("самый быстрый %q, ускорение %v", report.Fastest, *report.Speedup)
+	}
+	if report.Correlation == nil || *report.Correlation != -1 {
+		t.Errorf("корреляция %v, ожидалась -1", report.Correlation)
+	}
+	if v := report.better(); v == nil || v.Name != "enable_seqscan=off" {
+		t.Errorf("для подсказок выбран вариант %+v", v)
+	}
+	if e := *report.Plans[1].CostError; e < 0.06 || e > 0.07 {
+		t.Errorf("ошибка оценки %v", e)
+	}
+
+	var ids []string
+	for _, f := range report.Findings {
+		ids = append(ids, f.Message.ID)
+	}
+	if strings.Join(ids, ",") != i18n.ExperimentBetterPlan+","+i18n.ExperimentCostModel {
+		t.Errorf("выводы %v", ids)
+	}
+	if !strings.Contains(report.Findings[0].Description, "в 4.8 раза быстрее") {
+		t.Errorf("описание: %s", report.Findings[0].Description)
+	}
+}
+
+func TestEvaluateCostsOnly(t *testing.T) {
+	runs := []postgres.ExperimentRun{
+		{Variant: postgres.PlanVariant{Name: "baseline"}, Plan: explain("Index Scan", 100, -1)},
+		// индексное сканирование запрещено, но обойтись без него нельзя
+		{Variant: postgres.PlanVariant{Name: "enable_indexscan=off"}, Plan: explain("Index Scan", 1e10+100, -1)},
+		{Variant: postgres.PlanVariant{Name: "enable_seqscan=off"}, Plan: explain("Bitmap Heap Scan", 250, -1)},
+	}
+	report := Evaluate(runs, false)
+
+	if len(report.Plans) != 2 || report.Plans[0].Disabled || len(report.Plans[0].Variants) != 2 {
+		t.Fatalf("планы %+v", report.Plans)
+	}
+	if len(report.Findings) != 1 || report.Findings[0].Message.ID != i18n.ExperimentAlternatives {
+		t.Fatalf("выводы %+v", report.Findings)
+	}
+	if got := report.Findings[0].Message.Args["factor"]; got != "2.5" {
+		t.Errorf("отношение стоимостей %s", got)
+	}
+
+	single := Evaluate(runs[:2], false)
+	if len(single.Findings) != 1 || single.Findings[0].Message.ID != i18n.ExperimentNoAlternatives {
+		t.Errorf("выводы без альтернатив %+v", single.Findings)
+	}
+}
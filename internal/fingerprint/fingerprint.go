@@ -0,0 +1,83 @@
+// Package fingerprint вычисляет устойчивые отпечатки запросов и планов,
+// по которым повторные анализы одного и того же запроса группируются
+// в истории и сравниваются между собой.
+package fingerprint
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"sql-optimizer/internal/analyzer"
+)
+
+// Query возвращает отпечаток запроса — хеш нормализованного текста.
+// Как и queryid в pg_stat_statements, он не зависит от значений
+// констант, длины списков IN, пробелов, регистра ключевых слов
+// и комментариев.
+func Query(query string) string {
+	return hash(strings.Join(tokenTexts(normalizeTokens(query)), " "))
+}
+
+// Plan возвращает отпечаток формы плана: типы узлов, таблицы, индексы,
+// виды соединений и порядок потомков. Стоимости, оценки и фактические
+// значения в него не входят, поэтому отпечаток меняется только тогда,
+// когда планировщик выбирает другой план.
+func Plan(plan []analyzer.PlanNode) string {
+	var b strings.Builder
+	for i := range plan {
+		writeShape(&b, &plan[i])
+	}
+	return hash(b.String())
+}
+
+// Apply заполняет нормализованный текст и отпечатки в результате анализа
+func Apply(result *analyzer.AnalysisResult, query string, plan []analyzer.PlanNode) {
+	result.NormalizedQuery = Normalize(query)
+	result.QueryFingerprint = Query(query)
+	result.PlanFingerprint = Plan(plan)
+}
+
+// Shape возвращает читаемую запись формы плана, например
+// "Hash Join(Seq Scan orders, Hash(Index Scan users))"
+func Shape(plan []analyzer.PlanNode) string {
+	parts := make([]string, 0, len(plan))
+	for i := range plan {
+		parts = append(parts, shape(&plan[i]))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func shape(node *analyzer.PlanNode) string {
+	s := node.NodeType
+	if node.RelationName != "" {
+		s += " " + node.RelationName
+	}
+	if len(node.Plans) > 0 {
+		children := make([]string, 0, len(node.Plans))
+		for i := range node.Plans {
+			children = append(children, shape(&node.Plans[i]))
+		}
+		s += "(" + strings.Join(children, ", ") + ")"
+	}
+	return s
+}
+
+// writeShape записывает форму поддерева в виде
+// "Тип[таблица,индекс,соединение](потомки)"
+func writeShape(b *strings.Builder, node *analyzer.PlanNode) {
+	fmt.Fprintf(b, "%s[%s,%s,%s](", node.NodeType, node.RelationName, node.IndexName, node.JoinType)
+	for i := range node.Plans {
+		writeShape(b, &node.Plans[i])
+	}
+	b.WriteString(")")
+}
+
+// hash возвращает 64-битный FNV-1a хеш строки в шестнадцатеричном виде.
+// Строка вместо числа выбрана потому, что JavaScript теряет точность
+// на целых больше 2^53.
+func hash(s string) string {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
@@ -0,0 +1,98 @@
+package fingerprint
+
+import (
+	"testing"
+
+	"sql-optimizer/internal/analyzer"
+)
+
+func TestNormalize(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected string
+	}{
+		{
+			"SELECT *\n  FROM orders -- заказы\n WHERE status = 'new' AND total > 10.5;",
+			"select * from orders where status = $1 and total > $2",
+		},
+		{
+			"select id from users where id IN (1, 2, 3) and name <> E'it\\'s'",
+			"select id from users where id in ($1 /*, ... */) and name <> $2",
+		},
+		{
+			"SELECT count(*) FROM t WHERE a = $1 AND b = -5 AND c - 1 > 0",
+			"select count(*) from t where a = $1 and b = $2 and c - $3 > $4",
+		},
+		{
+			`SELECT "Name"::text, $$a;b$$ /* вложенный /* комментарий */ */ FROM "Users" WHERE x = ANY(ARRAY[1,2])`,
+			`select "Name"::text, $1 from "Users" where x = any (array[$2 /*, ... */])`,
+		},
+		{
+			"SELECT * FROM t WHERE id IN (SELECT id FROM s) AND k IN (7)",
+			"select * from t where id in (select id from s) and k in ($1)",
+		},
+	}
+
+	for _, tc := range testCases {
+		if got := Normalize(tc.query); got != tc.expected {
+			t.Errorf("Normalize(%q):\nожидали %q\nполучили %q", tc.query, tc.expected, got)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	same := []string{
+		"SELECT * FROM orders WHERE id IN (1, 2, 3) AND status = 'new'",
+		"select *\nfrom   orders /* ... */ where id in (42, 43) and status = 'paid';",
+		"Select * From Orders Where Id In (7,8,9,10) And Status='x'",
+	}
+	for _, query := range same[1:] {
+		if Query(query) != Query(same[0]) {
+			t.Errorf("Отпечатки %q и %q должны совпадать", query, same[0])
+		}
+	}
+
+	if Query(`SELECT * FROM "Orders"`) == Query("SELECT * FROM orders") {
+		t.Error("Идентификаторы в кавычках чувствительны к регистру, отпечатки должны различаться")
+	}
+	if Query("SELECT * FROM orders WHERE id = 1") == Query("SELECT * FROM orders WHERE user_id = 1") {
+		t.Error("Запросы к разным столбцам должны иметь разные отпечатки")
+	}
+	if len(Query("SELECT 1")) != 16 {
+		t.Errorf("Ожидали 16 шестнадцатеричных символов, получили %q", Query("SELECT 1"))
+	}
+}
+
+func TestPlan(t *testing.T) {
+	actual := 12.5
+	plan := func(scan analyzer.PlanNode, cost float64) []analyzer.PlanNode {
+		return []analyzer.PlanNode{{
+			NodeType:  "Hash Join",
+			JoinType:  "Inner",
+			TotalCost: cost,
+			Plans: []analyzer.PlanNode{
+				scan,
+				{NodeType: "Hash", Plans: []analyzer.PlanNode{{NodeType: "Seq Scan", RelationName: "users"}}},
+			},
+		}}
+	}
+	seqScan := analyzer.PlanNode{NodeType: "Seq Scan", RelationName: "orders", PlanRows: 100}
+	indexScan := analyzer.PlanNode{NodeType: "Index Scan", RelationName: "orders", IndexName: "orders_user_id_idx"}
+
+	base := Plan(plan(seqScan, 10))
+	withTiming := seqScan
+	withTiming.PlanRows = 5000
+	withTiming.ActualTotalTime = &actual
+	if Plan(plan(withTiming, 99)) != base {
+		t.Error("Стоимость, оценки и время не должны влиять на отпечаток плана")
+	}
+	if Plan(plan(indexScan, 10)) == base {
+		t.Error("Смена способа доступа к таблице должна менять отпечаток плана")
+	}
+
+	swapped := plan(seqScan, 10)
+	swapped[0].Plans[0], swapped[0].Plans[1] = swapped[0].Plans[1], swapped[0].Plans[0]
+	if Plan(swapped) == base {
+		t.Error("Смена порядка соединения должна менять отпечаток плана")
+	}
+}
@@ -0,0 +1,264 @@
+package fingerprint
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"sql-optimizer/internal/sqlscan"
+)
+
+// tokenKind — вид лексемы нормализуемого запроса
+type tokenKind int
+
+const (
+	tokWord  tokenKind = iota // ключевое слово или идентификатор без кавычек
+	tokIdent                  // идентификатор в кавычках
+	tokConst                  // строковая или числовая константа
+	tokParam                  // параметр $n из исходного текста
+	tokList                   // свернутый список констант
+	tokOp                     // оператор или знак препинания
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// keywords — ключевые слова, после которых скобка отделяется пробелом
+// и минус относится к следующему числу, а не является вычитанием
+var keywords = map[string]bool{
+	"all": true, "and": true, "any": true, "array": true, "as": true,
+	"between": true, "by": true, "case": true, "distinct": true, "else": true,
+	"except": true, "exists": true, "filter": true, "from": true, "having": true,
+	"ilike": true, "in": true, "intersect": true, "interval": true, "into": true,
+	"is": true, "join": true, "lateral": true, "like": true, "limit": true,
+	"not": true, "offset": true, "on": true, "or": true, "over": true,
+	"returning": true, "select": true, "set": true, "then": true, "union": true,
+	"using": true, "values": true, "when": true, "where": true, "with": true,
+	"within": true,
+}
+
+// Normalize приводит запрос к каноническому виду в духе pg_stat_statements:
+// комментарии удаляются, пробелы схлопываются, ключевые слова и
+// идентификаторы без кавычек приводятся к нижнему регистру, константы
+// заменяются параметрами $n, а списки констант в IN (...) и ARRAY[...]
+// сворачиваются в один параметр.
+func Normalize(query string) string {
+	tokens := normalizeTokens(query)
+
+	// Номера новых параметров продолжают номера тех, что уже есть в запросе
+	next := 1
+	for _, t := range tokens {
+		if t.kind == tokParam {
+			if n, err := strconv.Atoi(t.text[1:]); err == nil && n >= next {
+				next = n + 1
+			}
+		}
+	}
+
+	var b strings.Builder
+	for i, t := range tokens {
+		text := t.text
+		switch t.kind {
+		case tokConst:
+			text = fmt.Sprintf("$%d", next)
+			next++
+		case tokList:
+			text = fmt.Sprintf("$%d /*, ... */", next)
+			next++
+		}
+		if i > 0 && spaceBetween(tokens[i-1], t) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+// tokenTexts возвращает тексты лексем для отпечатка: константы
+// обезличены, чтобы отпечаток не зависел от их нумерации
+func tokenTexts(tokens []token) []string {
+	texts := make([]string, len(tokens))
+	for i, t := range tokens {
+		switch t.kind {
+		case tokConst:
+			texts[i] = "?"
+		case tokList:
+			texts[i] = "?..."
+		default:
+			texts[i] = t.text
+		}
+	}
+	return texts
+}
+
+// normalizeTokens разбирает запрос на лексемы, присоединяет унарный минус
+// к числам, сворачивает списки констант и отбрасывает завершающие ";"
+func normalizeTokens(query string) []token {
+	var tokens []token
+	for _, t := range lex(query) {
+		if t.kind == tokConst && len(tokens) > 0 && tokens[len(tokens)-1] == (token{tokOp, "-"}) &&
+			!valueBefore(tokens[:len(tokens)-1]) {
+			tokens[len(tokens)-1] = t
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+
+	tokens = collapseLists(tokens)
+	for len(tokens) > 0 && tokens[len(tokens)-1] == (token{tokOp, ";"}) {
+		tokens = tokens[:len(tokens)-1]
+	}
+	return tokens
+}
+
+// valueBefore сообщает, заканчивается ли последовательность значением:
+// тогда следующий минус — вычитание, а не знак числа
+func valueBefore(tokens []token) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	last := tokens[len(tokens)-1]
+	switch last.kind {
+	case tokIdent, tokConst, tokParam, tokList:
+		return true
+	case tokWord:
+		return !keywords[last.text]
+	}
+	return last.text == ")" || last.text == "]"
+}
+
+// collapseLists заменяет списки из двух и более констант или параметров
+// после IN и ARRAY одной лексемой tokList
+func collapseLists(tokens []token) []token {
+	var out []token
+	for i := 0; i < len(tokens); i++ {
+		out = append(out, tokens[i])
+		if tokens[i].kind != tokWord || (tokens[i].text != "in" && tokens[i].text != "array") ||
+			i+1 >= len(tokens) {
+			continue
+		}
+		open := tokens[i+1].text
+		if open != "(" && open != "[" {
+			continue
+		}
+		closing := map[string]string{"(": ")", "[": "]"}[open]
+
+		// Элементы списка чередуются с запятыми: значение, ",", значение...
+		j, count := i+2, 0
+		for j < len(tokens) {
+			if kind := tokens[j].kind; kind != tokConst && kind != tokParam {
+				break
+			}
+			count++
+			j++
+			if j < len(tokens) && tokens[j].text == "," && tokens[j].kind == tokOp {
+				j++
+				continue
+			}
+			break
+		}
+		if count < 2 || j >= len(tokens) || tokens[j].text != closing || tokens[j-1].text == "," {
+			continue
+		}
+		out = append(out, tokens[i+1], token{tokList, ""}, tokens[j])
+		i = j
+	}
+	return out
+}
+
+// spaceBetween решает, нужен ли пробел между соседними лексемами
+func spaceBetween(prev, cur token) bool {
+	if prev.kind == tokOp {
+		switch prev.text {
+		case "(", "[", ".", "::":
+			return false
+		}
+	}
+	if cur.kind == tokOp {
+		switch cur.text {
+		case ",", ")", "]", ";", ".", "::", "[":
+			return false
+		case "(":
+			// Вызов функции пишется слитно, скобка после ключевого слова — нет
+			return !(prev.kind == tokIdent || (prev.kind == tokWord && !keywords[prev.text]))
+		}
+	}
+	return true
+}
+
+// lex разбивает запрос на лексемы, пропуская пробелы и комментарии
+func lex(src string) []token {
+	var tokens []token
+	for i := 0; i < len(src); {
+		kind, end := sqlscan.Next(src, i)
+		switch kind {
+		case sqlscan.String:
+			tokens = append(tokens, token{tokConst, ""})
+		case sqlscan.QuotedIdent:
+			tokens = append(tokens, token{tokIdent, src[i:end]})
+		case sqlscan.Word:
+			tokens = append(tokens, token{tokWord, strings.ToLower(src[i:end])})
+		case sqlscan.Other:
+			tokens, end = lexOther(src, i, tokens)
+		}
+		i = end
+	}
+	return tokens
+}
+
+// lexOther разбирает лексему, которая начинается не со слова, строки или
+// комментария: параметр, число или оператор
+func lexOther(src string, i int, tokens []token) ([]token, int) {
+	c := src[i]
+	switch {
+	case c == '$':
+		j := i + 1
+		for j < len(src) && sqlscan.IsDigit(src[j]) {
+			j++
+		}
+		if j > i+1 {
+			return append(tokens, token{tokParam, src[i:j]}), j
+		}
+		return append(tokens, token{tokOp, "$"}), j
+
+	case sqlscan.IsDigit(c) || (c == '.' && i+1 < len(src) && sqlscan.IsDigit(src[i+1])):
+		return append(tokens, token{tokConst, ""}), skipNumber(src, i)
+
+	case strings.HasPrefix(src[i:], "::"):
+		return append(tokens, token{tokOp, "::"}), i + 2
+
+	case strings.ContainsRune("(),;[].:", rune(c)):
+		return append(tokens, token{tokOp, string(c)}), i + 1
+	}
+
+	j := i
+	for j < len(src) && strings.ContainsRune("+-*/<>=~!@#%^&|`?", rune(src[j])) {
+		if j > i && (strings.HasPrefix(src[j:], "--") || strings.HasPrefix(src[j:], "/*")) {
+			break
+		}
+		j++
+	}
+	if j == i {
+		j++ // неизвестный символ становится отдельной лексемой
+	}
+	return append(tokens, token{tokOp, src[i:j]}), j
+}
+
+// skipNumber возвращает позицию после числа: целого, десятичного,
+// с экспонентой, шестнадцатеричного или с разделителями "_"
+func skipNumber(src string, i int) int {
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case sqlscan.IsDigit(c) || c == '.' || c == '_' || (c|0x20 >= 'a' && c|0x20 <= 'z'):
+			i++
+		case (c == '+' || c == '-') && (src[i-1]|0x20 == 'e') && i+1 < len(src) && sqlscan.IsDigit(src[i+1]):
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
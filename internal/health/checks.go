@@ -0,0 +1,394 @@
+package health
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+	"sql-optimizer/internal/quote"
+)
+
+// Пороги проверок
+const (
+	// minCacheBlocks — меньше обращений к блокам недостаточно для оценки кэша
+	minCacheBlocks = 10000
+	// smallTableRows — для маленьких таблиц проблемы VACUUM и ключей несущественны
+	smallTableRows = 1000
+	// minBloatBytes — раздувание меньше этого не стоит обслуживания
+	minBloatBytes = 10 << 20
+	// minDeadTuples — меньше мертвых строк autovacuum еще может не трогать
+	minDeadTuples = 10000
+	// xidLimit — возраст транзакций, при котором сервер останавливает запись
+	xidLimit = 1 << 31
+)
+
+// cacheHitRatio проверяет долю чтений из shared_buffers в текущей базе
+func cacheHitRatio(databases []postgres.DatabaseStats) []Finding {
+	var findings []Finding
+	for _, db := range databases {
+		total := db.BlocksHit + db.BlocksRead
+		if !db.Current || total < minCacheBlocks {
+			continue
+		}
+		ratio := float64(db.BlocksHit) / float64(total) * 100
+		severity := ""
+		switch {
+		case ratio < 90:
+			severity = SeverityHigh
+		case ratio < 99:
+			severity = SeverityMedium
+		default:
+			continue
+		}
+		findings = append(findings, Finding{
+			Object:   db.Name,
+			Severity: severity,
+			Message:  i18n.New(i18n.HealthCacheHitRatio, "database", db.Name, "ratio", fmt.Sprintf("%.1f", ratio)),
+		})
+	}
+	return findings
+}
+
+// tableBloat оценивает пустое место в таблицах: ожидаемый размер
+// считается по числу строк и средней ширине столбцов из pg_stats
+func tableBloat(tables []postgres.Table, blockSize int64) []Finding {
+	var findings []Finding
+	for _, table := range tables {
+		if table.Kind != "r" || table.Pages == 0 || table.Rows == 0 || len(table.ColumnWidths) == 0 {
+			continue
+		}
+		width := 0
+		for _, w := range table.ColumnWidths {
+			width += w
+		}
+		// Заголовок строки 23 байта с выравниванием и указатель на нее 4 байта
+		tuple := align(24+int64(width)) + 4
+		perPage := max((blockSize-24)/tuple, 1)
+		expected := (table.Rows + perPage - 1) / perPage
+		if severity, args := estimateBloat(table.Pages, expected, blockSize); severity != "" {
+			findings = append(findings, Finding{
+				Object:   table.Name,
+				Severity: severity,
+				Message:  i18n.New(i18n.HealthTableBloat, append([]string{"table", table.Name}, args...)...),
+				Fix:      fmt.Sprintf("VACUUM FULL %s; -- ACCESS EXCLUSIVE", table.QualifiedName()),
+			})
+		}
+	}
+	return findings
+}
+
+// indexBloat оценивает пустое место в B-tree индексах по столбцам таблицы;
+// индексы по выражениям и частичные пропускаются — их размер не оценить
+func indexBloat(indexes []postgres.Index, tables []postgres.Table, blockSize int64) []Finding {
+	byName := make(map[string]postgres.Table, len(tables))
+	for _, table := range tables {
+		byName[table.Schema+"."+table.Name] = table
+	}
+
+	var findings []Finding
+	for _, index := range indexes {
+		table, ok := byName[index.Schema+"."+index.Table]
+		if !ok || index.Method != "btree" || !index.Valid || index.Predicate != "" || table.Rows == 0 {
+			continue
+		}
+		width, known := 0, true
+		for _, column := range append(append([]string(nil), index.Columns...), index.Include...) {
+			w, ok := table.ColumnWidths[column]
+			if !ok {
+				known = false
+				break
+			}
+			width += w
+		}
+		if !known {
+			continue
+		}
+		// Заголовок элемента индекса 8 байт, указатель 4 байта; страницы
+		// листьев заполняются на 90%, у каждой есть заголовок и область B-tree
+		tuple := align(8+int64(width)) + 4
+		perPage := max((blockSize-24-16)*9/10/tuple, 1)
+		expected := (table.Rows+perPage-1)/perPage + 1
+		if severity, args := estimateBloat(index.SizeBytes/blockSize, expected, blockSize); severity != "" {
+			findings = append(findings, Finding{
+				Object:   index.Name,
+				Severity: severity,
+				Message:  i18n.New(i18n.HealthIndexBloat, append([]string{"index", index.Name}, args...)...),
+				Fix: fmt.Sprintf("REINDEX INDEX CONCURRENTLY %s.%s;",
+					quote.Ident(index.Schema), quote.Ident(index.Name)),
+			})
+		}
+	}
+	return findings
+}
+
+// estimateBloat сравнивает фактический и ожидаемый размер в блоках и
+// возвращает серьезность раздувания (пустую, если оно несущественно) и
+// параметры сообщения wasted, size и percent
+func estimateBloat(pages, expected, blockSize int64) (string, []string) {
+	wasted := (pages - expected) * blockSize
+	if pages == 0 || wasted < minBloatBytes {
+		return "", nil
+	}
+	percent := float64(pages-expected) / float64(pages) * 100
+	severity := ""
+	switch {
+	case percent >= 60:
+		severity = SeverityHigh
+	case percent >= 30:
+		severity = SeverityMedium
+	default:
+		return "", nil
+	}
+	return severity, []string{"wasted", postgres.SizePretty(wasted), "size", postgres.SizePretty(pages * blockSize),
+		"percent", fmt.Sprintf("%.0f", percent)}
+}
+
+// neverAnalyzed находит таблицы с данными, которые ни разу не анализировались
+func neverAnalyzed(tables []postgres.Table) []Finding {
+	var findings []Finding
+	for _, table := range tables {
+		if table.Kind == "p" || table.LastAnalyze != nil || table.LastAutoanalyze != nil ||
+			table.LiveTuples == 0 && table.Pages == 0 {
+			continue
+		}
+		findings = append(findings, Finding{
+			Object:   table.Name,
+			Severity: sizeSeverity(table.LiveTuples),
+			Message:  i18n.New(i18n.HealthNeverAnalyzed, "table", table.Name),
+			Fix:      fmt.Sprintf("ANALYZE %s;", table.QualifiedName()),
+		})
+	}
+	return findings
+}
+
+// neverVacuumed находит заметные таблицы, которые ни разу не очищались
+func neverVacuumed(tables []postgres.Table) []Finding {
+	var findings []Finding
+	for _, table := range tables {
+		if table.Kind != "r" || table.LastVacuum != nil || table.LastAutovacuum != nil ||
+			table.LiveTuples+table.DeadTuples < smallTableRows {
+			continue
+		}
+		severity := SeverityLow
+		if table.DeadTuples > 0 {
+			severity = SeverityMedium
+		}
+		findings = append(findings, Finding{
+			Object:   table.Name,
+			Severity: severity,
+			Message:  i18n.New(i18n.HealthNeverVacuumed, "table", table.Name),
+			Fix:      fmt.Sprintf("VACUUM (ANALYZE) %s;", table.QualifiedName()),
+		})
+	}
+	return findings
+}
+
+// deadTuples находит таблицы, где мертвые строки составляют заметную долю
+func deadTuples(tables []postgres.Table) []Finding {
+	var findings []Finding
+	for _, table := range tables {
+		if table.DeadTuples < minDeadTuples {
+			continue
+		}
+		percent := float64(table.DeadTuples) / float64(table.LiveTuples+table.DeadTuples) * 100
+		severity := ""
+		switch {
+		case percent >= 50:
+			severity = SeverityHigh
+		case percent >= 20:
+			severity = SeverityMedium
+		default:
+			continue
+		}
+		findings = append(findings, Finding{
+			Object:   table.Name,
+			Severity: severity,
+			Message: i18n.New(i18n.HealthDeadTuples, "table", table.Name,
+				"dead", strconv.FormatInt(table.DeadTuples, 10), "percent", fmt.Sprintf("%.0f", percent)),
+			Fix: fmt.Sprintf("VACUUM (ANALYZE) %s;", table.QualifiedName()),
+		})
+	}
+	return findings
+}
+
+// noPrimaryKey находит таблицы без первичного ключа
+func noPrimaryKey(tables []postgres.Table) []Finding {
+	var findings []Finding
+	for _, table := range tables {
+		if table.Kind != "r" || table.HasPrimaryKey {
+			continue
+		}
+		findings = append(findings, Finding{
+			Object:   table.Name,
+			Severity: sizeSeverity(max(table.Rows, table.LiveTuples)),
+			Message:  i18n.New(i18n.HealthNoPrimaryKey, "table", table.Name),
+		})
+	}
+	return findings
+}
+
+// typeLimits — наибольшие значения целочисленных типов столбцов
+var typeLimits = map[string]int64{
+	"smallint": math.MaxInt16,
+	"integer":  math.MaxInt32,
+	"bigint":   math.MaxInt64,
+}
+
+// sequenceOverflow находит возрастающие последовательности, близкие к
+// пределу своего типа или типа столбца, которому они принадлежат
+func sequenceOverflow(sequences []postgres.Sequence) []Finding {
+	var findings []Finding
+	for _, seq := range sequences {
+		if seq.LastValue == nil || seq.Increment <= 0 || seq.MaxValue <= 0 {
+			continue
+		}
+		limit, limitType := seq.MaxValue, seq.DataType
+		if columnLimit, ok := typeLimits[seq.ColumnType]; ok && columnLimit < limit {
+			limit, limitType = columnLimit, seq.ColumnType
+		}
+		percent := float64(*seq.LastValue) / float64(limit) * 100
+		severity := ""
+		switch {
+		case percent >= 90:
+			severity = SeverityCritical
+		case percent >= 75:
+			severity = SeverityHigh
+		case percent >= 50:
+			severity = SeverityMedium
+		default:
+			continue
+		}
+
+		var fixes []string
+		if seq.Column != "" && seq.ColumnType != "bigint" {
+			fixes = append(fixes, fmt.Sprintf("ALTER TABLE %s.%s ALTER COLUMN %s TYPE bigint;",
+				quote.Ident(seq.Schema), quote.Ident(seq.Table), quote.Ident(seq.Column)))
+		}
+		if seq.DataType != "bigint" {
+			fixes = append(fixes, fmt.Sprintf("ALTER SEQUENCE %s.%s AS bigint;",
+				quote.Ident(seq.Schema), quote.Ident(seq.Name)))
+		}
+		findings = append(findings, Finding{
+			Object:   seq.Schema + "." + seq.Name,
+			Severity: severity,
+			Message: i18n.New(i18n.HealthSequenceOverflow, "sequence", seq.Name,
+				"percent", fmt.Sprintf("%.0f", percent), "limit", strconv.FormatInt(limit, 10), "type", limitType),
+			Fix: strings.Join(fixes, " "),
+		})
+	}
+	return findings
+}
+
+// wraparound проверяет возраст datfrozenxid баз кластера: если он намного
+// больше autovacuum_freeze_max_age, автоматическая заморозка не успевает
+func wraparound(databases []postgres.DatabaseStats, settings map[string]postgres.Setting) []Finding {
+	freezeMaxAge := settingInt(settings, "autovacuum_freeze_max_age", 200000000)
+
+	var findings []Finding
+	for _, db := range databases {
+		percent := float64(db.FrozenXIDAge) / xidLimit * 100
+		severity := ""
+		switch {
+		case percent >= 75:
+			severity = SeverityCritical
+		case percent >= 50:
+			severity = SeverityHigh
+		case db.FrozenXIDAge > 2*freezeMaxAge:
+			severity = SeverityMedium
+		default:
+			continue
+		}
+		findings = append(findings, Finding{
+			Object:   db.Name,
+			Severity: severity,
+			Message: i18n.New(i18n.HealthWraparound, "database", db.Name,
+				"age", strconv.FormatInt(db.FrozenXIDAge, 10), "percent", fmt.Sprintf("%.0f", percent)),
+			Fix: "VACUUM (FREEZE, VERBOSE);",
+		})
+	}
+	return findings
+}
+
+// longTransactions описывает долгие транзакции; простаивающие в открытой
+// транзакции и идущие дольше часа опаснее
+func longTransactions(transactions []postgres.Transaction) []Finding {
+	var findings []Finding
+	for _, tx := range transactions {
+		severity := SeverityMedium
+		if strings.HasPrefix(tx.State, "idle in transaction") || tx.Duration >= time.Hour.Seconds() {
+			severity = SeverityHigh
+		}
+		fix := fmt.Sprintf("SELECT pg_cancel_backend(%d);", tx.PID)
+		if strings.HasPrefix(tx.State, "idle") {
+			fix = fmt.Sprintf("SELECT pg_terminate_backend(%d);", tx.PID)
+		}
+		duration := (time.Duration(tx.Duration) * time.Second).String()
+		findings = append(findings, Finding{
+			Object:   strconv.Itoa(tx.PID),
+			Severity: severity,
+			Message: i18n.New(i18n.HealthLongTransaction, "pid", strconv.Itoa(tx.PID),
+				"state", tx.State, "duration", duration),
+			Fix: fix,
+		})
+	}
+	return findings
+}
+
+// harmfulSetting — значение параметра, опасное для данных или обслуживания
+type harmfulSetting struct {
+	name, value, safe string
+	severity          string
+	message           string
+}
+
+var harmful = []harmfulSetting{
+	{"fsync", "off", "on", SeverityCritical, i18n.HealthFsyncOff},
+	{"full_page_writes", "off", "on", SeverityCritical, i18n.HealthFullPageWritesOff},
+	{"zero_damaged_pages", "on", "off", SeverityHigh, i18n.HealthZeroDamagedPages},
+	{"autovacuum", "off", "on", SeverityHigh, i18n.HealthAutovacuumOff},
+	{"track_counts", "off", "on", SeverityHigh, i18n.HealthTrackCountsOff},
+	{"synchronous_commit", "off", "on", SeverityLow, i18n.HealthSyncCommitOff},
+}
+
+// harmfulSettings находит измененные параметры с опасными значениями
+func harmfulSettings(settings map[string]postgres.Setting) []Finding {
+	var findings []Finding
+	for _, h := range harmful {
+		setting, ok := settings[h.name]
+		if !ok || setting.Source == "default" || setting.Value != h.value {
+			continue
+		}
+		findings = append(findings, Finding{
+			Object:   h.name,
+			Severity: h.severity,
+			Message:  i18n.New(h.message),
+			Fix:      fmt.Sprintf("ALTER SYSTEM SET %s = %s; SELECT pg_reload_conf();", h.name, h.safe),
+		})
+	}
+	return findings
+}
+
+// sizeSeverity — низкая серьезность для маленьких таблиц, средняя для остальных
+func sizeSeverity(rows int64) string {
+	if rows < smallTableRows {
+		return SeverityLow
+	}
+	return SeverityMedium
+}
+
+// settingInt возвращает целое значение параметра или def
+func settingInt(settings map[string]postgres.Setting, name string, def int64) int64 {
+	if value, err := strconv.ParseInt(settings[name].Value, 10, 64); err == nil {
+		return value
+	}
+	return def
+}
+
+// align выравнивает размер на 8 байт, как MAXALIGN в PostgreSQL
+func align(n int64) int64 {
+	return (n + 7) &^ 7
+}
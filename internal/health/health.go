@@ -0,0 +1,187 @@
+// Package health проверяет здоровье базы целиком: кэш, раздувание таблиц
+// и индексов, VACUUM и ANALYZE, исчерпание последовательностей и счетчика
+// транзакций, долгие транзакции и опасные параметры сервера. Результат —
+// отчет с оценкой от 0 до 100.
+package health
+
+import (
+	"context"
+	"time"
+
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+)
+
+// Идентификаторы проверок
+const (
+	CheckCacheHitRatio    = "cache_hit_ratio"
+	CheckTableBloat       = "table_bloat"
+	CheckIndexBloat       = "index_bloat"
+	CheckNeverAnalyzed    = "never_analyzed"
+	CheckNeverVacuumed    = "never_vacuumed"
+	CheckDeadTuples       = "dead_tuples"
+	CheckNoPrimaryKey     = "no_primary_key"
+	CheckSequenceOverflow = "sequence_overflow"
+	CheckWraparound       = "xid_wraparound"
+	CheckLongTransactions = "long_transactions"
+	CheckSettings         = "settings"
+)
+
+// Серьезность находок; StatusOK — статус проверки без находок
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+	StatusOK         = "ok"
+)
+
+// penalties — сколько баллов снимает проверка с самой серьезной находкой
+var penalties = map[string]int{
+	SeverityCritical: 30,
+	SeverityHigh:     15,
+	SeverityMedium:   7,
+	SeverityLow:      3,
+}
+
+// DefaultLongTransaction — с какой длительности транзакция считается долгой
+const DefaultLongTransaction = 5 * time.Minute
+
+// Source — источник статистики базы; его реализует *postgres.Client
+type Source interface {
+	Tables(ctx context.Context) ([]postgres.Table, error)
+	Indexes(ctx context.Context) ([]postgres.Index, error)
+	DatabaseStats(ctx context.Context) ([]postgres.DatabaseStats, error)
+	Sequences(ctx context.Context) ([]postgres.Sequence, error)
+	LongTransactions(ctx context.Context, minDuration time.Duration) ([]postgres.Transaction, error)
+	Settings(ctx context.Context) (map[string]postgres.Setting, error)
+}
+
+// Options — параметры проверки
+type Options struct {
+	// LongTransaction — порог долгой транзакции, по умолчанию DefaultLongTransaction
+	LongTransaction time.Duration
+}
+
+// Snapshot — все, что проверки читают из базы
+type Snapshot struct {
+	Tables       []postgres.Table
+	Indexes      []postgres.Index
+	Databases    []postgres.DatabaseStats
+	Sequences    []postgres.Sequence
+	Transactions []postgres.Transaction
+	Settings     map[string]postgres.Setting
+}
+
+// Finding — найденная проблема
+type Finding struct {
+	Object      string       `json:"object"` // таблица, индекс, база, процесс или параметр
+	Severity    string       `json:"severity"`
+	Message     i18n.Message `json:"message"`
+	Description string       `json:"description"`
+	Fix         string       `json:"fix,omitempty"` // команда, исправляющая проблему
+}
+
+// Check — результат одной проверки
+type Check struct {
+	ID       string    `json:"id"`
+	Status   string    `json:"status"` // StatusOK или серьезность самой серьезной находки
+	Penalty  int       `json:"penalty"`
+	Findings []Finding `json:"findings"`
+}
+
+// Report — отчет о здоровье базы
+type Report struct {
+	// Score — оценка от 0 до 100: из 100 вычитается штраф каждой проверки
+	// по самой серьезной ее находке
+	Score  int     `json:"score"`
+	Checks []Check `json:"checks"`
+}
+
+// Run читает статистику из src и проверяет базу
+func Run(ctx context.Context, src Source, opts Options) (*Report, error) {
+	snapshot, err := Collect(ctx, src, opts)
+	if err != nil {
+		return nil, err
+	}
+	return Evaluate(snapshot), nil
+}
+
+// Collect читает из src все, что нужно проверкам
+func Collect(ctx context.Context, src Source, opts Options) (*Snapshot, error) {
+	if opts.LongTransaction <= 0 {
+		opts.LongTransaction = DefaultLongTransaction
+	}
+
+	var snapshot Snapshot
+	var err error
+	if snapshot.Tables, err = src.Tables(ctx); err != nil {
+		return nil, err
+	}
+	if snapshot.Indexes, err = src.Indexes(ctx); err != nil {
+		return nil, err
+	}
+	if snapshot.Databases, err = src.DatabaseStats(ctx); err != nil {
+		return nil, err
+	}
+	if snapshot.Sequences, err = src.Sequences(ctx); err != nil {
+		return nil, err
+	}
+	if snapshot.Transactions, err = src.LongTransactions(ctx, opts.LongTransaction); err != nil {
+		return nil, err
+	}
+	if snapshot.Settings, err = src.Settings(ctx); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// Evaluate выполняет проверки над прочитанной статистикой
+func Evaluate(s *Snapshot) *Report {
+	blockSize := settingInt(s.Settings, "block_size", 8192)
+	checks := []struct {
+		id       string
+		findings []Finding
+	}{
+		{CheckCacheHitRatio, cacheHitRatio(s.Databases)},
+		{CheckTableBloat, tableBloat(s.Tables, blockSize)},
+		{CheckIndexBloat, indexBloat(s.Indexes, s.Tables, blockSize)},
+		{CheckNeverAnalyzed, neverAnalyzed(s.Tables)},
+		{CheckNeverVacuumed, neverVacuumed(s.Tables)},
+		{CheckDeadTuples, deadTuples(s.Tables)},
+		{CheckNoPrimaryKey, noPrimaryKey(s.Tables)},
+		{CheckSequenceOverflow, sequenceOverflow(s.Sequences)},
+		{CheckWraparound, wraparound(s.Databases, s.Settings)},
+		{CheckLongTransactions, longTransactions(s.Transactions)},
+		{CheckSettings, harmfulSettings(s.Settings)},
+	}
+
+	report := &Report{Score: 100, Checks: make([]Check, 0, len(checks))}
+	for _, c := range checks {
+		check := Check{ID: c.id, Status: StatusOK, Findings: c.findings}
+		if check.Findings == nil {
+			check.Findings = []Finding{}
+		}
+		for _, f := range check.Findings {
+			if penalties[f.Severity] > check.Penalty {
+				check.Penalty = penalties[f.Severity]
+				check.Status = f.Severity
+			}
+		}
+		report.Score -= check.Penalty
+		report.Checks = append(report.Checks, check)
+	}
+	report.Score = max(report.Score, 0)
+	report.Localize(i18n.DefaultLocale)
+	return report
+}
+
+// Localize формирует тексты находок на языке locale
+func (r *Report) Localize(locale string) {
+	for i := range r.Checks {
+		for j := range r.Checks[i].Findings {
+			finding := &r.Checks[i].Findings[j]
+			finding.Description = finding.Message.Format(locale)
+		}
+	}
+}
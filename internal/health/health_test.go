@@ -0,0 +1,110 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"sql-optimizer/internal/postgres"
+)
+
+func TestEvaluate(t *testing.T) {
+	last := int64(1900000000)
+	analyzed := time.Now()
+	snapshot := &Snapshot{
+		Tables: []postgres.Table{
+			// 100 тысяч строк по ~100 байт занимают ~1600 блоков, а не 10000
+			{Schema: "public", Name: "orders", Kind: "r", Rows: 100000, LiveTuples: 100000, DeadTuples: 150000, Pages: 10000,
+				ColumnWidths: map[string]int{"id": 8, "user_id": 8, "note": 84}, HasPrimaryKey: true,
+				LastAutovacuum: &analyzed, LastAutoanalyze: &analyzed},
+			{Schema: "public", Name: "events", Kind: "r", Rows: 5000, LiveTuples: 5000, Pages: 50},
+			{Schema: "public", Name: "parts", Kind: "p"},
+		},
+		Indexes: []postgres.Index{
+			{Schema: "public", Name: "orders_user_id_idx", Table: "orders", Method: "btree", Valid: true,
+				Columns: []string{"user_id"}, SizeBytes: 40 << 20},
+			{Schema: "public", Name: "orders_pkey", Table: "orders", Method: "btree", Valid: true,
+				Columns: []string{"id"}, SizeBytes: 3 << 20},
+		},
+		Databases: []postgres.DatabaseStats{
+			{Name: "shop", Current: true, BlocksHit: 95000, BlocksRead: 5000, FrozenXIDAge: 150000000},
+			{Name: "legacy", BlocksHit: 1, BlocksRead: 100000, FrozenXIDAge: 1200000000},
+		},
+		Sequences: []postgres.Sequence{
+			{Schema: "public", Name: "orders_id_seq", DataType: "bigint", LastValue: &last, MaxValue: 1 << 62,
+				Increment: 1, Table: "orders", Column: "id", ColumnType: "integer"},
+			{Schema: "public", Name: "unused_seq", DataType: "bigint", MaxValue: 1 << 62, Increment: 1},
+		},
+		Transactions: []postgres.Transaction{{PID: 42, State: "idle in transaction", Duration: 600}},
+		Settings: map[string]postgres.Setting{
+			"fsync":              {Name: "fsync", Value: "on", Source: "default"},
+			"autovacuum":         {Name: "autovacuum", Value: "off", Source: "configuration file"},
+			"synchronous_commit": {Name: "synchronous_commit", Value: "off", Source: "default"},
+		},
+	}
+
+	report := Evaluate(snapshot)
+	checks := make(map[string]Check)
+	for _, check := range report.Checks {
+		checks[check.ID] = check
+	}
+
+	want := map[string]string{
+		CheckCacheHitRatio:    SeverityMedium,
+		CheckTableBloat:       SeverityHigh,
+		CheckIndexBloat:       SeverityHigh,
+		CheckNeverAnalyzed:    SeverityMedium,
+		CheckNeverVacuumed:    SeverityLow,
+		CheckDeadTuples:       SeverityHigh,
+		CheckNoPrimaryKey:     SeverityMedium,
+		CheckSequenceOverflow: SeverityHigh,
+		CheckWraparound:       SeverityHigh,
+		CheckLongTransactions: SeverityHigh,
+		CheckSettings:         SeverityHigh,
+	}
+	for id, status := range want {
+		if checks[id].Status != status {
+			t.Errorf("Проверка %s: ожидали %s, получили %s (%+v)", id, status, checks[id].Status, checks[id].Findings)
+		}
+	}
+
+	if got := checks[CheckIndexBloat].Findings; len(got) != 1 || got[0].Object != "orders_user_id_idx" ||
+		got[0].Fix != "REINDEX INDEX CONCURRENTLY public.orders_user_id_idx;" {
+		t.Errorf("Раздутым должен быть только orders_user_id_idx: %+v", got)
+	}
+	if got := checks[CheckSequenceOverflow].Findings; len(got) != 1 ||
+		got[0].Fix != "ALTER TABLE public.orders ALTER COLUMN id TYPE bigint;" ||
+		got[0].Description != "Последовательность orders_id_seq израсходована на 88%: предел 2147483647 задает тип integer" {
+		t.Errorf("Неверная находка о последовательности: %+v", got)
+	}
+	if got := checks[CheckLongTransactions].Findings[0]; got.Fix != "SELECT pg_terminate_backend(42);" {
+		t.Errorf("Неверное исправление долгой транзакции: %+v", got)
+	}
+	if got := checks[CheckSettings].Findings; len(got) != 1 || got[0].Object != "autovacuum" {
+		t.Errorf("Параметры по умолчанию не должны попадать в отчет: %+v", got)
+	}
+
+	// 100 − (7 + 15 + 15 + 7 + 3 + 15 + 7 + 15 + 15 + 15 + 15) = 0
+	if report.Score != 0 {
+		t.Errorf("Ожидали оценку 0, получили %d", report.Score)
+	}
+
+	report.Localize("en")
+	if got := checks[CheckNeverAnalyzed].Findings; len(got) != 1 || got[0].Object != "events" || got[0].Fix != "ANALYZE public.events;" {
+		t.Errorf("Ни разу не анализировалась только events: %+v", got)
+	}
+	if got := report.Checks[3].Findings[0].Description; got != "Table events has never been analyzed: the planner knows nothing about its data distribution" {
+		t.Errorf("Неверное описание на английском: %q", got)
+	}
+}
+
+func TestEvaluateHealthy(t *testing.T) {
+	report := Evaluate(&Snapshot{})
+	if report.Score != 100 || len(report.Checks) != 11 {
+		t.Errorf("Пустая база должна получить 100 баллов: %+v", report)
+	}
+	for _, check := range report.Checks {
+		if check.Status != StatusOK || check.Findings == nil {
+			t.Errorf("Проверка %s без находок должна быть ok: %+v", check.ID, check)
+		}
+	}
+}
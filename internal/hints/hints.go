@@ -0,0 +1,255 @@
+// Package hints составляет подсказки pg_hint_plan, которые заставляют
+// планировщик выбрать заданный план: порядок соединений (Leading),
+// методы соединений, способы сканирования, поправки оценки строк (Rows)
+// и параметры (Set).
+package hints
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/fingerprint"
+	"sql-optimizer/internal/postgres"
+	"sql-optimizer/internal/quote"
+)
+
+// Source строит план запроса с подсказками; его реализует *postgres.Client
+type Source interface {
+	ExplainHinted(ctx context.Context, query, hints string) (string, error)
+}
+
+// Result — подсказки для целевого плана
+type Result struct {
+	Hints []string `json:"hints"`
+	Block string   `json:"block"` // комментарий /*+ ... */
+	Query string   `json:"query"` // запрос с подсказками
+	// Verified — совпал ли план с подсказками с целевым; nil, если
+	// проверка не выполнялась
+	Verified *bool `json:"verified,omitempty"`
+	// Shape — форма плана, полученного с подсказками, если он не совпал
+	Shape string `json:"shape,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// joinMethods — подсказки методов соединения по типу узла
+var joinMethods = map[string]string{
+	"Nested Loop": "NestLoop",
+	"Hash Join":   "HashJoin",
+	"Merge Join":  "MergeJoin",
+}
+
+// scanMethods — подсказки способов сканирования по типу узла
+var scanMethods = map[string]string{
+	"Seq Scan":         "SeqScan",
+	"Index Scan":       "IndexScan",
+	"Index Only Scan":  "IndexOnlyScan",
+	"Bitmap Heap Scan": "BitmapScan",
+	"Tid Scan":         "TidScan",
+}
+
+// Suggest составляет подсказки для плана target запроса query. Если src
+// не nil, план строится заново с подсказками и сравнивается с целевым.
+// Параметры settings, кроме enable_*, передаются подсказками Set: способы
+// сканирования и соединения задаются точнее.
+func Suggest(ctx context.Context, src Source, query string, target []analyzer.PlanNode, settings []postgres.PlannerToggle) *Result {
+	list := Generate(target, settings)
+	block := Block(list)
+	result := &Result{Hints: list, Block: block, Query: block + "\n" + query}
+	if src == nil {
+		return result
+	}
+
+	planJSON, err := src.ExplainHinted(ctx, query, block)
+	if err != nil {
+		result.Error = err.Error()
+		if !errors.Is(err, postgres.ErrNoHintPlan) {
+			result.Error = "не удалось проверить подсказки: " + result.Error
+		}
+		return result
+	}
+	hinted, err := analyzer.ParseExplainJSON(planJSON)
+	if err != nil {
+		result.Error = "не удалось разобрать план с подсказками: " + err.Error()
+		return result
+	}
+	verified := fingerprint.Plan(hinted) == fingerprint.Plan(target)
+	result.Verified = &verified
+	if !verified {
+		result.Shape = fingerprint.Shape(hinted)
+	}
+	return result
+}
+
+// Block оформляет подсказки комментарием, который читает pg_hint_plan
+func Block(hints []string) string {
+	if len(hints) == 0 {
+		return "/*+ */"
+	}
+	return "/*+\n    " + strings.Join(hints, "\n    ") + "\n*/"
+}
+
+// Generate возвращает подсказки, описывающие план: сначала Leading для
+// каждого дерева соединений, затем методы соединений, способы
+// сканирования, поправки Rows для соединений, оценка строк которых
+// ошиблась больше чем в analyzer.MisestimateThreshold раз, и Set
+func Generate(plan []analyzer.PlanNode, settings []postgres.PlannerToggle) []string {
+	var g generator
+	for i := range plan {
+		g.walk(&plan[i], false)
+	}
+	for _, s := range settings {
+		if !strings.HasPrefix(s.Name, "enable_") {
+			g.set = append(g.set, fmt.Sprintf("Set(%s %s)", s.Name, s.Value))
+		}
+	}
+
+	var hints []string
+	for _, group := range [][]string{g.leading, g.joins, g.scans, g.rows, g.set} {
+		hints = append(hints, group...)
+	}
+	if hints == nil {
+		hints = []string{}
+	}
+	return hints
+}
+
+type generator struct {
+	leading, joins, scans, rows, set []string
+}
+
+// walk собирает подсказки поддерева; inJoin — узел входит в дерево
+// соединений, для которого Leading уже составлен
+func (g *generator) walk(node *analyzer.PlanNode, inJoin bool) {
+	if method, ok := joinMethods[node.NodeType]; ok {
+		relations := strings.Join(leaves(node), " ")
+		g.joins = append(g.joins, fmt.Sprintf("%s(%s)", method, relations))
+		if rows, ok := correctedRows(node); ok {
+			g.rows = append(g.rows, fmt.Sprintf("Rows(%s #%d)", relations, rows))
+		}
+		if !inJoin {
+			if tree, ok := joinTree(node); ok {
+				g.leading = append(g.leading, "Leading("+tree+")")
+				inJoin = true
+			}
+		}
+	}
+	if method, ok := scanMethods[node.NodeType]; ok && node.RelationName != "" {
+		args := append([]string{alias(node)}, scanIndexes(node)...)
+		g.scans = append(g.scans, fmt.Sprintf("%s(%s)", method, strings.Join(args, " ")))
+	}
+
+	for i := range node.Plans {
+		child := &node.Plans[i]
+		// подзапросы планируются отдельно, и порядок соединений в них
+		// задается своим Leading
+		childInJoin := inJoin && !subPlan(child) && !isLeaf(node)
+		g.walk(child, childInJoin)
+	}
+}
+
+// joinTree возвращает порядок соединений поддерева в виде ((a b) c);
+// false, если его нельзя выразить подсказкой Leading
+func joinTree(node *analyzer.PlanNode) (string, bool) {
+	if isLeaf(node) {
+		return alias(node), true
+	}
+	children := planChildren(node)
+	if _, ok := joinMethods[node.NodeType]; ok {
+		if len(children) != 2 {
+			return "", false
+		}
+		outer, ok := joinTree(children[0])
+		if !ok {
+			return "", false
+		}
+		inner, ok := joinTree(children[1])
+		if !ok {
+			return "", false
+		}
+		return "(" + outer + " " + inner + ")", true
+	}
+	// Hash, Sort, Materialize, Memoize, Gather... оборачивают один вход
+	if len(children) != 1 {
+		return "", false
+	}
+	return joinTree(children[0])
+}
+
+// leaves возвращает отношения, которые соединяет поддерево
+func leaves(node *analyzer.PlanNode) []string {
+	if isLeaf(node) {
+		return []string{alias(node)}
+	}
+	var result []string
+	for _, child := range planChildren(node) {
+		result = append(result, leaves(child)...)
+	}
+	return result
+}
+
+// isLeaf сообщает, что узел читает одно отношение: таблицу, подзапрос,
+// функцию или CTE
+func isLeaf(node *analyzer.PlanNode) bool {
+	return alias(node) != "" && strings.HasSuffix(node.NodeType, "Scan")
+}
+
+// planChildren возвращает входы узла без подпланов SubPlan и InitPlan
+func planChildren(node *analyzer.PlanNode) []*analyzer.PlanNode {
+	var children []*analyzer.PlanNode
+	for i := range node.Plans {
+		if !subPlan(&node.Plans[i]) {
+			children = append(children, &node.Plans[i])
+		}
+	}
+	return children
+}
+
+func subPlan(node *analyzer.PlanNode) bool {
+	return node.ParentRelationship == "SubPlan" || node.ParentRelationship == "InitPlan"
+}
+
+// alias возвращает имя отношения в запросе: pg_hint_plan ссылается
+// на псевдонимы, если они заданы
+func alias(node *analyzer.PlanNode) string {
+	if node.Alias != "" {
+		return quote.Ident(node.Alias)
+	}
+	if node.RelationName != "" {
+		return quote.Ident(node.RelationName)
+	}
+	return ""
+}
+
+// scanIndexes возвращает индексы узла сканирования; у Bitmap Heap Scan
+// они указаны в дочерних Bitmap Index Scan
+func scanIndexes(node *analyzer.PlanNode) []string {
+	if node.IndexName != "" {
+		return []string{quote.Ident(node.IndexName)}
+	}
+	var indexes []string
+	for i := range node.Plans {
+		child := &node.Plans[i]
+		if child.RelationName == "" && !subPlan(child) {
+			indexes = append(indexes, scanIndexes(child)...)
+		}
+	}
+	return indexes
+}
+
+// correctedRows возвращает фактическое число строк соединения, если
+// оценка ошиблась сильнее порога. Соединения, выполненные несколько раз
+// внутри Nested Loop, пропускаются: их оценка параметризована.
+func correctedRows(node *analyzer.PlanNode) (int, bool) {
+	if node.ActualRows == nil || node.ActualLoops != nil && *node.ActualLoops > 1 {
+		return 0, false
+	}
+	actual, planned := float64(*node.ActualRows), float64(max(node.PlanRows, 1))
+	ratio := max(actual, 1) / planned
+	if ratio < analyzer.MisestimateThreshold && 1/ratio < analyzer.MisestimateThreshold {
+		return 0, false
+	}
+	return *node.ActualRows, true
+}
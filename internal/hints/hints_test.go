@@ -0,0 +1,98 @@
+package hints
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/postgres"
+)
+
+func intPtr(i int) *int { return &i }
+
+// plan — соединение трех таблиц: (o ⋈ u) по хешу, затем вложенный цикл
+// с индексным сканированием items
+func plan() []analyzer.PlanNode {
+	return []analyzer.PlanNode{{
+		NodeType: "Nested Loop", PlanRows: 10, ActualRows: intPtr(5000), ActualLoops: intPtr(1),
+		Plans: []analyzer.PlanNode{
+			{NodeType: "Hash Join", ParentRelationship: "Outer", PlanRows: 100, ActualRows: intPtr(120), ActualLoops: intPtr(1),
+				Plans: []analyzer.PlanNode{
+					{NodeType: "Seq Scan", RelationName: "orders", Alias: "o", ParentRelationship: "Outer"},
+					{NodeType: "Hash", ParentRelationship: "Inner", Plans: []analyzer.PlanNode{
+						{NodeType: "Bitmap Heap Scan", RelationName: "users", Alias: "u", Plans: []analyzer.PlanNode{
+							{NodeType: "Bitmap Index Scan", IndexName: "users_city_idx"},
+						}},
+					}},
+				}},
+			{NodeType: "Index Scan", RelationName: "order_items", Alias: "order_items", IndexName: "order_items_order_id_idx",
+				ParentRelationship: "Inner"},
+			{NodeType: "Seq Scan", RelationName: "settings", Alias: "settings", ParentRelationship: "InitPlan"},
+		},
+	}}
+}
+
+func TestGenerate(t *testing.T) {
+	got := Generate(plan(), []postgres.PlannerToggle{
+		{Name: "enable_seqscan", Value: "off"},
+		{Name: "work_mem", Value: "256MB"},
+	})
+	want := []string{
+		"Leading(((o u) order_items))",
+		"NestLoop(o u order_items)",
+		"HashJoin(o u)",
+		"SeqScan(o)",
+		"BitmapScan(u users_city_idx)",
+		"IndexScan(order_items order_items_order_id_idx)",
+		"SeqScan(settings)",
+		"Rows(o u order_items #5000)",
+		"Set(work_mem 256MB)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("подсказки:\n%s\nожидались:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if block := Block(got[:2]); block != "/*+\n    Leading(((o u) order_items))\n    NestLoop(o u order_items)\n*/" {
+		t.Errorf("блок:\n%s", block)
+	}
+}
+
+type fakeSource struct {
+	plan  string
+	err   error
+	hints string
+}
+
+func (f *fakeSource) ExplainHinted(ctx context.Context, query, hints string) (string, error) {
+	f.hints = hints
+	return f.plan, f.err
+}
+
+func TestSuggest(t *testing.T) {
+	target := []analyzer.PlanNode{{NodeType: "Index Scan", RelationName: "orders", Alias: "orders", IndexName: "orders_pkey"}}
+
+	src := &fakeSource{plan: `[{"Plan": {"Node Type": "Index Scan", "Relation Name": "orders", "Alias": "orders", "Index Name": "orders_pkey"}}]`}
+	result := Suggest(context.Background(), src, "SELECT * FROM orders WHERE id = 1", target, nil)
+	if result.Verified == nil || !*result.Verified {
+		t.Errorf("подсказки не подтверждены: %+v", result)
+	}
+	if src.hints != "/*+\n    IndexScan(orders orders_pkey)\n*/" {
+		t.Errorf("переданы подсказки %q", src.hints)
+	}
+	if !strings.HasSuffix(result.Query, "*/\nSELECT * FROM orders WHERE id = 1") {
+		t.Errorf("запрос с подсказками %q", result.Query)
+	}
+
+	src.plan = `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "orders", "Alias": "orders"}}]`
+	result = Suggest(context.Background(), src, "SELECT * FROM orders WHERE id = 1", target, nil)
+	if result.Verified == nil || *result.Verified || result.Shape != "Seq Scan orders" {
+		t.Errorf("несовпадение не обнаружено: %+v", result)
+	}
+
+	src.err = postgres.ErrNoHintPlan
+	result = Suggest(context.Background(), src, "SELECT 1", target, nil)
+	if result.Verified != nil || result.Error != postgres.ErrNoHintPlan.Error() {
+		t.Errorf("без расширения: %+v", result)
+	}
+}
@@ -0,0 +1,168 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FileStore хранит историю в каталоге на диске, по JSON-файлу на запись.
+// Внешние сервисы не нужны, а записи можно просматривать и удалять
+// обычными средствами.
+type FileStore struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+// NewFileStore открывает хранилище в каталоге dir, создавая его при необходимости
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("ошибка создания каталога истории %s: %w", dir, err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Save записывает запись во временный файл и переименовывает его,
+// чтобы при сбое не осталось наполовину записанного JSON
+func (s *FileStore) Save(record Record) (Record, error) {
+	if record.ID == "" {
+		record.ID = newID(record.CreatedAt)
+	}
+	if !validID(record.ID) {
+		return record, fmt.Errorf("недопустимый идентификатор записи: %q", record.ID)
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return record, fmt.Errorf("ошибка кодирования записи истории: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return record, fmt.Errorf("ошибка записи истории: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return record, fmt.Errorf("ошибка записи истории: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return record, fmt.Errorf("ошибка записи истории: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(record.ID)); err != nil {
+		return record, fmt.Errorf("ошибка записи истории: %w", err)
+	}
+	return record, nil
+}
+
+// List читает все записи каталога и отбирает подходящие под фильтр.
+// Поврежденные записи пропускаются с сообщением в лог, чтобы один
+// испорченный файл не скрывал всю историю.
+func (s *FileStore) List(filter Filter) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения каталога истории: %w", err)
+	}
+
+	records := []Record{}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() || !validID(id) {
+			continue
+		}
+		record, err := s.read(id)
+		if err != nil {
+			log.Printf("История: пропускаем запись %s: %v", id, err)
+			continue
+		}
+		if filter.Match(record) {
+			records = append(records, record)
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if !records[i].CreatedAt.Equal(records[j].CreatedAt) {
+			return records[i].CreatedAt.After(records[j].CreatedAt)
+		}
+		return records[i].ID > records[j].ID
+	})
+	if filter.Limit > 0 && len(records) > filter.Limit {
+		records = records[:filter.Limit]
+	}
+	return records, nil
+}
+
+// Get читает запись по идентификатору
+func (s *FileStore) Get(id string) (Record, error) {
+	if !validID(id) {
+		return Record{}, ErrNotFound
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.read(id)
+}
+
+// Delete удаляет файл записи
+func (s *FileStore) Delete(id string) error {
+	if !validID(id) {
+		return ErrNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка удаления записи истории: %w", err)
+	}
+	return nil
+}
+
+// read читает и декодирует файл записи; вызывается под блокировкой
+func (s *FileStore) read(id string) (Record, error) {
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Record{}, ErrNotFound
+	}
+	if err != nil {
+		return Record{}, fmt.Errorf("ошибка чтения записи истории: %w", err)
+	}
+
+	var record Record
+	if err := json.Unmarshal(data, &record); err != nil {
+		return Record{}, fmt.Errorf("поврежденная запись истории %s: %w", id, err)
+	}
+	return record, nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// validID не пропускает идентификаторы, которые могли бы выйти за пределы
+// каталога хранилища
+func validID(id string) bool {
+	if id == "" || strings.HasPrefix(id, ".") {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '-' || c == '.' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
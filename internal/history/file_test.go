@@ -0,0 +1,115 @@
+package history
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sql-optimizer/internal/analyzer"
+)
+
+func TestFileStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	actual := 12.5
+	rows := 9
+	plan := []analyzer.PlanNode{{NodeType: "Seq Scan", RelationName: "orders", TotalCost: 180.5, ActualTotalTime: &actual, ActualRows: &rows}}
+	result := &analyzer.AnalysisResult{QueryFingerprint: "aaaa", PlanFingerprint: "p1"}
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	var saved []Record
+	for i, db := range []string{"localhost:5432/shop", "localhost:5432/shop", "replica:5432/shop"} {
+		record := NewRecord(db, "SELECT * FROM orders", plan, result)
+		record.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		if i == 2 {
+			record.QueryFingerprint = "bbbb"
+		}
+		record, err := store.Save(record)
+		if err != nil {
+			t.Fatalf("Неожиданная ошибка: %v", err)
+		}
+		saved = append(saved, record)
+	}
+
+	got, err := store.Get(saved[0].ID)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if got.TotalCost != 180.5 || got.ExecutionTime == nil || *got.ExecutionTime != 12.5 ||
+		got.Rows == nil || *got.Rows != 9 || len(got.Plan) != 1 || got.Result.PlanFingerprint != "p1" {
+		t.Errorf("Запись прочитана неверно: %+v", got)
+	}
+
+	testCases := []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{
+		{"все, новые первыми", Filter{}, []string{saved[2].ID, saved[1].ID, saved[0].ID}},
+		{"по отпечатку", Filter{QueryFingerprint: "aaaa"}, []string{saved[1].ID, saved[0].ID}},
+		{"по базе", Filter{Database: "replica:5432/shop"}, []string{saved[2].ID}},
+		{"по времени", Filter{Since: base.Add(time.Hour), Until: base.Add(2 * time.Hour)}, []string{saved[1].ID}},
+		{"с ограничением", Filter{Limit: 1}, []string{saved[2].ID}},
+	}
+	for _, tc := range testCases {
+		records, err := store.List(tc.filter)
+		if err != nil {
+			t.Fatalf("%s: неожиданная ошибка: %v", tc.name, err)
+		}
+		var ids []string
+		for _, r := range records {
+			ids = append(ids, r.ID)
+		}
+		if len(ids) != len(tc.expected) {
+			t.Errorf("%s: ожидали %v, получили %v", tc.name, tc.expected, ids)
+			continue
+		}
+		for i := range ids {
+			if ids[i] != tc.expected[i] {
+				t.Errorf("%s: ожидали %v, получили %v", tc.name, tc.expected, ids)
+				break
+			}
+		}
+	}
+
+	if err := store.Delete(saved[0].ID); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if _, err := store.Get(saved[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Ожидали ErrNotFound после удаления, получили %v", err)
+	}
+	if err := store.Delete(saved[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Ожидали ErrNotFound при повторном удалении, получили %v", err)
+	}
+	if _, err := store.Get("../../etc/passwd"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Идентификатор с путем должен отклоняться, получили %v", err)
+	}
+}
+
+func TestFileStoreListSkipsCorruptRecords(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	record, err := store.Save(NewRecord("localhost:5432/shop", "SELECT 1", nil, &analyzer.AnalysisResult{}))
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := store.List(Filter{})
+	if err != nil {
+		t.Fatalf("Поврежденная запись не должна прерывать список: %v", err)
+	}
+	if len(records) != 1 || records[0].ID != record.ID {
+		t.Errorf("Ожидали одну исправную запись, получили %+v", records)
+	}
+}
@@ -0,0 +1,99 @@
+// Package history хранит результаты прошлых анализов, чтобы их можно
+// было просмотреть позже и сравнить повторные запуски одного запроса.
+package history
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"sql-optimizer/internal/analyzer"
+)
+
+// ErrNotFound возвращается, если анализа с таким идентификатором нет
+var ErrNotFound = errors.New("анализ не найден в истории")
+
+// Record — один сохраненный запуск анализа
+type Record struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Database  string    `json:"database"` // хост, порт и имя БД без учетных данных
+	Query     string    `json:"query"`
+
+	QueryFingerprint string `json:"query_fingerprint"`
+	PlanFingerprint  string `json:"plan_fingerprint"`
+
+	// Показатели корня плана: время выполнения в мс, стоимость и строки
+	ExecutionTime *float64 `json:"execution_time,omitempty"`
+	TotalCost     float64  `json:"total_cost"`
+	Rows          *int     `json:"rows,omitempty"`
+
+	Plan   []analyzer.PlanNode      `json:"plan,omitempty"`
+	Result *analyzer.AnalysisResult `json:"result,omitempty"`
+}
+
+// NewRecord собирает запись истории из плана и результата анализа.
+// Отпечатки берутся из результата, поэтому fingerprint.Apply должен
+// быть вызван раньше.
+func NewRecord(database, query string, plan []analyzer.PlanNode, result *analyzer.AnalysisResult) Record {
+	record := Record{
+		CreatedAt:        time.Now().UTC(),
+		Database:         database,
+		Query:            query,
+		QueryFingerprint: result.QueryFingerprint,
+		PlanFingerprint:  result.PlanFingerprint,
+		Plan:             plan,
+		Result:           result,
+	}
+	if len(plan) > 0 {
+		root := plan[0]
+		record.ExecutionTime = root.ActualTotalTime
+		record.TotalCost = root.TotalCost
+		record.Rows = root.ActualRows
+	}
+	return record
+}
+
+// Filter — условия выборки из истории; пустые поля не ограничивают выборку
+type Filter struct {
+	QueryFingerprint string
+	Database         string
+	Since            time.Time
+	Until            time.Time
+	Limit            int // 0 — без ограничения
+}
+
+// Match сообщает, подходит ли запись под условия фильтра
+func (f Filter) Match(r Record) bool {
+	switch {
+	case f.QueryFingerprint != "" && r.QueryFingerprint != f.QueryFingerprint:
+		return false
+	case f.Database != "" && r.Database != f.Database:
+		return false
+	case !f.Since.IsZero() && r.CreatedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !r.CreatedAt.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// Store — хранилище истории анализов
+type Store interface {
+	// Save сохраняет запись, присваивая ей идентификатор, если его нет
+	Save(record Record) (Record, error)
+	// List возвращает подходящие под фильтр записи, новые первыми
+	List(filter Filter) ([]Record, error)
+	// Get возвращает запись по идентификатору или ErrNotFound
+	Get(id string) (Record, error)
+	// Delete удаляет запись по идентификатору или возвращает ErrNotFound
+	Delete(id string) error
+}
+
+// newID формирует идентификатор, который сортируется по времени создания
+func newID(t time.Time) string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return t.UTC().Format("20060102T150405.000000000") + "-" + hex.EncodeToString(suffix)
+}
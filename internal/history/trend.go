@@ -0,0 +1,181 @@
+package history
+
+import (
+	"sort"
+	"time"
+)
+
+// Виды изменений, обнаруживаемых в ряду анализов одного запроса
+const (
+	ChangeRegression  = "regression"  // показатель вырос относительно базовой линии
+	ChangeImprovement = "improvement" // показатель заметно снизился
+	ChangePlanFlip    = "plan_flip"   // планировщик выбрал план другой формы
+)
+
+// Показатели, по которым ищутся регрессии
+const (
+	MetricExecutionTime = "execution_time"
+	MetricTotalCost     = "total_cost"
+)
+
+// TrendOptions — параметры поиска изменений
+type TrendOptions struct {
+	// Window — сколько предыдущих запусков образуют скользящую базовую линию
+	Window int
+	// Threshold — во сколько раз показатель должен отличаться от базовой
+	// линии, чтобы считаться изменением
+	Threshold float64
+	// MinSamples — минимальное число запусков в базовой линии
+	MinSamples int
+}
+
+// DefaultTrendOptions — параметры по умолчанию: медиана последних пяти
+// запусков и изменение в полтора раза
+var DefaultTrendOptions = TrendOptions{Window: 5, Threshold: 1.5, MinSamples: 3}
+
+// Point — один запуск в ряду
+type Point struct {
+	RecordID        string    `json:"record_id"`
+	CreatedAt       time.Time `json:"created_at"`
+	ExecutionTime   *float64  `json:"execution_time,omitempty"`
+	TotalCost       float64   `json:"total_cost"`
+	Rows            *int      `json:"rows,omitempty"`
+	PlanFingerprint string    `json:"plan_fingerprint"`
+}
+
+// ChangePoint — запуск, на котором обнаружено изменение
+type ChangePoint struct {
+	RecordID  string    `json:"record_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Kind      string    `json:"kind"`
+	Metric    string    `json:"metric,omitempty"`
+	Baseline  float64   `json:"baseline,omitempty"` // медиана показателя в окне
+	Value     float64   `json:"value,omitempty"`
+	Ratio     float64   `json:"ratio,omitempty"` // Value / Baseline
+
+	// Для смены плана — отпечатки прежней и новой формы
+	PreviousPlan string `json:"previous_plan,omitempty"`
+	Plan         string `json:"plan,omitempty"`
+}
+
+// Trend — ряд запусков одного запроса на одной БД и найденные в нем
+// изменения
+type Trend struct {
+	QueryFingerprint string        `json:"query_fingerprint"`
+	Database         string        `json:"database,omitempty"`
+	Points           []Point       `json:"points"`
+	ChangePoints     []ChangePoint `json:"change_points"`
+}
+
+// LoadTrend выбирает из хранилища запуски по фильтру и строит по ним ряд
+func LoadTrend(store Store, filter Filter, opts TrendOptions) (Trend, error) {
+	records, err := store.List(filter)
+	if err != nil {
+		return Trend{}, err
+	}
+	trend := ComputeTrend(records, opts)
+	trend.QueryFingerprint = filter.QueryFingerprint
+	trend.Database = filter.Database
+	return trend, nil
+}
+
+// ComputeTrend упорядочивает запуски по времени и ищет изменения:
+// смену формы плана относительно предыдущего запуска и отклонение
+// времени выполнения и стоимости от медианы предыдущих Window запусков.
+// Медиана устойчива к единичным выбросам, а скользящее окно позволяет
+// базовой линии следовать за естественным ростом данных.
+func ComputeTrend(records []Record, opts TrendOptions) Trend {
+	sorted := append([]Record(nil), records...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	trend := Trend{Points: []Point{}, ChangePoints: []ChangePoint{}}
+	for i, r := range sorted {
+		if trend.QueryFingerprint == "" {
+			trend.QueryFingerprint = r.QueryFingerprint
+		}
+		trend.Points = append(trend.Points, Point{
+			RecordID:        r.ID,
+			CreatedAt:       r.CreatedAt,
+			ExecutionTime:   r.ExecutionTime,
+			TotalCost:       r.TotalCost,
+			Rows:            r.Rows,
+			PlanFingerprint: r.PlanFingerprint,
+		})
+
+		if i > 0 && r.PlanFingerprint != "" && sorted[i-1].PlanFingerprint != "" &&
+			r.PlanFingerprint != sorted[i-1].PlanFingerprint {
+			trend.ChangePoints = append(trend.ChangePoints, ChangePoint{
+				RecordID:     r.ID,
+				CreatedAt:    r.CreatedAt,
+				Kind:         ChangePlanFlip,
+				PreviousPlan: sorted[i-1].PlanFingerprint,
+				Plan:         r.PlanFingerprint,
+			})
+		}
+
+		window := sorted[max(0, i-opts.Window):i]
+		for _, metric := range []struct {
+			name  string
+			value func(Record) (float64, bool)
+		}{
+			{MetricExecutionTime, func(r Record) (float64, bool) {
+				if r.ExecutionTime == nil {
+					return 0, false
+				}
+				return *r.ExecutionTime, true
+			}},
+			{MetricTotalCost, func(r Record) (float64, bool) { return r.TotalCost, r.TotalCost > 0 }},
+		} {
+			value, ok := metric.value(r)
+			if !ok {
+				continue
+			}
+			var samples []float64
+			for _, prev := range window {
+				if v, ok := metric.value(prev); ok {
+					samples = append(samples, v)
+				}
+			}
+			if len(samples) < opts.MinSamples {
+				continue
+			}
+			baseline := median(samples)
+			if baseline <= 0 {
+				continue
+			}
+
+			kind := ""
+			switch ratio := value / baseline; {
+			case ratio >= opts.Threshold:
+				kind = ChangeRegression
+			case ratio <= 1/opts.Threshold:
+				kind = ChangeImprovement
+			}
+			if kind != "" {
+				trend.ChangePoints = append(trend.ChangePoints, ChangePoint{
+					RecordID:  r.ID,
+					CreatedAt: r.CreatedAt,
+					Kind:      kind,
+					Metric:    metric.name,
+					Baseline:  baseline,
+					Value:     value,
+					Ratio:     value / baseline,
+				})
+			}
+		}
+	}
+	return trend
+}
+
+// median возвращает медиану выборки, не меняя ее
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
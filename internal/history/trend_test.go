@@ -0,0 +1,72 @@
+package history
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestComputeTrend(t *testing.T) {
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	samples := []struct {
+		time float64
+		cost float64
+		plan string
+	}{
+		{10, 100, "p1"},
+		{11, 100, "p1"},
+		{9, 100, "p1"},
+		{10.5, 110, "p1"}, // рост в пределах порога
+		{30, 400, "p2"},   // план сменился после ANALYZE, запрос замедлился
+		{31, 400, "p2"},
+	}
+
+	// Записи передаются в обратном порядке: ряд должен упорядочиваться сам
+	var records []Record
+	for i := len(samples) - 1; i >= 0; i-- {
+		s := samples[i]
+		execution := s.time
+		records = append(records, Record{
+			ID:               fmt.Sprintf("r%d", i),
+			CreatedAt:        base.Add(time.Duration(i) * 24 * time.Hour),
+			QueryFingerprint: "q1",
+			PlanFingerprint:  s.plan,
+			ExecutionTime:    &execution,
+			TotalCost:        s.cost,
+		})
+	}
+
+	trend := ComputeTrend(records, DefaultTrendOptions)
+	if trend.QueryFingerprint != "q1" || len(trend.Points) != len(samples) || trend.Points[0].RecordID != "r0" {
+		t.Fatalf("Неверный ряд: %+v", trend)
+	}
+
+	expected := []struct {
+		record string
+		kind   string
+		metric string
+	}{
+		{"r4", ChangePlanFlip, ""},
+		{"r4", ChangeRegression, MetricExecutionTime},
+		{"r4", ChangeRegression, MetricTotalCost},
+		{"r5", ChangeRegression, MetricExecutionTime},
+		{"r5", ChangeRegression, MetricTotalCost},
+	}
+	if len(trend.ChangePoints) != len(expected) {
+		t.Fatalf("Ожидали %d изменений, получили %+v", len(expected), trend.ChangePoints)
+	}
+	for i, e := range expected {
+		got := trend.ChangePoints[i]
+		if got.RecordID != e.record || got.Kind != e.kind || got.Metric != e.metric {
+			t.Errorf("Изменение %d: ожидали %s/%s/%s, получили %+v", i, e.record, e.kind, e.metric, got)
+		}
+	}
+
+	flip := trend.ChangePoints[0]
+	if flip.PreviousPlan != "p1" || flip.Plan != "p2" {
+		t.Errorf("Неверные отпечатки смены плана: %+v", flip)
+	}
+	if regression := trend.ChangePoints[1]; regression.Baseline != 10.25 || regression.Value != 30 {
+		t.Errorf("Ожидали медиану 10.25 и значение 30, получили %+v", regression)
+	}
+}
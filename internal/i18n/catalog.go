@@ -0,0 +1,493 @@
+package i18n
+
+// Идентификаторы сообщений анализатора
+const (
+	ProblemSeqScan               = "problem.seq_scan"
+	ProblemSeqScanRecommendation = "problem.seq_scan.recommendation"
+	ProblemSort                  = "problem.sort"
+	ProblemSortRecommendation    = "problem.sort.recommendation"
+	ProblemJoin                  = "problem.join"
+	ProblemJoinRecommendation    = "problem.join.recommendation"
+	RecommendCreateIndex         = "recommendation.create_index"
+	WarningRowMisestimate        = "warning.row_misestimate"
+
+	ProblemForeignKeyCascade        = "problem.fk_cascade"
+	ProblemForeignKeyRecommendation = "problem.fk_cascade.recommendation"
+	RecommendForeignKeyIndex        = "recommendation.foreign_key_index"
+
+	ProblemHeapFetches                 = "problem.heap_fetches"
+	ProblemHeapFetchesRecommendation   = "problem.heap_fetches.recommendation"
+	RecommendVacuum                    = "recommendation.vacuum"
+	ProblemCoveringIndex               = "problem.covering_index"
+	ProblemCoveringIndexRecommendation = "problem.covering_index.recommendation"
+
+	IndexTypeTrigram    = "index_type.trigram"
+	IndexTypeJSONB      = "index_type.jsonb"
+	IndexTypeFullText   = "index_type.fulltext"
+	IndexTypeExpression = "index_type.expression"
+	IndexTypeRange      = "index_type.range"
+	IndexTypeBRIN       = "index_type.brin"
+	IndexTypePartial    = "index_type.partial"
+)
+
+// Идентификаторы сообщений движка рекомендаций
+const (
+	EngineHighTotalCost      = "engine.high_total_cost"
+	EngineSlowExecution      = "engine.slow_execution"
+	EngineSeqScan            = "engine.seq_scan"
+	EngineSort               = "engine.sort"
+	EngineHashJoin           = "engine.hash_join"
+	EngineNestedLoop         = "engine.nested_loop"
+	EngineExpensiveOperation = "engine.expensive_operation"
+)
+
+// Идентификаторы сообщений проверок каталога
+const (
+	IndexUnused     = "index.unused"
+	IndexRarelyUsed = "index.rarely_used"
+	IndexDuplicate  = "index.duplicate"
+	IndexRedundant  = "index.redundant"
+	IndexInvalid    = "index.invalid"
+
+	ForeignKeyUnindexed = "foreign_key.unindexed"
+)
+
+// Идентификаторы сообщений отчета о здоровье базы
+const (
+	HealthCacheHitRatio     = "health.cache_hit_ratio"
+	HealthTableBloat        = "health.table_bloat"
+	HealthIndexBloat        = "health.index_bloat"
+	HealthNeverAnalyzed     = "health.never_analyzed"
+	HealthNeverVacuumed     = "health.never_vacuumed"
+	HealthDeadTuples        = "health.dead_tuples"
+	HealthNoPrimaryKey      = "health.no_primary_key"
+	HealthSequenceOverflow  = "health.sequence_overflow"
+	HealthWraparound        = "health.xid_wraparound"
+	HealthLongTransaction   = "health.long_transaction"
+	HealthFsyncOff          = "health.setting.fsync"
+	HealthFullPageWritesOff = "health.setting.full_page_writes"
+	HealthZeroDamagedPages  = "health.setting.zero_damaged_pages"
+	HealthAutovacuumOff     = "health.setting.autovacuum"
+	HealthTrackCountsOff    = "health.setting.track_counts"
+	HealthSyncCommitOff     = "health.setting.synchronous_commit"
+)
+
+// Идентификаторы обоснований рекомендаций по параметрам сервера
+const (
+	TuningSharedBuffers      = "tuning.shared_buffers"
+	TuningEffectiveCacheSize = "tuning.effective_cache_size"
+	TuningWorkMem            = "tuning.work_mem"
+	TuningWorkMemSpills      = "tuning.work_mem.spills"
+	TuningWorkMemSession     = "tuning.work_mem.session"
+	TuningRandomPageCostSSD  = "tuning.random_page_cost.ssd"
+	TuningRandomPageCostHDD  = "tuning.random_page_cost.hdd"
+	TuningParallelPerGather  = "tuning.max_parallel_workers_per_gather"
+	TuningParallelWorkers    = "tuning.max_parallel_workers"
+	TuningWorkerProcesses    = "tuning.max_worker_processes"
+	TuningJIT                = "tuning.jit"
+)
+
+// Идентификаторы выводов эксперимента с планировщиком
+const (
+	ExperimentNoAlternatives = "experiment.no_alternatives"
+	ExperimentAlternatives   = "experiment.alternatives"
+	ExperimentBetterPlan     = "experiment.better_plan"
+	ExperimentConfirmed      = "experiment.confirmed"
+	ExperimentCostModel      = "experiment.cost_model"
+)
+
+// Идентификаторы рекомендаций по статистике планировщика
+const (
+	StatisticsExtendedFilter    = "statistics.extended.filter"
+	StatisticsExtendedGroup     = "statistics.extended.group"
+	StatisticsExtendedImproved  = "statistics.extended.improved"
+	StatisticsExtendedUnchanged = "statistics.extended.unchanged"
+
+	StatisticsStale               = "statistics.stale"
+	StatisticsStaleNever          = "statistics.stale.never"
+	StatisticsStaleRecommendation = "statistics.stale.recommendation"
+	StatisticsStaleFixed          = "statistics.stale.fixed"
+	StatisticsStaleNotFixed       = "statistics.stale.not_fixed"
+	StatisticsEstimateFresh       = "statistics.estimate.fresh"
+)
+
+// Идентификаторы событий мониторинга
+const (
+	MonitorRegressionTime = "monitor.regression.execution_time"
+	MonitorRegressionCost = "monitor.regression.total_cost"
+	MonitorPlanFlip       = "monitor.plan_flip"
+)
+
+// Идентификаторы фраз пересказа плана и записи чисел в нем
+const (
+	NarrateSeqScan       = "narrate.seq_scan"
+	NarrateSeqScanFilter = "narrate.seq_scan_filter"
+	NarrateIndexScan     = "narrate.index_scan"
+	NarrateIndexOnlyScan = "narrate.index_only_scan"
+	NarrateBitmapIndex   = "narrate.bitmap_index"
+	NarrateBitmapHeap    = "narrate.bitmap_heap"
+	NarrateHash          = "narrate.hash"
+	NarrateJoin          = "narrate.join"
+	NarrateSort          = "narrate.sort"
+	NarrateSortDisk      = "narrate.sort_disk"
+	NarrateAggregate     = "narrate.aggregate"
+	NarrateAggregateAll  = "narrate.aggregate_all"
+	NarrateLimit         = "narrate.limit"
+	NarrateGather        = "narrate.gather"
+	NarrateOther         = "narrate.other"
+	NarrateLoops         = "narrate.loops"
+	NarrateIntermediate  = "narrate.intermediate"
+	NarrateApprox        = "narrate.approx"
+
+	NumberThousand = "number.thousand"
+	NumberMillion  = "number.million"
+	NumberDecimal  = "number.decimal"
+)
+
+// Идентификаторы текстов отчетов в Markdown, JUnit XML и HTML
+const (
+	ReportTitle           = "report.title"
+	ReportGenerated       = "report.generated"
+	ReportError           = "report.error"
+	ReportNoResult        = "report.no_result"
+	ReportQuery           = "report.query"
+	ReportCost            = "report.cost"
+	ReportTime            = "report.time"
+	ReportProblems        = "report.problems"
+	ReportProblemCount    = "report.problem_count"
+	ReportWarnings        = "report.warnings"
+	ReportRecommendations = "report.recommendations"
+	ReportPlan            = "report.plan"
+	ReportFailed          = "report.failed"
+	ReportFingerprints    = "report.fingerprints"
+	ReportWorstNodes      = "report.worst_nodes"
+	ReportNode            = "report.node"
+	ReportExclusiveTime   = "report.exclusive_time"
+	ReportShare           = "report.share"
+	ReportRows            = "report.rows"
+
+	ReportNodeCost        = "report.node.cost"
+	ReportNodeTime        = "report.node.time"
+	ReportNodeRows        = "report.node.rows"
+	ReportNodeMisestimate = "report.node.misestimate"
+	ReportNodeLoops       = "report.node.loops"
+
+	ReportJUnitFailure = "report.junit.failure"
+)
+
+// catalog — шаблоны сообщений по языкам. Параметры в фигурных скобках
+// подставляются по имени, поэтому порядок слов в переводах может отличаться.
+var catalog = map[string]map[string]string{
+	"ru": catalogRU,
+	"en": catalogEN,
+}
+
+var catalogRU = map[string]string{
+	ProblemSeqScan:               "Sequential Scan на таблице {table}",
+	ProblemSeqScanRecommendation: "Добавить индекс на используемые в WHERE поля",
+	ProblemSort:                  "Операция сортировки",
+	ProblemSortRecommendation:    "Использовать индексы для предварительной сортировки",
+	ProblemJoin:                  "Операция соединения {node_type}",
+	ProblemJoinRecommendation:    "Проверить индексы на полях соединения",
+	RecommendCreateIndex:         "Создать индекс для таблицы {table}",
+	WarningRowMisestimate:        "Плохая оценка строк: планировалось {planned}, фактически {actual}",
+
+	ProblemForeignKeyCascade:        "Изменение строк {referenced} проверяет внешний ключ {constraint} полным чтением таблицы {table}",
+	ProblemForeignKeyRecommendation: "Проиндексировать столбцы внешнего ключа: {ddl}",
+	RecommendForeignKeyIndex:        "Создать индекс для внешнего ключа {constraint}: {ddl}",
+
+	IndexTypeTrigram:    "{operator} с шаблоном, начинающимся с подстановочного символа, не использует B-tree по {column}: нужен GIN-индекс с триграммами pg_trgm: {ddl}",
+	IndexTypeJSONB:      "Оператор {operator} по jsonb-столбцу {column} не поддерживается B-tree: нужен GIN-индекс: {ddl}",
+	IndexTypeFullText:   "Полнотекстовый поиск {operator} по {column} не использует B-tree: нужен GIN-индекс по tsvector: {ddl}",
+	IndexTypeExpression: "Условие на {operator} не использует B-tree по {column}: нужен индекс по выражению: {ddl}",
+	IndexTypeRange:      "Оператор {operator} по диапазону {column} не поддерживается B-tree: нужен GiST-индекс: {ddl}",
+	IndexTypeBRIN:       "Строки {table} лежат в порядке {column} (корреляция {correlation}): для условий по времени достаточно компактного BRIN-индекса: {ddl}",
+	IndexTypePartial:    "Условию {predicate} соответствует {percent}% строк {table}: вместо полного индекса достаточно частичного, он меньше примерно в {ratio} раз ({partial} вместо {full}): {ddl}",
+
+	ProblemHeapFetches:                 "Index Only Scan по {index} обратился к таблице {table} {fetches} раз на {rows} строк: в карте видимости отмечено только {visible}% страниц ({all_visible} из {pages}), для остальных видимость строк проверяется чтением таблицы",
+	ProblemHeapFetchesRecommendation:   "Обновите карту видимости: {vacuum} Чтобы она не отставала от изменений, запускайте автоочистку таблицы чаще: {autovacuum}",
+	RecommendVacuum:                    "Выполнить VACUUM таблицы {table}, чтобы Index Only Scan не читал таблицу: {vacuum}",
+	ProblemCoveringIndex:               "Index Scan по {index} читает из таблицы {table} столбцы {columns}, которых нет в индексе",
+	ProblemCoveringIndexRecommendation: "Добавьте эти столбцы в INCLUDE, и сканирование станет Index Only Scan; после этого {index} станет лишним, если на него не опираются ограничения: {ddl}",
+
+	EngineHighTotalCost:      "Общая стоимость запроса очень высока. Рассмотрите рефакторинг запроса или добавление индексов.",
+	EngineSlowExecution:      "Общее время выполнения превышает 1 секунду. Оптимизация необходима.",
+	EngineSeqScan:            "Sequential Scan обнаружен. Добавьте индексы на поля, используемые в условиях фильтрации.",
+	EngineSort:               "Обнаружена операция сортировки. Используйте индексы для предварительной сортировки данных.",
+	EngineHashJoin:           "Hash Join обнаружен. Убедитесь, что обе таблицы имеют индексы на полях соединения.",
+	EngineNestedLoop:         "Nested Loop обнаружен. Рассмотрите изменение условий соединения или добавление индексов.",
+	EngineExpensiveOperation: "Операция {node_type} имеет высокую стоимость. Рассмотрите оптимизацию.",
+
+	IndexUnused:     "Индекс {index} на таблице {table} ни разу не использовался с момента сброса статистики",
+	IndexRarelyUsed: "Индекс {index} на таблице {table} использовался всего {scans} раз с момента сброса статистики",
+	IndexDuplicate:  "Индекс {index} полностью повторяет индекс {other}",
+	IndexRedundant:  "Индекс {index} избыточен: его ключ является левым префиксом ключа {other}, и запросы могут использовать его",
+	IndexInvalid:    "Индекс {index} недействителен (прерванное CREATE INDEX CONCURRENTLY): планировщик его не использует, а запись в таблицу {table} его обновляет",
+
+	ForeignKeyUnindexed: "Для внешнего ключа {constraint} нет индекса по столбцам {table} ({columns}): удаление и изменение строк {referenced} и соединения с ней читают {table} целиком",
+
+	HealthCacheHitRatio:     "Доля чтений из кэша в базе {database} — {ratio}%: данные часто читаются с диска, стоит проверить shared_buffers и объем памяти",
+	HealthTableBloat:        "Таблица {table} раздута: примерно {wasted} из {size} ({percent}%) — пустое место; вернуть его можно VACUUM FULL, но он берет блокировку ACCESS EXCLUSIVE и останавливает чтение и запись таблицы на все время перезаписи; без долгой блокировки место возвращает pg_repack",
+	HealthIndexBloat:        "Индекс {index} раздут: примерно {wasted} из {size} ({percent}%) — пустое место",
+	HealthNeverAnalyzed:     "Таблица {table} ни разу не анализировалась: планировщик не знает распределения данных",
+	HealthNeverVacuumed:     "Таблица {table} ни разу не очищалась VACUUM",
+	HealthDeadTuples:        "В таблице {table} {dead} мертвых строк ({percent}%): autovacuum не успевает или ему мешают долгие транзакции",
+	HealthNoPrimaryKey:      "У таблицы {table} нет первичного ключа: строки нельзя однозначно адресовать, логическая репликация не передаст UPDATE и DELETE",
+	HealthSequenceOverflow:  "Последовательность {sequence} израсходована на {percent}%: предел {limit} задает тип {type}",
+	HealthWraparound:        "Возраст замороженных транзакций базы {database} — {age} ({percent}% до аварийной остановки записи)",
+	HealthLongTransaction:   "Транзакция процесса {pid} ({state}) открыта {duration}: она удерживает снимок, и VACUUM не может удалить мертвые строки",
+	HealthFsyncOff:          "fsync = off: после сбоя сервера или ОС данные могут оказаться поврежденными",
+	HealthFullPageWritesOff: "full_page_writes = off: частично записанные страницы после сбоя нельзя будет восстановить",
+	HealthZeroDamagedPages:  "zero_damaged_pages = on: поврежденные страницы молча обнуляются вместе с данными",
+	HealthAutovacuumOff:     "autovacuum = off: мертвые строки копятся, статистика устаревает, растет риск переполнения счетчика транзакций",
+	HealthTrackCountsOff:    "track_counts = off: без статистики активности autovacuum не знает, какие таблицы обрабатывать",
+	HealthSyncCommitOff:     "synchronous_commit = off: при сбое сервера теряются последние подтвержденные транзакции",
+
+	TuningSharedBuffers:      "shared_buffers = {current}: обычно под кэш страниц отводят четверть памяти сервера ({memory}) — {recommended}; остальное остается кэшу ОС и рабочей памяти. Изменение требует перезапуска",
+	TuningEffectiveCacheSize: "effective_cache_size = {current}: это оценка памяти под кэш ОС и shared_buffers, около трех четвертей памяти ({memory}) — {recommended}. Память не выделяется, но при заниженном значении планировщик недооценивает индексные сканирования",
+	TuningWorkMem:            "work_mem = {current}: столько может занять каждая сортировка и хеш-таблица каждого соединения. При {connections} соединениях и {memory} памяти безопасно {recommended}",
+	TuningWorkMemSpills:      "work_mem = {current}: в {spills} узлах планов сортировка или хеш-таблица не поместились в память и сбрасывались на диск, крупнейшей не хватило около {needed}. Безопасное значение {recommended} уберет большинство сбросов",
+	TuningWorkMemSession:     "В {spills} узлах планов данные сбрасывались на диск, крупнейшему узлу нужно около {recommended} — больше безопасного для всего сервера значения {safe}. Поднимите work_mem только в сеансе или транзакции тяжелого запроса",
+	TuningRandomPageCostSSD:  "random_page_cost = {current} рассчитан на вращающиеся диски. На SSD случайное чтение почти не дороже последовательного — {recommended}, и планировщик перестанет избегать индексных сканирований",
+	TuningRandomPageCostHDD:  "random_page_cost = {current} слишком мал для HDD: планировщик недооценивает случайные чтения и выбирает индексные сканирования там, где быстрее читать таблицу целиком; рекомендуется {recommended}",
+	TuningParallelPerGather:  "max_parallel_workers_per_gather = {current}: при {cpus} ядрах разумно {recommended} — половина ядер, но не больше четырех на один запрос",
+	TuningParallelWorkers:    "max_parallel_workers = {current}: в {count} узлах Gather запущено меньше параллельных процессов, чем планировалось, — общий пул исчерпан; рекомендуется {recommended}",
+	TuningWorkerProcesses:    "max_worker_processes = {current} ограничивает все фоновые процессы, включая параллельные: в {count} узлах Gather их не хватило; рекомендуется {recommended}. Изменение требует перезапуска",
+	TuningJIT:                "{name} = {current}: {count} запросов выше этого порога выполнились быстрее 100 мс, и JIT-компиляция стоила дороже выигрыша; рекомендуется {recommended}",
+
+	ExperimentNoAlternatives: "Ни один вариант параметров не изменил план: у планировщика нет других планов для этого запроса",
+	ExperimentAlternatives:   "Параметры дали {count} других планов; ближайший по стоимости — в варианте {variant}, он дороже выбранного в {factor} раза. Выполните эксперимент с ANALYZE, чтобы сравнить фактическое время",
+	ExperimentBetterPlan:     "В варианте {variant} запрос выполнился за {time} мс — в {factor} раза быстрее плана планировщика ({baseline} мс), хотя стоимость этого плана выше: {cost} против {baseline_cost}. Модель стоимости ошибается для этого запроса: проверьте статистику таблиц и параметры random_page_cost и effective_cache_size",
+	ExperimentConfirmed:      "План планировщика оказался самым быстрым из {count} найденных планов: модель стоимости для этого запроса верна",
+	ExperimentCostModel:      "Стоимость плохо предсказывает время: ранговая корреляция стоимости и времени по {count} планам равна {correlation}, оценка отдельных планов ошибается до {error} раз",
+
+	StatisticsExtendedFilter:    "Оценка строк {table} по условию на {columns} ошиблась в {factor} раз ({planned} вместо {actual}): планировщик считает столбцы независимыми и перемножает их селективности. Статистика зависимостей и MCV по сочетанию столбцов учтет их связь",
+	StatisticsExtendedGroup:     "Число групп по {columns} таблицы {table} оценено в {planned} вместо {actual} (ошибка в {factor} раз): планировщик перемножает числа различных значений столбцов ({distinct}). Статистика ndistinct по сочетанию столбцов даст точное число",
+	StatisticsExtendedImproved:  "Проверено: со статистикой оценка стала {after} строк при фактических {actual} (было {planned})",
+	StatisticsExtendedUnchanged: "Проверено: со статистикой оценка {after} строк при фактических {actual} (было {planned}) — ошибка не исправилась, ее причина в другом",
+
+	StatisticsStale:               "Оценка строк {table} ошиблась в {factor} раз ({planned} вместо {actual}), а статистика таблицы устарела: с последнего ANALYZE ({analyzed}) изменено {modified} строк из {rows}",
+	StatisticsStaleNever:          "Оценка строк {table} ошиблась в {factor} раз ({planned} вместо {actual}): статистика таблицы ни разу не собиралась, а в ней {rows} строк",
+	StatisticsStaleRecommendation: "Соберите статистику: {command}",
+	StatisticsStaleFixed:          "После ANALYZE {table} оценка стала {after} строк при фактических {actual}: ошибку вызывала устаревшая статистика",
+	StatisticsStaleNotFixed:       "После ANALYZE {table} оценка осталась {after} строк при фактических {actual}: одной свежей статистики недостаточно",
+	StatisticsEstimateFresh:       "Оценка строк {table} ошиблась в {factor} раз ({planned} вместо {actual}), хотя статистика таблицы свежая: причина в связи столбцов, выражениях или параметрах запроса",
+
+	MonitorRegressionTime: "Время выполнения, мс: рост в {ratio} раза ({value} против медианы {baseline})",
+	MonitorRegressionCost: "Стоимость: рост в {ratio} раза ({value} против медианы {baseline})",
+	MonitorPlanFlip:       "План сменился: {previous} → {plan}",
+
+	"rule.seq-scan":       "Последовательное сканирование таблицы",
+	"rule.sort":           "Дорогая операция сортировки",
+	"rule.expensive-join": "Дорогая операция соединения",
+
+	"rule.unindexed-foreign-key": "Внешний ключ без индекса",
+	"rule.stale-statistics":      "Устаревшая статистика таблицы",
+	"rule.heap-fetches":          "Index Only Scan читает таблицу из-за карты видимости",
+	"rule.covering-index":        "Индекс без столбцов INCLUDE для Index Only Scan",
+
+	NarrateSeqScan:       "PostgreSQL читает все {rows} строк таблицы {table}.",
+	NarrateSeqScanFilter: "PostgreSQL читает все {total} строк таблицы {table} и после фильтра по {columns} оставляет {kept} ({percent}%).",
+	NarrateIndexScan:     "Находит {rows} строк таблицы {table} по индексу {index}.",
+	NarrateIndexOnlyScan: "Получает {rows} строк только из индекса {index}, не обращаясь к таблице.",
+	NarrateBitmapIndex:   "Отмечает в битовой карте подходящие страницы по индексу {index}.",
+	NarrateBitmapHeap:    "Читает отмеченные страницы таблицы {table} и получает {rows} строк.",
+	NarrateHash:          "Строит в памяти хеш-таблицу из {rows} строк.",
+	NarrateJoin:          "Затем соединяет их с {other} методом {method} и получает {rows} строк.",
+	NarrateSort:          "Сортирует {rows} строк по {key}.",
+	NarrateSortDisk:      "Сортирует {rows} строк по {key}; данные не поместились в work_mem, и сортировка шла на диске.",
+	NarrateAggregate:     "Группирует строки по {key} и получает {rows} групп.",
+	NarrateAggregateAll:  "Вычисляет агрегаты и получает {rows} строк.",
+	NarrateLimit:         "Оставляет первые {rows} строк.",
+	NarrateGather:        "Собирает результаты {launched} параллельных воркеров из {planned} запланированных.",
+	NarrateOther:         "Выполняет операцию {operation} и получает {rows} строк.",
+	NarrateLoops:         " Этот шаг повторяется {loops} раз.",
+	NarrateIntermediate:  "промежуточным результатом",
+	NarrateApprox:        "около ",
+	NumberThousand:       " тыс.",
+	NumberMillion:        " млн",
+	NumberDecimal:        ",",
+
+	ReportTitle:           "Отчет SQL Optimizer",
+	ReportGenerated:       "Сформирован {time}",
+	ReportError:           "Ошибка",
+	ReportNoResult:        "нет результата анализа",
+	ReportQuery:           "Запрос",
+	ReportCost:            "Стоимость",
+	ReportTime:            "Время, мс",
+	ReportProblems:        "Проблемы",
+	ReportProblemCount:    "Проблемных операций",
+	ReportWarnings:        "Предупреждения",
+	ReportRecommendations: "Рекомендации",
+	ReportPlan:            "План выполнения",
+	ReportFailed:          "ошибка",
+	ReportFingerprints:    "Отпечаток запроса {query}, плана {plan}",
+	ReportWorstNodes:      "Самые дорогие узлы",
+	ReportNode:            "Узел",
+	ReportExclusiveTime:   "Собств. время, мс",
+	ReportShare:           "Доля",
+	ReportRows:            "Строки (факт / оценка)",
+
+	ReportNodeCost:        "стоимость {cost}",
+	ReportNodeTime:        "{time} мс",
+	ReportNodeRows:        "строки {rows}",
+	ReportNodeMisestimate: "{direction} оценка строк ошиблась в {factor} раз",
+	ReportNodeLoops:       "Циклов: {loops}",
+
+	ReportJUnitFailure: "проблемных операций: {count}",
+}
+
+var catalogEN = map[string]string{
+	ProblemSeqScan:               "Sequential Scan on table {table}",
+	ProblemSeqScanRecommendation: "Add an index on the columns used in WHERE",
+	ProblemSort:                  "Sort operation",
+	ProblemSortRecommendation:    "Use indexes to provide presorted data",
+	ProblemJoin:                  "{node_type} join operation",
+	ProblemJoinRecommendation:    "Check indexes on the join columns",
+	RecommendCreateIndex:         "Create an index for table {table}",
+	WarningRowMisestimate:        "Poor row estimate: planned {planned}, actual {actual}",
+
+	ProblemForeignKeyCascade:        "Changing rows of {referenced} checks foreign key {constraint} by reading table {table} in full",
+	ProblemForeignKeyRecommendation: "Index the foreign key columns: {ddl}",
+	RecommendForeignKeyIndex:        "Create an index for foreign key {constraint}: {ddl}",
+
+	IndexTypeTrigram:    "{operator} with a pattern starting with a wildcard cannot use a B-tree on {column}: a GIN index with pg_trgm trigrams is needed: {ddl}",
+	IndexTypeJSONB:      "Operator {operator} on jsonb column {column} is not supported by B-tree: a GIN index is needed: {ddl}",
+	IndexTypeFullText:   "Full-text search {operator} on {column} cannot use a B-tree: a GIN index on tsvector is needed: {ddl}",
+	IndexTypeExpression: "A condition on {operator} cannot use a B-tree on {column}: an expression index is needed: {ddl}",
+	IndexTypeRange:      "Operator {operator} on range {column} is not supported by B-tree: a GiST index is needed: {ddl}",
+	IndexTypeBRIN:       "Rows of {table} are stored in {column} order (correlation {correlation}): a compact BRIN index is enough for time conditions: {ddl}",
+	IndexTypePartial:    "Condition {predicate} matches {percent}% of rows of {table}: a partial index is enough instead of a full one, about {ratio} times smaller ({partial} instead of {full}): {ddl}",
+
+	ProblemHeapFetches:                 "Index Only Scan on {index} visited table {table} {fetches} times for {rows} rows: only {visible}% of pages ({all_visible} of {pages}) are marked in the visibility map, for the rest row visibility is checked by reading the table",
+	ProblemHeapFetchesRecommendation:   "Update the visibility map: {vacuum} To keep it up with changes, autovacuum the table more often: {autovacuum}",
+	RecommendVacuum:                    "Run VACUUM on table {table} so that Index Only Scan does not read the table: {vacuum}",
+	ProblemCoveringIndex:               "Index Scan on {index} reads columns {columns} of table {table} that are not in the index",
+	ProblemCoveringIndexRecommendation: "Add these columns to INCLUDE and the scan becomes an Index Only Scan; {index} then becomes redundant unless constraints depend on it: {ddl}",
+
+	EngineHighTotalCost:      "The total query cost is very high. Consider refactoring the query or adding indexes.",
+	EngineSlowExecution:      "Total execution time exceeds 1 second. Optimization is required.",
+	EngineSeqScan:            "Sequential Scan detected. Add indexes on the columns used in filter conditions.",
+	EngineSort:               "Sort operation detected. Use indexes to provide presorted data.",
+	EngineHashJoin:           "Hash Join detected. Make sure both tables have indexes on the join columns.",
+	EngineNestedLoop:         "Nested Loop detected. Consider changing the join conditions or adding indexes.",
+	EngineExpensiveOperation: "Operation {node_type} has a high cost. Consider optimizing it.",
+
+	IndexUnused:     "Index {index} on table {table} has not been used since the statistics were reset",
+	IndexRarelyUsed: "Index {index} on table {table} has been used only {scans} times since the statistics were reset",
+	IndexDuplicate:  "Index {index} is an exact duplicate of {other}",
+	IndexRedundant:  "Index {index} is redundant: its key is a left prefix of the key of {other}, which queries can use instead",
+	IndexInvalid:    "Index {index} is invalid (an interrupted CREATE INDEX CONCURRENTLY): the planner ignores it, but writes to table {table} still maintain it",
+
+	ForeignKeyUnindexed: "Foreign key {constraint} has no index on {table} ({columns}): deleting or updating rows of {referenced} and joins with it read {table} in full",
+
+	HealthCacheHitRatio:     "Cache hit ratio of database {database} is {ratio}%: data is often read from disk, check shared_buffers and available memory",
+	HealthTableBloat:        "Table {table} is bloated: about {wasted} of {size} ({percent}%) is free space; VACUUM FULL can reclaim it, but it takes an ACCESS EXCLUSIVE lock that blocks reads and writes for the whole rewrite; pg_repack reclaims it without a long lock",
+	HealthIndexBloat:        "Index {index} is bloated: about {wasted} of {size} ({percent}%) is free space",
+	HealthNeverAnalyzed:     "Table {table} has never been analyzed: the planner knows nothing about its data distribution",
+	HealthNeverVacuumed:     "Table {table} has never been vacuumed",
+	HealthDeadTuples:        "Table {table} has {dead} dead rows ({percent}%): autovacuum is falling behind or long transactions hold it back",
+	HealthNoPrimaryKey:      "Table {table} has no primary key: rows cannot be addressed uniquely and logical replication cannot publish UPDATE and DELETE",
+	HealthSequenceOverflow:  "Sequence {sequence} is {percent}% used: its limit {limit} comes from type {type}",
+	HealthWraparound:        "Frozen transaction ID age of database {database} is {age} ({percent}% of the way to the wraparound shutdown)",
+	HealthLongTransaction:   "Transaction of process {pid} ({state}) has been open for {duration}: it holds a snapshot, so VACUUM cannot remove dead rows",
+	HealthFsyncOff:          "fsync = off: data may be corrupted after a server or OS crash",
+	HealthFullPageWritesOff: "full_page_writes = off: torn pages cannot be recovered after a crash",
+	HealthZeroDamagedPages:  "zero_damaged_pages = on: damaged pages are silently zeroed together with their data",
+	HealthAutovacuumOff:     "autovacuum = off: dead rows pile up, statistics go stale and the risk of transaction ID wraparound grows",
+	HealthTrackCountsOff:    "track_counts = off: without activity statistics autovacuum cannot tell which tables need processing",
+	HealthSyncCommitOff:     "synchronous_commit = off: the most recently committed transactions are lost if the server crashes",
+
+	TuningSharedBuffers:      "shared_buffers = {current}: a quarter of server memory ({memory}) is the usual page cache size — {recommended}; the rest stays with the OS cache and working memory. Changing it requires a restart",
+	TuningEffectiveCacheSize: "effective_cache_size = {current}: it estimates the memory available to the OS cache and shared_buffers, about three quarters of memory ({memory}) — {recommended}. Nothing is allocated, but a low value makes the planner underrate index scans",
+	TuningWorkMem:            "work_mem = {current}: every sort and hash table of every connection may use this much. With {connections} connections and {memory} of memory {recommended} is safe",
+	TuningWorkMemSpills:      "work_mem = {current}: {spills} plan nodes spilled sorts or hash tables to disk, the largest was about {needed} short. The safe value {recommended} removes most spills",
+	TuningWorkMemSession:     "{spills} plan nodes spilled to disk and the largest needs about {recommended}, more than the server-wide safe value {safe}. Raise work_mem only in the session or transaction of the heavy query",
+	TuningRandomPageCostSSD:  "random_page_cost = {current} assumes spinning disks. On SSD random reads cost almost the same as sequential ones — {recommended}, and the planner stops avoiding index scans",
+	TuningRandomPageCostHDD:  "random_page_cost = {current} is too low for HDD: the planner underrates random reads and picks index scans where reading the whole table is faster; {recommended} is recommended",
+	TuningParallelPerGather:  "max_parallel_workers_per_gather = {current}: with {cpus} cores {recommended} is reasonable — half of the cores but no more than four per query",
+	TuningParallelWorkers:    "max_parallel_workers = {current}: {count} Gather nodes launched fewer workers than planned because the shared pool was exhausted; {recommended} is recommended",
+	TuningWorkerProcesses:    "max_worker_processes = {current} limits all background processes including parallel workers, and {count} Gather nodes ran short of them; {recommended} is recommended. Changing it requires a restart",
+	TuningJIT:                "{name} = {current}: {count} queries above this threshold finished in under 100 ms, so JIT compilation cost more than it saved; {recommended} is recommended",
+
+	ExperimentNoAlternatives: "No setting variant changed the plan: the planner has no other plans for this query",
+	ExperimentAlternatives:   "The settings produced {count} other plans; the cheapest of them, in variant {variant}, costs {factor} times more than the chosen one. Run the experiment with ANALYZE to compare the actual time",
+	ExperimentBetterPlan:     "In variant {variant} the query ran in {time} ms, {factor} times faster than the planner's plan ({baseline} ms), although its cost is higher: {cost} versus {baseline_cost}. The cost model is wrong for this query: check the table statistics and the random_page_cost and effective_cache_size settings",
+	ExperimentConfirmed:      "The planner's plan was the fastest of the {count} plans found: the cost model is right for this query",
+	ExperimentCostModel:      "Cost is a poor predictor of time: the rank correlation of cost and time over {count} plans is {correlation}, and the estimate of individual plans is off by up to {error} times",
+
+	StatisticsExtendedFilter:    "The row estimate of {table} for the condition on {columns} is off by {factor} times ({planned} instead of {actual}): the planner treats the columns as independent and multiplies their selectivities. Dependencies and MCV statistics on the column combination capture their correlation",
+	StatisticsExtendedGroup:     "The number of groups by {columns} of {table} is estimated at {planned} instead of {actual} (off by {factor} times): the planner multiplies the distinct counts of the columns ({distinct}). Ndistinct statistics on the column combination give the exact number",
+	StatisticsExtendedImproved:  "Verified: with the statistics the estimate became {after} rows against {actual} actual (was {planned})",
+	StatisticsExtendedUnchanged: "Verified: with the statistics the estimate is {after} rows against {actual} actual (was {planned}), so the error has a different cause",
+
+	StatisticsStale:               "The row estimate of {table} is off by {factor} times ({planned} instead of {actual}) and the table statistics are stale: {modified} of {rows} rows changed since the last ANALYZE ({analyzed})",
+	StatisticsStaleNever:          "The row estimate of {table} is off by {factor} times ({planned} instead of {actual}): statistics were never collected for the table, which has {rows} rows",
+	StatisticsStaleRecommendation: "Collect statistics: {command}",
+	StatisticsStaleFixed:          "After ANALYZE of {table} the estimate became {after} rows against {actual} actual: stale statistics caused the error",
+	StatisticsStaleNotFixed:       "After ANALYZE of {table} the estimate is still {after} rows against {actual} actual: fresh statistics alone are not enough",
+	StatisticsEstimateFresh:       "The row estimate of {table} is off by {factor} times ({planned} instead of {actual}) although the table statistics are fresh: the cause is correlated columns, expressions or query parameters",
+
+	MonitorRegressionTime: "Execution time, ms: {ratio}x increase ({value} vs. median {baseline})",
+	MonitorRegressionCost: "Cost: {ratio}x increase ({value} vs. median {baseline})",
+	MonitorPlanFlip:       "Plan changed: {previous} → {plan}",
+
+	"rule.seq-scan":       "Sequential table scan",
+	"rule.sort":           "Expensive sort operation",
+	"rule.expensive-join": "Expensive join operation",
+
+	"rule.unindexed-foreign-key": "Foreign key without an index",
+	"rule.stale-statistics":      "Stale table statistics",
+	"rule.heap-fetches":          "Index Only Scan reads the table because of the visibility map",
+	"rule.covering-index":        "Index lacks INCLUDE columns for an Index Only Scan",
+
+	NarrateSeqScan:       "PostgreSQL reads all {rows} rows of {table}.",
+	NarrateSeqScanFilter: "PostgreSQL reads all {total} rows of {table} and keeps {kept} ({percent}%) after filtering on {columns}.",
+	NarrateIndexScan:     "It finds {rows} rows of {table} using the index {index}.",
+	NarrateIndexOnlyScan: "It gets {rows} rows from the index {index} alone, without visiting the table.",
+	NarrateBitmapIndex:   "It marks the matching pages in a bitmap using the index {index}.",
+	NarrateBitmapHeap:    "It reads the marked pages of {table} and gets {rows} rows.",
+	NarrateHash:          "It builds an in-memory hash table from {rows} rows.",
+	NarrateJoin:          "Then it joins them with {other} using {method}, producing {rows} rows.",
+	NarrateSort:          "It sorts {rows} rows by {key}.",
+	NarrateSortDisk:      "It sorts {rows} rows by {key}; the data did not fit into work_mem, so the sort spilled to disk.",
+	NarrateAggregate:     "It groups the rows by {key} into {rows} groups.",
+	NarrateAggregateAll:  "It computes aggregates, producing {rows} rows.",
+	NarrateLimit:         "It keeps the first {rows} rows.",
+	NarrateGather:        "It collects the results of {launched} parallel workers out of {planned} planned.",
+	NarrateOther:         "It performs {operation}, producing {rows} rows.",
+	NarrateLoops:         " This step is repeated {loops} times.",
+	NarrateIntermediate:  "the intermediate result",
+	NarrateApprox:        "about ",
+	NumberThousand:       "K",
+	NumberMillion:        "M",
+	NumberDecimal:        ".",
+
+	ReportTitle:           "SQL Optimizer report",
+	ReportGenerated:       "Generated {time}",
+	ReportError:           "Error",
+	ReportNoResult:        "no analysis result",
+	ReportQuery:           "Query",
+	ReportCost:            "Cost",
+	ReportTime:            "Time, ms",
+	ReportProblems:        "Problems",
+	ReportProblemCount:    "Problematic operations",
+	ReportWarnings:        "Warnings",
+	ReportRecommendations: "Recommendations",
+	ReportPlan:            "Execution plan",
+	ReportFailed:          "error",
+	ReportFingerprints:    "Query fingerprint {query}, plan fingerprint {plan}",
+	ReportWorstNodes:      "Most expensive nodes",
+	ReportNode:            "Node",
+	ReportExclusiveTime:   "Self time, ms",
+	ReportShare:           "Share",
+	ReportRows:            "Rows (actual / estimated)",
+
+	ReportNodeCost:        "cost {cost}",
+	ReportNodeTime:        "{time} ms",
+	ReportNodeRows:        "rows {rows}",
+	ReportNodeMisestimate: "{direction} row estimate is off by {factor}x",
+	ReportNodeLoops:       "Loops: {loops}",
+
+	ReportJUnitFailure: "problematic operations: {count}",
+}
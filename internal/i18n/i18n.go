@@ -0,0 +1,137 @@
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale — язык сообщений, если другой не выбран
+const DefaultLocale = "ru"
+
+// Message — сообщение с идентификатором из каталога и именованными
+// параметрами, которые подставляются в шаблон вместо {имя}
+type Message struct {
+	ID   string            `json:"id"`
+	Args map[string]string `json:"args,omitempty"`
+}
+
+// New создает сообщение; параметры передаются парами имя, значение
+func New(id string, args ...string) Message {
+	m := Message{ID: id}
+	if len(args) > 0 {
+		m.Args = make(map[string]string, len(args)/2)
+		for i := 0; i+1 < len(args); i += 2 {
+			m.Args[args[i]] = args[i+1]
+		}
+	}
+	return m
+}
+
+// Format возвращает текст сообщения на языке locale
+func (m Message) Format(locale string) string {
+	return Format(locale, m.ID, m.Args)
+}
+
+// Key возвращает строку, однозначно определяющую сообщение вместе
+// с параметрами, например для удаления дубликатов
+func (m Message) Key() string {
+	names := make([]string, 0, len(m.Args))
+	for name := range m.Args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(m.ID)
+	for _, name := range names {
+		b.WriteString("|" + name + "=" + m.Args[name])
+	}
+	return b.String()
+}
+
+// Format подставляет параметры в шаблон сообщения id на языке locale.
+// Если перевода нет, используется язык по умолчанию, а если нет и его —
+// сам идентификатор.
+func Format(locale, id string, args map[string]string) string {
+	template, ok := catalog[locale][id]
+	if !ok {
+		template, ok = catalog[DefaultLocale][id]
+	}
+	if !ok {
+		template = id
+	}
+	if len(args) == 0 {
+		return template
+	}
+
+	replacements := make([]string, 0, len(args)*2)
+	for name, value := range args {
+		replacements = append(replacements, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(replacements...).Replace(template)
+}
+
+// FormatAll переводит список сообщений в тексты
+func FormatAll(locale string, messages []Message) []string {
+	texts := make([]string, 0, len(messages))
+	for _, m := range messages {
+		texts = append(texts, m.Format(locale))
+	}
+	return texts
+}
+
+// Supported сообщает, есть ли каталог для языка
+func Supported(locale string) bool {
+	_, ok := catalog[locale]
+	return ok
+}
+
+// Match приводит тег языка вида "en-US" или "en_US.UTF-8" к поддерживаемому
+// языку. Если язык не поддерживается, возвращается пустая строка.
+func Match(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_.;"); i >= 0 {
+		tag = tag[:i]
+	}
+	if Supported(tag) {
+		return tag
+	}
+	return ""
+}
+
+// FromAcceptLanguage выбирает язык по заголовку Accept-Language с учетом
+// весов q; если ни один язык не поддерживается, возвращается язык по умолчанию
+func FromAcceptLanguage(header string) string {
+	type candidate struct {
+		locale string
+		weight float64
+	}
+	var candidates []candidate
+
+	for i, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		locale := Match(tag)
+		if locale == "" {
+			continue
+		}
+		weight := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				weight = parsed
+			} else {
+				weight = 0
+			}
+		}
+		// При равных весах выигрывает язык, указанный раньше
+		candidates = append(candidates, candidate{locale, weight - float64(i)*1e-6})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].weight > candidates[j].weight
+	})
+	if len(candidates) == 0 || candidates[0].weight <= 0 {
+		return DefaultLocale
+	}
+	return candidates[0].locale
+}
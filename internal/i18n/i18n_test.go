@@ -0,0 +1,87 @@
+package i18n
+
+import (
+	"regexp"
+	"sort"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		name     string
+		locale   string
+		message  Message
+		expected string
+	}{
+		{"Русский шаблон", "ru", New(ProblemSeqScan, "table", "orders"), "Sequential Scan на таблице orders"},
+		{"Английский шаблон", "en", New(ProblemJoin, "node_type", "Hash Join"), "Hash Join join operation"},
+		{"Неизвестный язык", "de", New(ProblemSort), "Операция сортировки"},
+		{"Неизвестный идентификатор", "en", New("missing.id"), "missing.id"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.message.Format(tc.locale); got != tc.expected {
+				t.Errorf("Ожидали %q, получили %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestCatalogsConsistent проверяет, что во всех каталогах одинаковые
+// идентификаторы и одинаковые параметры в шаблонах
+func TestCatalogsConsistent(t *testing.T) {
+	placeholder := regexp.MustCompile(`\{[a-z_]+\}`)
+	params := func(template string) []string {
+		found := placeholder.FindAllString(template, -1)
+		sort.Strings(found)
+		return found
+	}
+
+	reference := catalog[DefaultLocale]
+	for locale, messages := range catalog {
+		for id, template := range reference {
+			translated, ok := messages[id]
+			if !ok {
+				t.Errorf("%s: нет перевода для %s", locale, id)
+				continue
+			}
+			if got, want := params(translated), params(template); len(got) != len(want) || !equal(got, want) {
+				t.Errorf("%s: параметры %s не совпадают: %v вместо %v", locale, id, got, want)
+			}
+		}
+		for id := range messages {
+			if _, ok := reference[id]; !ok {
+				t.Errorf("%s: лишний идентификатор %s", locale, id)
+			}
+		}
+	}
+}
+
+func TestFromAcceptLanguage(t *testing.T) {
+	testCases := []struct {
+		header   string
+		expected string
+	}{
+		{"", DefaultLocale},
+		{"en-US,en;q=0.9", "en"},
+		{"de-DE, en;q=0.5, ru;q=0.8", "ru"},
+		{"fr, de", DefaultLocale},
+		{"en;q=0", DefaultLocale},
+	}
+
+	for _, tc := range testCases {
+		if got := FromAcceptLanguage(tc.header); got != tc.expected {
+			t.Errorf("%q: ожидали %s, получили %s", tc.header, tc.expected, got)
+		}
+	}
+}
+
+func equal(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"sql-optimizer/internal/history"
+	"sql-optimizer/internal/i18n"
+)
+
+// Duration — длительность, которая в JSON записывается строкой вида "24h"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("длительность должна быть строкой вида \"30s\": %v", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Connection — сохраненное подключение к БД
+type Connection struct {
+	DSN string `json:"dsn"`
+}
+
+// SavedQuery — запрос, который регулярно проверяется
+type SavedQuery struct {
+	Name       string `json:"name"`
+	Connection string `json:"connection"` // имя подключения из Config.Connections
+	Query      string `json:"query"`
+}
+
+// Config — настройки мониторинга, читаются из JSON-файла
+type Config struct {
+	// Interval — период проверок, по умолчанию сутки
+	Interval Duration `json:"interval"`
+	// StartAt — время первой проверки "ЧЧ:ММ" по местному времени;
+	// без него первая проверка выполняется сразу после запуска
+	StartAt string `json:"start_at,omitempty"`
+	// StatementTimeout ограничивает выполнение одного запроса
+	StatementTimeout Duration `json:"statement_timeout"`
+	// WebhookURL — адрес, на который отправляются уведомления
+	WebhookURL string `json:"webhook_url,omitempty"`
+	// Locale — язык текстов уведомлений, по умолчанию ru
+	Locale string `json:"locale,omitempty"`
+
+	// Window и Threshold задают скользящую базовую линию для регрессий
+	Window    int     `json:"window,omitempty"`
+	Threshold float64 `json:"threshold,omitempty"`
+
+	Connections map[string]Connection `json:"connections"`
+	Queries     []SavedQuery          `json:"queries"`
+}
+
+// LoadConfig читает настройки мониторинга из файла и проверяет их
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("ошибка чтения настроек мониторинга %s: %v", path, err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("ошибка разбора настроек мониторинга %s: %v", path, err)
+	}
+	return cfg, cfg.validate()
+}
+
+// validate подставляет значения по умолчанию и проверяет ссылки на подключения
+func (c *Config) validate() error {
+	if c.Interval <= 0 {
+		c.Interval = Duration(24 * time.Hour)
+	}
+	if c.StatementTimeout <= 0 {
+		c.StatementTimeout = Duration(time.Minute)
+	}
+	if c.Window <= 0 {
+		c.Window = history.DefaultTrendOptions.Window
+	}
+	if c.Threshold <= 1 {
+		c.Threshold = history.DefaultTrendOptions.Threshold
+	}
+	if c.Locale == "" {
+		c.Locale = i18n.DefaultLocale
+	}
+	if !i18n.Supported(c.Locale) {
+		return fmt.Errorf("неподдерживаемый язык уведомлений %q", c.Locale)
+	}
+	if c.StartAt != "" {
+		if _, err := time.Parse("15:04", c.StartAt); err != nil {
+			return fmt.Errorf("неверное время start_at %q: ожидается ЧЧ:ММ", c.StartAt)
+		}
+	}
+
+	for i, q := range c.Queries {
+		if q.Query == "" {
+			return fmt.Errorf("у запроса %d (%s) пустой текст", i+1, q.Name)
+		}
+		if _, ok := c.Connections[q.Connection]; !ok {
+			return fmt.Errorf("запрос %q ссылается на неизвестное подключение %q", q.Name, q.Connection)
+		}
+	}
+	return nil
+}
+
+// trendOptions возвращает параметры поиска регрессий
+func (c Config) trendOptions() history.TrendOptions {
+	opts := history.DefaultTrendOptions
+	opts.Window = c.Window
+	opts.Threshold = c.Threshold
+	opts.MinSamples = min(opts.MinSamples, c.Window)
+	return opts
+}
+
+// firstRun возвращает время первой проверки после now
+func (c Config) firstRun(now time.Time) time.Time {
+	if c.StartAt == "" {
+		return now
+	}
+	at, _ := time.Parse("15:04", c.StartAt)
+	next := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, now.Location())
+	if next.Before(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
@@ -0,0 +1,278 @@
+// Package monitor по расписанию повторно анализирует сохраненные запросы,
+// сохраняет результаты в историю и отправляет уведомления на webhook,
+// если запрос замедлился, сменил план или получил новую серьезную находку.
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/fingerprint"
+	"sql-optimizer/internal/history"
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+)
+
+// Виды событий в уведомлении
+const (
+	EventRegression = history.ChangeRegression
+	EventPlanFlip   = history.ChangePlanFlip
+	EventNewFinding = "new_finding"
+)
+
+// regressionMessages — сообщения о регрессии по показателям
+var regressionMessages = map[string]string{
+	history.MetricExecutionTime: i18n.MonitorRegressionTime,
+	history.MetricTotalCost:     i18n.MonitorRegressionCost,
+}
+
+// Event — обнаруженное при проверке изменение
+type Event struct {
+	Kind        string       `json:"kind"`
+	Message     i18n.Message `json:"message"`
+	Description string       `json:"description"`
+
+	Metric   string  `json:"metric,omitempty"`
+	Baseline float64 `json:"baseline,omitempty"`
+	Value    float64 `json:"value,omitempty"`
+	Ratio    float64 `json:"ratio,omitempty"`
+
+	PreviousPlan string `json:"previous_plan,omitempty"`
+	Plan         string `json:"plan,omitempty"`
+
+	RuleID   string `json:"rule_id,omitempty"`
+	Relation string `json:"relation,omitempty"`
+}
+
+// Notification — тело POST-запроса на webhook
+type Notification struct {
+	Query            string    `json:"query"` // имя сохраненного запроса
+	Database         string    `json:"database"`
+	RecordID         string    `json:"record_id"`
+	QueryFingerprint string    `json:"query_fingerprint"`
+	CheckedAt        time.Time `json:"checked_at"`
+	Events           []Event   `json:"events"`
+}
+
+// Localize формирует тексты событий на языке locale
+func (n *Notification) Localize(locale string) {
+	for i := range n.Events {
+		n.Events[i].Description = n.Events[i].Message.Format(locale)
+	}
+}
+
+// explainFunc получает план запроса; в тестах подменяется
+type explainFunc func(ctx context.Context, dsn, query string, timeout time.Duration) ([]analyzer.PlanNode, error)
+
+// Monitor выполняет проверки сохраненных запросов
+type Monitor struct {
+	config  Config
+	store   history.Store
+	explain explainFunc
+	client  *http.Client
+}
+
+// New создает монитор; результаты проверок сохраняются в store
+func New(config Config, store history.Store) *Monitor {
+	return &Monitor{
+		config:  config,
+		store:   store,
+		explain: explainSafe,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Run выполняет проверки по расписанию до отмены ctx
+func (m *Monitor) Run(ctx context.Context) {
+	next := m.config.firstRun(time.Now())
+	log.Printf("Мониторинг %d запросов, первая проверка в %s", len(m.config.Queries), next.Format("2006-01-02 15:04"))
+
+	for {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		m.Check(ctx)
+		next = next.Add(time.Duration(m.config.Interval))
+		if now := time.Now(); next.Before(now) {
+			// Проверка заняла больше интервала — следующую не откладываем
+			next = now
+		}
+	}
+}
+
+// Check однократно проверяет все сохраненные запросы и возвращает
+// отправленные уведомления. Ошибка одного запроса не мешает остальным.
+func (m *Monitor) Check(ctx context.Context) []Notification {
+	var notifications []Notification
+	for _, q := range m.config.Queries {
+		notification, err := m.checkQuery(ctx, q)
+		if err != nil {
+			log.Printf("Мониторинг %q: %v", q.Name, err)
+			continue
+		}
+		if len(notification.Events) == 0 {
+			continue
+		}
+		notification.Localize(m.config.Locale)
+		notifications = append(notifications, notification)
+		if err := m.notify(ctx, notification); err != nil {
+			log.Printf("Мониторинг %q: ошибка отправки уведомления: %v", q.Name, err)
+		}
+	}
+	return notifications
+}
+
+// checkQuery анализирует запрос, сохраняет результат и сравнивает его
+// с предыдущими запусками того же запроса на той же БД
+func (m *Monitor) checkQuery(ctx context.Context, q SavedQuery) (Notification, error) {
+	dsn := m.config.Connections[q.Connection].DSN
+	database := postgres.DatabaseName(dsn)
+
+	plan, err := m.explain(ctx, dsn, q.Query, time.Duration(m.config.StatementTimeout))
+	if err != nil {
+		return Notification{}, err
+	}
+	result := analyzer.AnalyzeNodes(plan)
+	fingerprint.Apply(result, q.Query, plan)
+
+	previous, err := m.store.List(history.Filter{
+		QueryFingerprint: result.QueryFingerprint,
+		Database:         database,
+		Limit:            m.config.Window,
+	})
+	if err != nil {
+		return Notification{}, err
+	}
+
+	record, err := m.store.Save(history.NewRecord(database, q.Query, plan, result))
+	if err != nil {
+		return Notification{}, err
+	}
+
+	return Notification{
+		Query:            q.Name,
+		Database:         database,
+		RecordID:         record.ID,
+		QueryFingerprint: record.QueryFingerprint,
+		CheckedAt:        record.CreatedAt,
+		Events:           detect(previous, record, m.config.trendOptions()),
+	}, nil
+}
+
+// detect сравнивает новый запуск с предыдущими: регрессии и смена плана
+// ищутся так же, как в трендах истории, а новые находки высокой
+// серьезности — относительно последнего предыдущего запуска
+func detect(previous []history.Record, current history.Record, opts history.TrendOptions) []Event {
+	var events []Event
+
+	trend := history.ComputeTrend(append(append([]history.Record(nil), previous...), current), opts)
+	for _, cp := range trend.ChangePoints {
+		if cp.RecordID != current.ID {
+			continue
+		}
+		switch cp.Kind {
+		case history.ChangeRegression:
+			events = append(events, Event{
+				Kind: EventRegression,
+				Message: i18n.New(regressionMessages[cp.Metric],
+					"ratio", strconv.FormatFloat(cp.Ratio, 'f', 1, 64),
+					"value", strconv.FormatFloat(cp.Value, 'f', 2, 64),
+					"baseline", strconv.FormatFloat(cp.Baseline, 'f', 2, 64)),
+				Metric:   cp.Metric,
+				Baseline: cp.Baseline,
+				Value:    cp.Value,
+				Ratio:    cp.Ratio,
+			})
+		case history.ChangePlanFlip:
+			events = append(events, Event{
+				Kind:         EventPlanFlip,
+				Message:      i18n.New(i18n.MonitorPlanFlip, "previous", cp.PreviousPlan, "plan", cp.Plan),
+				PreviousPlan: cp.PreviousPlan,
+				Plan:         cp.Plan,
+			})
+		}
+	}
+
+	var last *history.Record
+	for i := range previous {
+		if previous[i].Result != nil && (last == nil || previous[i].CreatedAt.After(last.CreatedAt)) {
+			last = &previous[i]
+		}
+	}
+	if last == nil || current.Result == nil {
+		return events
+	}
+
+	known := make(map[string]bool)
+	for _, problem := range last.Result.ProblematicOperations {
+		if problem.Severity == "high" {
+			known[problem.RuleID+"/"+problem.Relation] = true
+		}
+	}
+	for _, problem := range current.Result.ProblematicOperations {
+		if problem.Severity != "high" || known[problem.RuleID+"/"+problem.Relation] {
+			continue
+		}
+		known[problem.RuleID+"/"+problem.Relation] = true
+		events = append(events, Event{
+			Kind:     EventNewFinding,
+			Message:  problem.DescriptionMessage,
+			RuleID:   problem.RuleID,
+			Relation: problem.Relation,
+		})
+	}
+	return events
+}
+
+// notify отправляет уведомление на webhook методом POST
+func (m *Monitor) notify(ctx context.Context, notification Notification) error {
+	if m.config.WebhookURL == "" {
+		return nil
+	}
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.config.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook ответил %s", resp.Status)
+	}
+	return nil
+}
+
+// explainSafe подключается к БД и получает план в безопасном режиме
+func explainSafe(ctx context.Context, dsn, query string, timeout time.Duration) ([]analyzer.PlanNode, error) {
+	client, err := postgres.NewClient(dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	planJSON, err := client.GetExplainPlanSafe(ctx, query, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return analyzer.ParseExplainJSON(planJSON)
+}
@@ -0,0 +1,138 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/history"
+	"sql-optimizer/internal/i18n"
+)
+
+func TestCheck(t *testing.T) {
+	var received []Notification
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n Notification
+		if err := json.NewDecoder(r.Body).Decode(&n); err != nil {
+			t.Errorf("Неверное тело уведомления: %v", err)
+		}
+		received = append(received, n)
+	}))
+	defer webhook.Close()
+
+	store, err := history.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	cfg := Config{
+		WebhookURL:  webhook.URL,
+		Locale:      "en",
+		Connections: map[string]Connection{"prod": {DSN: "host=db port=5432 dbname=shop"}},
+		Queries:     []SavedQuery{{Name: "orders", Connection: "prod", Query: "SELECT * FROM orders WHERE user_id = 42"}},
+	}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	// Три быстрых запуска по индексу, затем план сменяется на Seq Scan
+	indexScan := func(ms float64) []analyzer.PlanNode {
+		return []analyzer.PlanNode{{NodeType: "Index Scan", RelationName: "orders", IndexName: "orders_user_id_idx", TotalCost: 8, ActualTotalTime: &ms}}
+	}
+	seqScan := func(ms float64) []analyzer.PlanNode {
+		return []analyzer.PlanNode{{NodeType: "Seq Scan", RelationName: "orders", TotalCost: 1800, ActualTotalTime: &ms}}
+	}
+	plans := [][]analyzer.PlanNode{indexScan(1), indexScan(1.2), indexScan(0.9), seqScan(45)}
+
+	m := New(cfg, store)
+	m.explain = func(ctx context.Context, dsn, query string, timeout time.Duration) ([]analyzer.PlanNode, error) {
+		if timeout != time.Minute {
+			t.Errorf("Ожидали statement_timeout по умолчанию 1m, получили %s", timeout)
+		}
+		plan := plans[0]
+		plans = plans[1:]
+		return plan, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if notifications := m.Check(context.Background()); len(notifications) != 0 {
+			t.Fatalf("Запуск %d: не ожидали уведомлений, получили %+v", i+1, notifications)
+		}
+		time.Sleep(time.Millisecond) // записи истории упорядочиваются по времени
+	}
+	m.Check(context.Background())
+
+	if len(received) != 1 {
+		t.Fatalf("Ожидали одно уведомление, получили %d", len(received))
+	}
+	n := received[0]
+	if n.Query != "orders" || n.Database != "db:5432/shop" || n.RecordID == "" {
+		t.Errorf("Неверные поля уведомления: %+v", n)
+	}
+
+	kinds := map[string]int{}
+	for _, e := range n.Events {
+		kinds[e.Kind]++
+		if e.Kind == EventPlanFlip && (e.Message.ID != i18n.MonitorPlanFlip || !strings.HasPrefix(e.Description, "Plan changed: ")) {
+			t.Errorf("Неверный текст смены плана: %+v", e)
+		}
+		if e.Description == "" || e.Description == e.Message.ID {
+			t.Errorf("Текст события не сформирован: %+v", e)
+		}
+	}
+	if kinds[EventPlanFlip] != 1 || kinds[EventRegression] != 2 || kinds[EventNewFinding] != 1 {
+		t.Errorf("Ожидали смену плана, две регрессии и новую находку, получили %+v", n.Events)
+	}
+
+	records, err := store.List(history.Filter{})
+	if err != nil || len(records) != 4 {
+		t.Errorf("Ожидали 4 записи в истории, получили %d (%v)", len(records), err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitor.json")
+	data := `{
+		"interval": "12h",
+		"start_at": "03:00",
+		"connections": {"prod": {"dsn": "postgres://app@db/shop"}},
+		"queries": [{"name": "orders", "connection": "stage", "query": "SELECT 1"}]
+	}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), "stage") {
+		t.Fatalf("Ожидали ошибку о неизвестном подключении, получили %v", err)
+	}
+
+	data = strings.Replace(data, `"stage"`, `"prod"`, 1)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if time.Duration(cfg.Interval) != 12*time.Hour || cfg.Window != 5 || cfg.Locale != "ru" {
+		t.Errorf("Неверные настройки: %+v", cfg)
+	}
+
+	cfg.Locale = "de"
+	if err := cfg.validate(); err == nil || !strings.Contains(err.Error(), "de") {
+		t.Errorf("Ожидали ошибку о неподдерживаемом языке, получили %v", err)
+	}
+
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	if next := cfg.firstRun(now); !next.Equal(time.Date(2024, 3, 2, 3, 0, 0, 0, time.UTC)) {
+		t.Errorf("Ожидали первую проверку завтра в 03:00, получили %s", next)
+	}
+}
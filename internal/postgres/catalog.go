@@ -0,0 +1,281 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"sql-optimizer/internal/quote"
+)
+
+// userTables — условие на таблицы пользователя во всех схемах, кроме
+// системных, TOAST и временных; запрос соединяет pg_class t и pg_namespace n
+const userTables = `t.relkind IN ('r', 'p', 'm')
+	AND n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname !~ '^pg_(toast|temp_)'`
+
+// visibleTables — таблицы пользователя, видимые через search_path. По ним
+// ищутся таблицы из плана: там имя не уточнено схемой и разрешается так же,
+// как в запросе
+const visibleTables = userTables + ` AND pg_table_is_visible(t.oid)`
+
+// Index — индекс таблицы пользователя из pg_index
+type Index struct {
+	Schema     string   `json:"schema"`
+	Name       string   `json:"name"`
+	Table      string   `json:"table"`
+	Method     string   `json:"method"`              // btree, hash, gin, gist...
+	Columns    []string `json:"columns"`             // ключевые столбцы без кавычек; выражения в скобках, см. IsExpression
+	Include    []string `json:"include,omitempty"`   // столбцы INCLUDE, имена без кавычек
+	Predicate  string   `json:"predicate,omitempty"` // условие частичного индекса
+	Unique     bool     `json:"unique"`
+	Primary    bool     `json:"primary"`
+	Valid      bool     `json:"valid"`
+	SizeBytes  int64    `json:"size_bytes"`
+	Scans      int64    `json:"scans"`      // idx_scan из pg_stat_user_indexes
+	Definition string   `json:"definition"` // CREATE INDEX из pg_get_indexdef, только для вывода
+	// Constraints — ограничения, которые опираются на индекс: первичный
+	// ключ, UNIQUE, EXCLUDE и внешние ключи, ссылающиеся на него
+	Constraints []string `json:"constraints,omitempty"`
+}
+
+// Table — таблица пользователя со статистикой изменений
+type Table struct {
+	Schema     string `json:"schema"`
+	Name       string `json:"name"`
+	Kind       string `json:"kind"`              // relkind: r — таблица, p — секционированная, m — материализованное представление
+	Rows       int64  `json:"rows"`              // оценка из pg_class.reltuples
+	Pages      int64  `json:"pages"`             // relpages — размер основного слоя в блоках
+	AllVisible int64  `json:"all_visible_pages"` // relallvisible — страницы, видимые всем по карте видимости
+	SizeBytes  int64  `json:"size_bytes"`
+	Inserts    int64  `json:"inserts"`
+	Updates    int64  `json:"updates"`
+	HotUpdates int64  `json:"hot_updates"`
+	Deletes    int64  `json:"deletes"`
+	SeqScans   int64  `json:"seq_scans"`    // seq_scan из pg_stat_user_tables
+	SeqTupRead int64  `json:"seq_tup_read"` // строк прочитано последовательными сканированиями
+	IdxScans   int64  `json:"idx_scans"`
+	LiveTuples int64  `json:"live_tuples"`
+	DeadTuples int64  `json:"dead_tuples"`
+	// Последние ручные и автоматические VACUUM и ANALYZE; nil — не было
+	LastVacuum      *time.Time `json:"last_vacuum,omitempty"`
+	LastAutovacuum  *time.Time `json:"last_autovacuum,omitempty"`
+	LastAnalyze     *time.Time `json:"last_analyze,omitempty"`
+	LastAutoanalyze *time.Time `json:"last_autoanalyze,omitempty"`
+	HasPrimaryKey   bool       `json:"has_primary_key"`
+	// ColumnWidths — средняя ширина столбцов из pg_stats, байт
+	ColumnWidths map[string]int `json:"column_widths,omitempty"`
+}
+
+// QualifiedName — имя таблицы, уточненное схемой, для команд SQL
+func (t Table) QualifiedName() string {
+	return quote.Ident(t.Schema) + "." + quote.Ident(t.Name)
+}
+
+// ForeignKey — внешний ключ таблицы пользователя из pg_constraint
+type ForeignKey struct {
+	Schema            string   `json:"schema"`
+	Name              string   `json:"name"`
+	Table             string   `json:"table"`
+	Columns           []string `json:"columns"`
+	ReferencedSchema  string   `json:"referenced_schema"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	OnDelete          string   `json:"on_delete"` // NO ACTION, RESTRICT, CASCADE, SET NULL, SET DEFAULT
+	OnUpdate          string   `json:"on_update"`
+}
+
+// foreignKeyActions — действия внешнего ключа по коду из pg_constraint
+var foreignKeyActions = map[string]string{
+	"a": "NO ACTION", "r": "RESTRICT", "c": "CASCADE", "n": "SET NULL", "d": "SET DEFAULT",
+}
+
+// Indexes возвращает индексы таблиц пользователя. Требуется PostgreSQL 11
+// и новее: ключевые столбцы отделяются от INCLUDE по pg_index.indnkeyatts.
+// Столбцы берутся из pg_attribute по indkey, а не из pg_get_indexdef: там
+// имена в кавычках ("userId") и не совпадают с именами из других запросов.
+func (c *Client) Indexes(ctx context.Context) ([]Index, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT n.nspname, i.relname, t.relname, am.amname,
+		       COALESCE(pg_get_expr(ix.indpred, ix.indrelid), ''),
+		       ix.indisunique, ix.indisprimary, ix.indisvalid,
+		       pg_relation_size(i.oid), COALESCE(s.idx_scan, 0), pg_get_indexdef(i.oid),
+		       ARRAY(SELECT CASE WHEN ix.indkey[k] = 0 THEN '(' || pg_get_indexdef(i.oid, k + 1, true) || ')' ELSE a.attname::text END
+		             FROM generate_series(0, ix.indnkeyatts - 1) AS k
+		             LEFT JOIN pg_attribute a ON a.attrelid = ix.indrelid AND a.attnum = ix.indkey[k]
+		             ORDER BY k),
+		       ARRAY(SELECT a.attname::text
+		             FROM generate_series(ix.indnkeyatts, ix.indnatts - 1) AS k
+		             JOIN pg_attribute a ON a.attrelid = ix.indrelid AND a.attnum = ix.indkey[k]
+		             ORDER BY k),
+		       ARRAY(SELECT con.conname FROM pg_constraint con WHERE con.conindid = i.oid ORDER BY con.conname)
+		FROM pg_index ix
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_namespace n ON n.oid = i.relnamespace
+		JOIN pg_am am ON am.oid = i.relam
+		LEFT JOIN pg_stat_user_indexes s ON s.indexrelid = i.oid
+		WHERE `+userTables+`
+		ORDER BY t.relname, i.relname`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения индексов: %v", err)
+	}
+	defer rows.Close()
+
+	var indexes []Index
+	for rows.Next() {
+		var index Index
+		if err := rows.Scan(&index.Schema, &index.Name, &index.Table, &index.Method, &index.Predicate,
+			&index.Unique, &index.Primary, &index.Valid, &index.SizeBytes, &index.Scans, &index.Definition,
+			pq.Array(&index.Columns), pq.Array(&index.Include), pq.Array(&index.Constraints)); err != nil {
+			return nil, fmt.Errorf("ошибка чтения индексов: %v", err)
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, rows.Err()
+}
+
+// IsExpression сообщает, что ключ индекса из Index.Columns — выражение,
+// а не столбец
+func IsExpression(key string) bool {
+	return strings.HasPrefix(key, "(")
+}
+
+// ForeignKeys возвращает внешние ключи таблиц пользователя; столбцы
+// перечисляются в порядке объявления ключа
+func (c *Client) ForeignKeys(ctx context.Context) ([]ForeignKey, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT n.nspname, con.conname, t.relname, rn.nspname, r.relname,
+		       ARRAY(SELECT a.attname FROM unnest(con.conkey) WITH ORDINALITY AS k(attnum, ord)
+		             JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum ORDER BY k.ord),
+		       ARRAY(SELECT a.attname FROM unnest(con.confkey) WITH ORDINALITY AS k(attnum, ord)
+		             JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum ORDER BY k.ord),
+		       con.confdeltype, con.confupdtype
+		FROM pg_constraint con
+		JOIN pg_class t ON t.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		JOIN pg_class r ON r.oid = con.confrelid
+		JOIN pg_namespace rn ON rn.oid = r.relnamespace
+		WHERE con.contype = 'f' AND `+userTables+`
+		ORDER BY t.relname, con.conname`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения внешних ключей: %v", err)
+	}
+	defer rows.Close()
+
+	var keys []ForeignKey
+	for rows.Next() {
+		var key ForeignKey
+		var onDelete, onUpdate string
+		if err := rows.Scan(&key.Schema, &key.Name, &key.Table, &key.ReferencedSchema, &key.ReferencedTable,
+			pq.Array(&key.Columns), pq.Array(&key.ReferencedColumns), &onDelete, &onUpdate); err != nil {
+			return nil, fmt.Errorf("ошибка чтения внешних ключей: %v", err)
+		}
+		key.OnDelete, key.OnUpdate = foreignKeyActions[onDelete], foreignKeyActions[onUpdate]
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// StatsReset возвращает время последнего сброса статистики текущей базы:
+// счетчики использования индексов накоплены с этого момента. Если
+// статистика не сбрасывалась, возвращается nil.
+func (c *Client) StatsReset(ctx context.Context) (*time.Time, error) {
+	var reset sql.NullTime
+	err := c.db.QueryRowContext(ctx,
+		"SELECT stats_reset FROM pg_stat_database WHERE datname = current_database()").Scan(&reset)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения pg_stat_database: %v", err)
+	}
+	return nullTime(reset), nil
+}
+
+// nullTime переводит NULL в nil
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// Tables возвращает таблицы пользователя с размерами, счетчиками
+// изменений из pg_stat_user_tables и шириной столбцов из pg_stats
+func (c *Client) Tables(ctx context.Context) ([]Table, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT n.nspname, t.relname, t.relkind, GREATEST(t.reltuples, 0)::bigint, t.relpages, t.relallvisible, pg_table_size(t.oid),
+		       COALESCE(s.n_tup_ins, 0), COALESCE(s.n_tup_upd, 0),
+		       COALESCE(s.n_tup_hot_upd, 0), COALESCE(s.n_tup_del, 0),
+		       COALESCE(s.seq_scan, 0), COALESCE(s.seq_tup_read, 0), COALESCE(s.idx_scan, 0),
+		       COALESCE(s.n_live_tup, 0), COALESCE(s.n_dead_tup, 0),
+		       s.last_vacuum, s.last_autovacuum, s.last_analyze, s.last_autoanalyze,
+		       EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conrelid = t.oid AND con.contype = 'p')
+		FROM pg_class t
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		LEFT JOIN pg_stat_user_tables s ON s.relid = t.oid
+		WHERE `+userTables+`
+		ORDER BY t.relname`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения таблиц: %v", err)
+	}
+	defer rows.Close()
+
+	var tables []Table
+	byName := make(map[string]int)
+	for rows.Next() {
+		var table Table
+		var vacuum, autovacuum, analyze, autoanalyze sql.NullTime
+		if err := rows.Scan(&table.Schema, &table.Name, &table.Kind, &table.Rows, &table.Pages, &table.AllVisible, &table.SizeBytes,
+			&table.Inserts, &table.Updates, &table.HotUpdates, &table.Deletes,
+			&table.SeqScans, &table.SeqTupRead, &table.IdxScans, &table.LiveTuples, &table.DeadTuples,
+			&vacuum, &autovacuum, &analyze, &autoanalyze, &table.HasPrimaryKey); err != nil {
+			return nil, fmt.Errorf("ошибка чтения таблиц: %v", err)
+		}
+		table.LastVacuum, table.LastAutovacuum = nullTime(vacuum), nullTime(autovacuum)
+		table.LastAnalyze, table.LastAutoanalyze = nullTime(analyze), nullTime(autoanalyze)
+		byName[table.Schema+"."+table.Name] = len(tables)
+		tables = append(tables, table)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения таблиц: %v", err)
+	}
+
+	widths, err := c.db.QueryContext(ctx, `
+		SELECT s.schemaname, s.tablename, s.attname, s.avg_width
+		FROM pg_stats s
+		JOIN pg_namespace n ON n.nspname = s.schemaname
+		JOIN pg_class t ON t.relname = s.tablename AND t.relnamespace = n.oid
+		WHERE `+userTables)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения pg_stats: %v", err)
+	}
+	defer widths.Close()
+
+	for widths.Next() {
+		var schema, table, column string
+		var width int
+		if err := widths.Scan(&schema, &table, &column, &width); err != nil {
+			return nil, fmt.Errorf("ошибка чтения pg_stats: %v", err)
+		}
+		if i, ok := byName[schema+"."+table]; ok {
+			if tables[i].ColumnWidths == nil {
+				tables[i].ColumnWidths = make(map[string]int)
+			}
+			tables[i].ColumnWidths[column] = width
+		}
+	}
+	return tables, widths.Err()
+}
+
+// SizePretty выводит размер как pg_size_pretty
+func SizePretty(n int64) string {
+	units := []string{"bytes", "kB", "MB", "GB", "TB"}
+	value, unit := float64(n), 0
+	for value >= 10*1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.0f %s", value, units[unit])
+}
@@ -4,8 +4,15 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
 
 	_ "github.com/lib/pq" // PostgreSQL driver
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/render"
 )
 
 type Client struct {
@@ -34,15 +41,87 @@ func (c *Client) Close() error {
 func (c *Client) GetExplainPlan(ctx context.Context, query string) (string, error) {
 	var planJSON string
 
-	explainQuery := fmt.Sprintf("EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) %s", query)
+	explainQuery := fmt.Sprintf("EXPLAIN (ANALYZE, BUFFERS, VERBOSE, FORMAT JSON) %s", query)
 
-	fmt.Printf("Выполняем: %s\n", explainQuery)
+	log.Printf("Выполняем: %s", explainQuery)
 
 	err := c.db.QueryRowContext(ctx, explainQuery).Scan(&planJSON)
 	if err != nil {
 		return "", fmt.Errorf("ошибка выполнения EXPLAIN: %v", err)
 	}
 
-	fmt.Printf("Получен JSON плана: %s\n", planJSON)
+	logPlan(planJSON)
 	return planJSON, nil
 }
+
+// GetExplainPlanSafe получает план в безопасном режиме: запрос выполняется
+// в транзакции только для чтения с ограничением statement_timeout, и
+// транзакция всегда откатывается. Изменяющие запросы завершатся ошибкой,
+// а не изменят данные, поэтому режим подходит для регулярных проверок
+// без участия человека.
+func (c *Client) GetExplainPlanSafe(ctx context.Context, query string, timeout time.Duration) (string, error) {
+	tx, err := c.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return "", fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	if timeout > 0 {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())); err != nil {
+			return "", fmt.Errorf("ошибка установки statement_timeout: %v", err)
+		}
+	}
+
+	var planJSON string
+	explainQuery := fmt.Sprintf("EXPLAIN (ANALYZE, BUFFERS, VERBOSE, FORMAT JSON) %s", query)
+	log.Printf("Выполняем в безопасном режиме: %s", explainQuery)
+	if err := tx.QueryRowContext(ctx, explainQuery).Scan(&planJSON); err != nil {
+		return "", fmt.Errorf("ошибка выполнения EXPLAIN: %v", err)
+	}
+
+	logPlan(planJSON)
+	return planJSON, nil
+}
+
+// DatabaseName извлекает из строки подключения хост, порт и имя БД в виде
+// "host:port/dbname" — под этим именем анализы сохраняются в истории.
+// Поддерживаются URL (postgres://...) и формат "ключ=значение".
+func DatabaseName(dsn string) string {
+	host, port, dbname := "localhost", "5432", ""
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		if u.Hostname() != "" {
+			host = u.Hostname()
+		}
+		if u.Port() != "" {
+			port = u.Port()
+		}
+		dbname = strings.TrimPrefix(u.Path, "/")
+	} else {
+		for _, field := range strings.Fields(dsn) {
+			key, value, _ := strings.Cut(field, "=")
+			value = strings.Trim(value, "'")
+			switch key {
+			case "host":
+				host = value
+			case "port":
+				port = value
+			case "dbname":
+				dbname = value
+			}
+		}
+	}
+	return fmt.Sprintf("%s:%s/%s", host, port, dbname)
+}
+
+// logPlan выводит полученный план в лог в виде текстового дерева
+func logPlan(planJSON string) {
+	planNodes, err := analyzer.ParseExplainJSON(planJSON)
+	if err != nil {
+		log.Printf("Получен план, который не удалось разобрать: %v", err)
+		return
+	}
+
+	var buf strings.Builder
+	render.Text(&buf, planNodes, render.TextOptions{})
+	log.Printf("Получен план:\n%s", buf.String())
+}
@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"sql-optimizer/internal/quote"
+)
+
+// plannerSetting — параметры, которые можно менять в эксперименте: они
+// влияют только на выбор и выполнение плана текущего сеанса
+var plannerSetting = regexp.MustCompile(`^(enable_[a-z_]+|jit(_[a-z_]+)?|work_mem|hash_mem_multiplier|` +
+	`(random|seq)_page_cost|cpu_[a-z_]+_cost|effective_cache_size|effective_io_concurrency|` +
+	`max_parallel_workers_per_gather|parallel_(setup|tuple)_cost|min_parallel_(table|index)_scan_size|` +
+	`(join|from)_collapse_limit|geqo(_threshold)?|plan_cache_mode|default_statistics_target)$`)
+
+// PlannerToggle — значение параметра планировщика в варианте эксперимента
+type PlannerToggle struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// PlanVariant — набор параметров, под которым план строится заново
+type PlanVariant struct {
+	Name     string          `json:"name"`
+	Settings []PlannerToggle `json:"settings"`
+}
+
+// ExperimentOptions — параметры эксперимента с планировщиком
+type ExperimentOptions struct {
+	Variants []PlanVariant
+	// Analyze — выполнять запрос (EXPLAIN ANALYZE), чтобы сравнить
+	// стоимость с фактическим временем
+	Analyze bool
+	// Timeout — statement_timeout каждого варианта
+	Timeout time.Duration
+}
+
+// ExperimentRun — план запроса, построенный в одном варианте
+type ExperimentRun struct {
+	Variant PlanVariant `json:"variant"`
+	Plan    string      `json:"plan,omitempty"` // EXPLAIN в формате JSON
+	Error   string      `json:"error,omitempty"`
+}
+
+// PlannerSettingAllowed сообщает, можно ли менять параметр в эксперименте
+func PlannerSettingAllowed(name string) bool {
+	return plannerSetting.MatchString(name)
+}
+
+// Experiment строит план запроса заново в каждом варианте. Все варианты
+// выполняются в одном сеансе, каждый — в своей транзакции только для
+// чтения, где параметры задаются через SET LOCAL; транзакция всегда
+// откатывается. Первым возвращается базовый вариант без изменений.
+// С Analyze запрос перед измерениями выполняется один раз вхолостую,
+// чтобы базовый вариант не проигрывал остальным из-за холодного кэша.
+// Ошибка варианта (например, таймаут) записывается в его результат
+// и не прерывает эксперимент.
+func (c *Client) Experiment(ctx context.Context, query string, opts ExperimentOptions) ([]ExperimentRun, error) {
+	for _, variant := range opts.Variants {
+		for _, s := range variant.Settings {
+			if !PlannerSettingAllowed(s.Name) {
+				return nil, fmt.Errorf("параметр %s нельзя менять в эксперименте", s.Name)
+			}
+		}
+	}
+
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подключения к БД: %v", err)
+	}
+	defer conn.Close()
+
+	options := "FORMAT JSON"
+	if opts.Analyze {
+		options = "ANALYZE, BUFFERS, FORMAT JSON"
+	}
+	explainQuery := fmt.Sprintf("EXPLAIN (%s) %s", options, query)
+	log.Printf("Эксперимент с планировщиком, вариантов: %d: %s", len(opts.Variants)+1, explainQuery)
+
+	baseline := PlanVariant{Name: "baseline", Settings: []PlannerToggle{}}
+	if opts.Analyze {
+		if _, err := explainVariant(ctx, conn, explainQuery, baseline, opts.Timeout); err != nil {
+			log.Printf("Прогрев перед экспериментом не удался: %v", err)
+		}
+	}
+
+	variants := append([]PlanVariant{baseline}, opts.Variants...)
+	runs := make([]ExperimentRun, 0, len(variants))
+	for _, variant := range variants {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		run := ExperimentRun{Variant: variant}
+		if run.Plan, err = explainVariant(ctx, conn, explainQuery, variant, opts.Timeout); err != nil {
+			run.Error = err.Error()
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// explainVariant выполняет EXPLAIN в транзакции с параметрами варианта
+func explainVariant(ctx context.Context, conn *sql.Conn, explainQuery string, variant PlanVariant, timeout time.Duration) (string, error) {
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return "", fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	if timeout > 0 {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())); err != nil {
+			return "", fmt.Errorf("ошибка установки statement_timeout: %v", err)
+		}
+	}
+	for _, s := range variant.Settings {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL %s = %s", s.Name, quote.Literal(s.Value))); err != nil {
+			return "", fmt.Errorf("ошибка установки %s: %v", s.Name, err)
+		}
+	}
+
+	var planJSON string
+	if err := tx.QueryRowContext(ctx, explainQuery).Scan(&planJSON); err != nil {
+		return "", fmt.Errorf("ошибка выполнения EXPLAIN: %v", err)
+	}
+	return planJSON, nil
+}
@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DatabaseStats — статистика базы кластера из pg_database и pg_stat_database
+type DatabaseStats struct {
+	Name       string `json:"name"`
+	Current    bool   `json:"current"` // база, к которой подключен клиент
+	BlocksHit  int64  `json:"blocks_hit"`
+	BlocksRead int64  `json:"blocks_read"`
+	// FrozenXIDAge — возраст datfrozenxid: сколько транзакций назад база
+	// была полностью заморожена
+	FrozenXIDAge int64 `json:"frozen_xid_age"`
+}
+
+// Sequence — последовательность и столбец, которому она принадлежит
+type Sequence struct {
+	Schema    string `json:"schema"`
+	Name      string `json:"name"`
+	DataType  string `json:"data_type"`
+	LastValue *int64 `json:"last_value,omitempty"` // nil — ни разу не использовалась
+	MaxValue  int64  `json:"max_value"`
+	Increment int64  `json:"increment"`
+	// Столбец, которому принадлежит последовательность (serial, identity);
+	// пустой, если она создана отдельно
+	Table      string `json:"table,omitempty"`
+	Column     string `json:"column,omitempty"`
+	ColumnType string `json:"column_type,omitempty"`
+}
+
+// Transaction — открытая транзакция из pg_stat_activity
+type Transaction struct {
+	PID      int       `json:"pid"`
+	User     string    `json:"user"`
+	Database string    `json:"database"`
+	State    string    `json:"state"` // active, idle in transaction...
+	Started  time.Time `json:"started"`
+	Duration float64   `json:"duration"` // секунды
+	Query    string    `json:"query"`
+}
+
+// Setting — параметр сервера из pg_settings
+type Setting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"` // в единицах Unit
+	Unit   string `json:"unit,omitempty"`
+	Source string `json:"source"` // default, configuration file, override...
+	Boot   string `json:"boot_value"`
+}
+
+// DatabaseStats возвращает статистику всех баз кластера, к которым
+// разрешено подключение
+func (c *Client) DatabaseStats(ctx context.Context) ([]DatabaseStats, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT d.datname, d.datname = current_database(),
+		       COALESCE(s.blks_hit, 0), COALESCE(s.blks_read, 0), age(d.datfrozenxid)
+		FROM pg_database d
+		LEFT JOIN pg_stat_database s ON s.datid = d.oid
+		WHERE d.datallowconn
+		ORDER BY d.datname`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения pg_stat_database: %v", err)
+	}
+	defer rows.Close()
+
+	var databases []DatabaseStats
+	for rows.Next() {
+		var db DatabaseStats
+		if err := rows.Scan(&db.Name, &db.Current, &db.BlocksHit, &db.BlocksRead, &db.FrozenXIDAge); err != nil {
+			return nil, fmt.Errorf("ошибка чтения pg_stat_database: %v", err)
+		}
+		databases = append(databases, db)
+	}
+	return databases, rows.Err()
+}
+
+// Sequences возвращает последовательности пользователя вместе с типом
+// столбца, которому они принадлежат: serial на столбце integer исчерпается
+// раньше, чем сама последовательность типа bigint. Требуется PostgreSQL 10
+// и новее (pg_sequences).
+func (c *Client) Sequences(ctx context.Context) ([]Sequence, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT s.schemaname, s.sequencename, s.data_type::text, s.last_value, s.max_value, s.increment_by,
+		       COALESCE(t.relname, ''), COALESCE(a.attname, ''), COALESCE(format_type(a.atttypid, a.atttypmod), '')
+		FROM pg_sequences s
+		JOIN pg_namespace n ON n.nspname = s.schemaname
+		JOIN pg_class seq ON seq.relname = s.sequencename AND seq.relnamespace = n.oid
+		LEFT JOIN pg_depend d ON d.objid = seq.oid AND d.classid = 'pg_class'::regclass
+		     AND d.refclassid = 'pg_class'::regclass AND d.deptype IN ('a', 'i')
+		LEFT JOIN pg_class t ON t.oid = d.refobjid
+		LEFT JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+		WHERE s.schemaname NOT IN ('pg_catalog', 'information_schema')
+		ORDER BY s.schemaname, s.sequencename`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения последовательностей: %v", err)
+	}
+	defer rows.Close()
+
+	var sequences []Sequence
+	for rows.Next() {
+		var seq Sequence
+		var last sql.NullInt64
+		if err := rows.Scan(&seq.Schema, &seq.Name, &seq.DataType, &last, &seq.MaxValue, &seq.Increment,
+			&seq.Table, &seq.Column, &seq.ColumnType); err != nil {
+			return nil, fmt.Errorf("ошибка чтения последовательностей: %v", err)
+		}
+		if last.Valid {
+			seq.LastValue = &last.Int64
+		}
+		sequences = append(sequences, seq)
+	}
+	return sequences, rows.Err()
+}
+
+// LongTransactions возвращает транзакции, открытые дольше minDuration,
+// кроме транзакции самого клиента; самые старые — первыми
+func (c *Client) LongTransactions(ctx context.Context, minDuration time.Duration) ([]Transaction, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT pid, COALESCE(usename, ''), COALESCE(datname, ''), COALESCE(state, ''), xact_start,
+		       EXTRACT(EPOCH FROM now() - xact_start)::float8, COALESCE(query, '')
+		FROM pg_stat_activity
+		WHERE xact_start IS NOT NULL AND pid <> pg_backend_pid()
+		  AND now() - xact_start > make_interval(secs => $1)
+		ORDER BY xact_start`, minDuration.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения pg_stat_activity: %v", err)
+	}
+	defer rows.Close()
+
+	var transactions []Transaction
+	for rows.Next() {
+		var tx Transaction
+		if err := rows.Scan(&tx.PID, &tx.User, &tx.Database, &tx.State, &tx.Started, &tx.Duration, &tx.Query); err != nil {
+			return nil, fmt.Errorf("ошибка чтения pg_stat_activity: %v", err)
+		}
+		transactions = append(transactions, tx)
+	}
+	return transactions, rows.Err()
+}
+
+// Settings возвращает параметры сервера по имени
+func (c *Client) Settings(ctx context.Context) (map[string]Setting, error) {
+	rows, err := c.db.QueryContext(ctx,
+		"SELECT name, setting, COALESCE(unit, ''), source, COALESCE(boot_val, '') FROM pg_settings")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения pg_settings: %v", err)
+	}
+	defer rows.Close()
+
+	settings := make(map[string]Setting)
+	for rows.Next() {
+		var s Setting
+		if err := rows.Scan(&s.Name, &s.Value, &s.Unit, &s.Source, &s.Boot); err != nil {
+			return nil, fmt.Errorf("ошибка чтения pg_settings: %v", err)
+		}
+		settings[s.Name] = s
+	}
+	return settings, rows.Err()
+}
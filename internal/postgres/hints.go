@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// ErrNoHintPlan возвращается, если расширение pg_hint_plan недоступно
+// на сервере
+var ErrNoHintPlan = errors.New("расширение pg_hint_plan недоступно: установите его или добавьте в shared_preload_libraries")
+
+// ExplainHinted строит план запроса с подсказками pg_hint_plan без его
+// выполнения. Если библиотека не загружена заранее, она загружается
+// в сеансе командой LOAD; EXPLAIN выполняется в транзакции только для
+// чтения, которая откатывается.
+func (c *Client) ExplainHinted(ctx context.Context, query, hints string) (string, error) {
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return "", fmt.Errorf("ошибка подключения к БД: %v", err)
+	}
+	defer conn.Close()
+
+	var enabled sql.NullString
+	if err := conn.QueryRowContext(ctx, "SELECT current_setting('pg_hint_plan.enable_hint', true)").Scan(&enabled); err != nil {
+		return "", fmt.Errorf("ошибка проверки pg_hint_plan: %v", err)
+	}
+	if enabled.String == "" {
+		if _, err := conn.ExecContext(ctx, "LOAD 'pg_hint_plan'"); err != nil {
+			log.Printf("Не удалось загрузить pg_hint_plan: %v", err)
+			return "", ErrNoHintPlan
+		}
+	}
+
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return "", fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	// pg_hint_plan читает подсказки из первого комментария текста запроса,
+	// поэтому блок идет перед EXPLAIN
+	explainQuery := fmt.Sprintf("%s\nEXPLAIN (FORMAT JSON) %s", hints, query)
+	log.Printf("Выполняем с подсказками: %s", explainQuery)
+	var planJSON string
+	if err := tx.QueryRowContext(ctx, explainQuery).Scan(&planJSON); err != nil {
+		return "", fmt.Errorf("ошибка выполнения EXPLAIN: %v", err)
+	}
+	return planJSON, nil
+}
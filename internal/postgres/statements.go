@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNoStatStatements возвращается, если расширение pg_stat_statements
+// не установлено в базе
+var ErrNoStatStatements = errors.New("расширение pg_stat_statements не установлено: выполните CREATE EXTENSION pg_stat_statements")
+
+// StatStatement — накопленная статистика одного оператора из pg_stat_statements
+type StatStatement struct {
+	QueryID        int64   `json:"queryid,string"` // строкой: JavaScript теряет точность на int64
+	Query          string  `json:"query"`
+	Calls          int64   `json:"calls"`
+	TotalTime      float64 `json:"total_time"` // мс
+	MeanTime       float64 `json:"mean_time"`  // мс
+	Rows           int64   `json:"rows"`
+	SharedBlksHit  int64   `json:"shared_blks_hit"`
+	SharedBlksRead int64   `json:"shared_blks_read"`
+}
+
+// ServerVersion возвращает номер версии сервера, например 160002
+func (c *Client) ServerVersion(ctx context.Context) (int, error) {
+	var version int
+	err := c.db.QueryRowContext(ctx, "SELECT current_setting('server_version_num')::int").Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения версии сервера: %v", err)
+	}
+	return version, nil
+}
+
+// StatStatements читает статистику операторов текущей базы. Начиная с
+// PostgreSQL 13 столбцы времени называются total_exec_time и mean_exec_time.
+func (c *Client) StatStatements(ctx context.Context) ([]StatStatement, error) {
+	var installed bool
+	err := c.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_stat_statements')").Scan(&installed)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка проверки расширения pg_stat_statements: %v", err)
+	}
+	if !installed {
+		return nil, ErrNoStatStatements
+	}
+
+	version, err := c.ServerVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	totalColumn, meanColumn := "total_exec_time", "mean_exec_time"
+	if version < 130000 {
+		totalColumn, meanColumn = "total_time", "mean_time"
+	}
+
+	rows, err := c.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT queryid, query, calls, %s, %s, rows, shared_blks_hit, shared_blks_read
+		FROM pg_stat_statements
+		WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+		  AND queryid IS NOT NULL`, totalColumn, meanColumn))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения pg_stat_statements: %v", err)
+	}
+	defer rows.Close()
+
+	var statements []StatStatement
+	for rows.Next() {
+		var s StatStatement
+		if err := rows.Scan(&s.QueryID, &s.Query, &s.Calls, &s.TotalTime, &s.MeanTime,
+			&s.Rows, &s.SharedBlksHit, &s.SharedBlksRead); err != nil {
+			return nil, fmt.Errorf("ошибка чтения pg_stat_statements: %v", err)
+		}
+		statements = append(statements, s)
+	}
+	return statements, rows.Err()
+}
+
+// GetEstimatedPlan получает план без выполнения запроса (EXPLAIN без
+// ANALYZE). С generic = true используется GENERIC_PLAN из PostgreSQL 16,
+// который строит план для запросов с параметрами $1, $2... — именно так
+// тексты хранятся в pg_stat_statements.
+func (c *Client) GetEstimatedPlan(ctx context.Context, query string, generic bool) (string, error) {
+	options := "FORMAT JSON"
+	if generic {
+		options = "GENERIC_PLAN, FORMAT JSON"
+	}
+
+	var planJSON string
+	if err := c.db.QueryRowContext(ctx, fmt.Sprintf("EXPLAIN (%s) %s", options, query)).Scan(&planJSON); err != nil {
+		return "", fmt.Errorf("ошибка выполнения EXPLAIN: %v", err)
+	}
+	return planJSON, nil
+}
@@ -0,0 +1,211 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"sql-optimizer/internal/quote"
+)
+
+// ColumnStats — статистика столбца из pg_stats
+type ColumnStats struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	// NDistinct — n_distinct как в pg_stats: отрицательное значение —
+	// доля от числа строк
+	NDistinct float64 `json:"n_distinct"`
+	// Distinct — оценка числа различных значений в штуках
+	Distinct    float64   `json:"distinct"`
+	NullFrac    float64   `json:"null_frac"`
+	Width       int       `json:"avg_width"`
+	Correlation *float64  `json:"correlation,omitempty"`
+	CommonVals  []string  `json:"most_common_vals,omitempty"`
+	CommonFreqs []float64 `json:"most_common_freqs,omitempty"`
+	// Rows — оценка числа строк таблицы из pg_class.reltuples
+	Rows float64 `json:"rows"`
+}
+
+// ExtendedStatistic — объект расширенной статистики из pg_statistic_ext
+type ExtendedStatistic struct {
+	Schema  string   `json:"schema"`
+	Name    string   `json:"name"`
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	// Kinds — виды статистики: d (dependencies), f (ndistinct), m (mcv), e
+	Kinds []string `json:"kinds"`
+}
+
+// AnalyzeState — когда собиралась статистика таблицы и сколько строк
+// изменилось с тех пор, из pg_stat_user_tables
+type AnalyzeState struct {
+	Table                string     `json:"table"`
+	LiveTuples           int64      `json:"n_live_tup"`
+	ModifiedSinceAnalyze int64      `json:"n_mod_since_analyze"`
+	LastAnalyze          *time.Time `json:"last_analyze,omitempty"`
+	LastAutoanalyze      *time.Time `json:"last_autoanalyze,omitempty"`
+}
+
+// AnalyzeStates возвращает состояние статистики таблиц tables
+func (c *Client) AnalyzeStates(ctx context.Context, tables []string) ([]AnalyzeState, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT s.relname, s.n_live_tup, s.n_mod_since_analyze, s.last_analyze, s.last_autoanalyze
+		FROM pg_stat_user_tables s
+		JOIN pg_class t ON t.oid = s.relid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE s.relname = ANY($1) AND `+visibleTables+`
+		ORDER BY s.relname`, pq.Array(tables))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения pg_stat_user_tables: %v", err)
+	}
+	defer rows.Close()
+
+	var states []AnalyzeState
+	for rows.Next() {
+		var state AnalyzeState
+		var analyze, autoanalyze sql.NullTime
+		if err := rows.Scan(&state.Table, &state.LiveTuples, &state.ModifiedSinceAnalyze, &analyze, &autoanalyze); err != nil {
+			return nil, fmt.Errorf("ошибка чтения pg_stat_user_tables: %v", err)
+		}
+		state.LastAnalyze, state.LastAutoanalyze = nullTime(analyze), nullTime(autoanalyze)
+		states = append(states, state)
+	}
+	return states, rows.Err()
+}
+
+// ColumnStats возвращает статистику столбцов таблицы table; столбцы без
+// статистики (ANALYZE еще не выполнялся) пропускаются
+func (c *Client) ColumnStats(ctx context.Context, table string, columns []string) ([]ColumnStats, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (s.attname) s.tablename, s.attname, s.n_distinct,
+		       CASE WHEN s.n_distinct < 0 THEN -s.n_distinct * GREATEST(t.reltuples, 0) ELSE s.n_distinct END,
+		       s.null_frac, s.avg_width, s.correlation,
+		       COALESCE(s.most_common_vals::text::text[], '{}'), COALESCE(s.most_common_freqs, '{}'),
+		       GREATEST(t.reltuples, 0)
+		FROM pg_stats s
+		JOIN pg_namespace n ON n.nspname = s.schemaname
+		JOIN pg_class t ON t.relname = s.tablename AND t.relnamespace = n.oid
+		WHERE s.tablename = $1 AND s.attname = ANY($2) AND `+visibleTables+`
+		ORDER BY s.attname, s.inherited`, table, pq.Array(columns))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения pg_stats: %v", err)
+	}
+	defer rows.Close()
+
+	var stats []ColumnStats
+	for rows.Next() {
+		var s ColumnStats
+		var correlation sql.NullFloat64
+		if err := rows.Scan(&s.Table, &s.Column, &s.NDistinct, &s.Distinct, &s.NullFrac, &s.Width, &correlation,
+			pq.Array(&s.CommonVals), pq.Array(&s.CommonFreqs), &s.Rows); err != nil {
+			return nil, fmt.Errorf("ошибка чтения pg_stats: %v", err)
+		}
+		if correlation.Valid {
+			s.Correlation = &correlation.Float64
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+// ExtendedStatistics возвращает объекты расширенной статистики таблиц
+// пользователя
+func (c *Client) ExtendedStatistics(ctx context.Context) ([]ExtendedStatistic, error) {
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT sn.nspname, s.stxname, t.relname,
+		       ARRAY(SELECT a.attname FROM unnest(s.stxkeys) WITH ORDINALITY k(attnum, ord)
+		             JOIN pg_attribute a ON a.attrelid = s.stxrelid AND a.attnum = k.attnum
+		             ORDER BY k.ord),
+		       s.stxkind::text[]
+		FROM pg_statistic_ext s
+		JOIN pg_class t ON t.oid = s.stxrelid
+		JOIN pg_namespace sn ON sn.oid = s.stxnamespace
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE `+visibleTables+`
+		ORDER BY t.relname, s.stxname`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения pg_statistic_ext: %v", err)
+	}
+	defer rows.Close()
+
+	var stats []ExtendedStatistic
+	for rows.Next() {
+		var s ExtendedStatistic
+		if err := rows.Scan(&s.Schema, &s.Name, &s.Table, pq.Array(&s.Columns), pq.Array(&s.Kinds)); err != nil {
+			return nil, fmt.Errorf("ошибка чтения pg_statistic_ext: %v", err)
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+// AnalyzeTarget — таблица и столбцы, статистику которых собирает ANALYZE
+// при проверке рекомендаций; пустой Columns — вся таблица
+type AnalyzeTarget struct {
+	Table   string
+	Columns []string
+}
+
+// Command возвращает команду ANALYZE для цели
+func (t AnalyzeTarget) Command() string {
+	command := "ANALYZE " + quote.Ident(t.Table)
+	if len(t.Columns) > 0 {
+		columns := make([]string, len(t.Columns))
+		for i, column := range t.Columns {
+			columns[i] = quote.Ident(column)
+		}
+		command += " (" + strings.Join(columns, ", ") + ")"
+	}
+	return command
+}
+
+// ExplainWithStatistics выполняет команды ddl (например, CREATE STATISTICS),
+// собирает статистику целей analyze командой ANALYZE и строит план запроса
+// без выполнения; ddl может быть пустым, если нужно только проверить,
+// исправит ли оценку свежая статистика. Все происходит в одной транзакции,
+// которая затем откатывается: созданные объекты и строки pg_statistic не
+// сохраняются. Откат не отменяет того, что ANALYZE записывает в обход
+// транзакции: last_analyze, analyze_count и n_live_tup в
+// pg_stat_user_tables, reltuples и relpages в pg_class. ANALYZE всей
+// таблицы вдобавок обнуляет n_mod_since_analyze, и автоочистка и проверка
+// устаревания сочтут статистику свежей, поэтому в цели стоит перечислять
+// только нужные столбцы: со списком столбцов счетчик изменений сохраняется.
+func (c *Client) ExplainWithStatistics(ctx context.Context, query string, ddl []string, analyze []AnalyzeTarget, timeout time.Duration) (string, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	if timeout > 0 {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())); err != nil {
+			return "", fmt.Errorf("ошибка установки statement_timeout: %v", err)
+		}
+	}
+	// блокировки таблиц не должны задерживать рабочую нагрузку
+	if _, err := tx.ExecContext(ctx, "SET LOCAL lock_timeout = 1000"); err != nil {
+		return "", fmt.Errorf("ошибка установки lock_timeout: %v", err)
+	}
+	for _, command := range ddl {
+		log.Printf("Выполняем в откатываемой транзакции: %s", command)
+		if _, err := tx.ExecContext(ctx, command); err != nil {
+			return "", fmt.Errorf("ошибка выполнения %q: %v", command, err)
+		}
+	}
+	for _, target := range analyze {
+		if _, err := tx.ExecContext(ctx, target.Command()); err != nil {
+			return "", fmt.Errorf("ошибка ANALYZE %s: %v", target.Table, err)
+		}
+	}
+
+	var planJSON string
+	if err := tx.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+query).Scan(&planJSON); err != nil {
+		return "", fmt.Errorf("ошибка выполнения EXPLAIN: %v", err)
+	}
+	return planJSON, nil
+}
@@ -0,0 +1,58 @@
+// Package quote записывает идентификаторы и литералы для текста команд SQL,
+// которые предлагаются пользователю или выполняются при проверках.
+package quote
+
+import "strings"
+
+// reserved — зарезервированные ключевые слова PostgreSQL, которые нельзя
+// использовать как имя таблицы или столбца без кавычек (категории
+// reserved и reserved (can be function or type) из приложения C
+// документации)
+var reserved = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true,
+	"array": true, "as": true, "asc": true, "asymmetric": true,
+	"authorization": true, "binary": true, "both": true, "case": true,
+	"cast": true, "check": true, "collate": true, "collation": true,
+	"column": true, "concurrently": true, "constraint": true, "create": true,
+	"cross": true, "current_catalog": true, "current_date": true,
+	"current_role": true, "current_schema": true, "current_time": true,
+	"current_timestamp": true, "current_user": true, "default": true,
+	"deferrable": true, "desc": true, "distinct": true, "do": true,
+	"else": true, "end": true, "except": true, "false": true, "fetch": true,
+	"for": true, "foreign": true, "freeze": true, "from": true, "full": true,
+	"grant": true, "group": true, "having": true, "ilike": true, "in": true,
+	"initially": true, "inner": true, "intersect": true, "into": true,
+	"is": true, "isnull": true, "join": true, "lateral": true, "leading": true,
+	"left": true, "like": true, "limit": true, "localtime": true,
+	"localtimestamp": true, "natural": true, "not": true, "notnull": true,
+	"null": true, "offset": true, "on": true, "only": true, "or": true,
+	"order": true, "outer": true, "overlaps": true, "placing": true,
+	"primary": true, "references": true, "returning": true, "right": true,
+	"select": true, "session_user": true, "similar": true, "some": true,
+	"symmetric": true, "system_user": true, "table": true, "tablesample": true,
+	"then": true, "to": true, "trailing": true, "true": true, "union": true,
+	"unique": true, "user": true, "using": true, "variadic": true,
+	"verbose": true, "when": true, "where": true, "window": true, "with": true,
+}
+
+// Ident заключает идентификатор в кавычки, если без них PostgreSQL
+// прочитает его иначе: в имени есть заглавные буквы или другие символы
+// или оно совпадает с зарезервированным словом
+func Ident(name string) string {
+	simple := name != "" && !(name[0] >= '0' && name[0] <= '9') && !reserved[name]
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_') {
+			simple = false
+			break
+		}
+	}
+	if simple {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// Literal записывает строку как строковый литерал SQL
+func Literal(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,28 @@
+package quote
+
+import "testing"
+
+func TestIdent(t *testing.T) {
+	tests := map[string]string{
+		"orders":      "orders",
+		"user_id2":    "user_id2",
+		"user":        `"user"`,
+		"order":       `"order"`,
+		"Orders":      `"Orders"`,
+		"order items": `"order items"`,
+		"2fa":         `"2fa"`,
+		`say"hi`:      `"say""hi"`,
+		"":            `""`,
+	}
+	for name, want := range tests {
+		if got := Ident(name); got != want {
+			t.Errorf("Ident(%q) = %s, ожидалось %s", name, got, want)
+		}
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	if got := Literal("O'Brien"); got != "'O''Brien'" {
+		t.Errorf("Literal = %s", got)
+	}
+}
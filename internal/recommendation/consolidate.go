@@ -0,0 +1,218 @@
+package recommendation
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"sql-optimizer/internal/analyzer"
+)
+
+// Параметры оценки размера B-tree индекса
+const (
+	indexTupleOverhead = 12  // заголовок индексного кортежа и указатель на него, байт
+	defaultColumnWidth = 8   // ширина столбца, если статистики нет
+	indexFillFactor    = 0.9 // заполнение листовых страниц B-tree по умолчанию
+)
+
+// IndexCandidate — индекс, предложенный для одного или нескольких запросов
+type IndexCandidate struct {
+	Table   string
+	Columns []string // ключевые столбцы в порядке индекса
+	Include []string // неключевые столбцы INCLUDE
+	Queries []string // идентификаторы запросов, которым нужен индекс
+	Weight  float64  // польза, например доля времени этих запросов
+}
+
+// ExistingIndex — индекс, который уже есть в базе
+type ExistingIndex struct {
+	Name    string
+	Table   string
+	Columns []string
+	Include []string
+}
+
+// TableStats — сведения о таблице для оценки стоимости индекса
+type TableStats struct {
+	Rows         int64
+	Writes       int64          // вставки и не-HOT обновления: столько записей получит индекс
+	ColumnWidths map[string]int // средняя ширина столбцов, байт
+}
+
+// ConsolidationInput — все предложения по нагрузке и сведения о базе
+type ConsolidationInput struct {
+	Candidates []IndexCandidate
+	Existing   []ExistingIndex
+	Tables     map[string]TableStats
+}
+
+// ConsolidatedIndex — индекс итогового набора
+type ConsolidatedIndex struct {
+	Name    string   `json:"name"`
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	Include []string `json:"include,omitempty"`
+	DDL     string   `json:"ddl"`
+	Queries []string `json:"queries"`
+	Weight  float64  `json:"weight"`
+	// EstimatedBytes — оценка размера индекса; 0, если число строк неизвестно
+	EstimatedBytes int64 `json:"estimated_bytes"`
+	// WriteOverhead — сколько записей в индекс добавят изменения таблицы
+	// за период накопленной статистики
+	WriteOverhead int64 `json:"write_overhead"`
+	// Merged — предложения, поглощенные этим индексом
+	Merged []string `json:"merged,omitempty"`
+}
+
+// CoveredCandidate — предложение, которое уже обслуживает существующий индекс
+type CoveredCandidate struct {
+	Candidate string   `json:"candidate"`
+	Index     string   `json:"index"`
+	Queries   []string `json:"queries"`
+}
+
+// Consolidation — минимальный набор индексов для всей нагрузки
+type Consolidation struct {
+	Indexes []ConsolidatedIndex `json:"indexes"`
+	Covered []CoveredCandidate  `json:"covered"`
+}
+
+// ConsolidateIndexes сводит предложения индексов по многим запросам
+// в минимальный набор: одинаковые предложения объединяются, уже покрытые
+// существующими индексами отбрасываются, а индекс, ключ которого является
+// префиксом ключа другого, поглощается более длинным — например, (user_id),
+// (user_id, status) и (user_id) INCLUDE (amount) сводятся к
+// (user_id, status) INCLUDE (amount). Набор упорядочен по убыванию пользы,
+// при равной пользе первыми идут меньшие индексы.
+func (e *Engine) ConsolidateIndexes(input ConsolidationInput) Consolidation {
+	result := Consolidation{Indexes: []ConsolidatedIndex{}, Covered: []CoveredCandidate{}}
+
+	// Длинные ключи обрабатываются первыми, чтобы короткие могли в них влиться
+	candidates := append([]IndexCandidate(nil), input.Candidates...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Table != candidates[j].Table {
+			return candidates[i].Table < candidates[j].Table
+		}
+		return len(candidates[i].Columns) > len(candidates[j].Columns)
+	})
+
+	var chosen []*ConsolidatedIndex
+	for _, c := range candidates {
+		if len(c.Columns) == 0 {
+			continue
+		}
+		if existing := coveringIndex(c.Table, c.Columns, c.Include, input.Existing); existing != "" {
+			result.Covered = append(result.Covered, CoveredCandidate{Candidate: describeIndex(c.Columns, c.Include), Index: existing, Queries: c.Queries})
+			continue
+		}
+
+		var target *ConsolidatedIndex
+		for _, index := range chosen {
+			if index.Table == c.Table && hasPrefix(index.Columns, c.Columns) {
+				target = index
+				break
+			}
+		}
+		if target == nil {
+			target = &ConsolidatedIndex{Table: c.Table, Columns: c.Columns}
+			chosen = append(chosen, target)
+		} else {
+			target.Merged = append(target.Merged, describeIndex(c.Columns, c.Include))
+		}
+		for _, column := range c.Include {
+			if !slices.Contains(target.Columns, column) && !slices.Contains(target.Include, column) {
+				target.Include = append(target.Include, column)
+			}
+		}
+		for _, query := range c.Queries {
+			if !slices.Contains(target.Queries, query) {
+				target.Queries = append(target.Queries, query)
+			}
+		}
+		target.Weight += c.Weight
+	}
+
+	// Слияние не может дать индекс, который уже есть в базе: сводный индекс
+	// покрыт существующим, только если тот покрывает и каждое предложение,
+	// а покрытые предложения отброшены до слияния
+	for _, index := range chosen {
+		candidate := analyzer.IndexCandidate{Table: index.Table, Columns: index.Columns, Include: index.Include}
+		index.Name, index.DDL = candidate.Name(), candidate.DDL()
+
+		if stats, ok := input.Tables[index.Table]; ok {
+			index.EstimatedBytes = EstimateIndexSize(stats, append(append([]string(nil), index.Columns...), index.Include...))
+			index.WriteOverhead = stats.Writes
+		}
+		if index.Queries == nil {
+			index.Queries = []string{}
+		}
+		result.Indexes = append(result.Indexes, *index)
+	}
+
+	sort.SliceStable(result.Indexes, func(i, j int) bool {
+		if result.Indexes[i].Weight != result.Indexes[j].Weight {
+			return result.Indexes[i].Weight > result.Indexes[j].Weight
+		}
+		return result.Indexes[i].EstimatedBytes < result.Indexes[j].EstimatedBytes
+	})
+	return result
+}
+
+// coveringIndex возвращает имя существующего индекса, ключ которого
+// начинается с columns и который содержит все столбцы include
+func coveringIndex(table string, columns, include []string, existing []ExistingIndex) string {
+	for _, index := range existing {
+		if index.Table != table || !hasPrefix(index.Columns, columns) {
+			continue
+		}
+		covered := true
+		for _, column := range include {
+			if !slices.Contains(index.Columns, column) && !slices.Contains(index.Include, column) {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return index.Name
+		}
+	}
+	return ""
+}
+
+// EstimateIndexSize оценивает размер B-tree индекса: строки таблицы,
+// умноженные на размер индексного кортежа с выравниванием до 8 байт,
+// с поправкой на заполнение страниц
+func EstimateIndexSize(stats TableStats, columns []string) int64 {
+	width := 0
+	for _, column := range columns {
+		if w, ok := stats.ColumnWidths[column]; ok && w > 0 {
+			width += w
+		} else {
+			width += defaultColumnWidth
+		}
+	}
+	tuple := indexTupleOverhead + (width+7)/8*8
+	return int64(float64(stats.Rows*int64(tuple)) / indexFillFactor)
+}
+
+// describeIndex записывает состав индекса в виде "(a, b) INCLUDE (c)"
+func describeIndex(columns, include []string) string {
+	s := "(" + strings.Join(columns, ", ") + ")"
+	if len(include) > 0 {
+		s += " INCLUDE (" + strings.Join(include, ", ") + ")"
+	}
+	return s
+}
+
+// hasPrefix сообщает, начинается ли columns с prefix
+func hasPrefix(columns, prefix []string) bool {
+	if len(prefix) > len(columns) {
+		return false
+	}
+	for i := range prefix {
+		if columns[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package recommendation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConsolidateIndexes(t *testing.T) {
+	engine := NewEngine()
+
+	input := ConsolidationInput{
+		Candidates: []IndexCandidate{
+			{Table: "orders", Columns: []string{"user_id"}, Queries: []string{"q1"}, Weight: 10},
+			{Table: "orders", Columns: []string{"user_id", "status"}, Queries: []string{"q2"}, Weight: 30},
+			{Table: "orders", Columns: []string{"user_id"}, Include: []string{"amount"}, Queries: []string{"q3", "q1"}, Weight: 5},
+			{Table: "users", Columns: []string{"email"}, Queries: []string{"q4"}, Weight: 50},
+			{Table: "payments", Columns: []string{"order_id"}, Queries: []string{"q5"}, Weight: 40},
+		},
+		Existing: []ExistingIndex{
+			{Name: "users_email_key", Table: "users", Columns: []string{"email"}},
+		},
+		Tables: map[string]TableStats{
+			"orders": {Rows: 1000000, Writes: 25000, ColumnWidths: map[string]int{"user_id": 4, "status": 7, "amount": 8}},
+		},
+	}
+
+	result := engine.ConsolidateIndexes(input)
+
+	if len(result.Indexes) != 2 {
+		t.Fatalf("Ожидали 2 индекса, получили %+v", result.Indexes)
+	}
+
+	orders, payments := result.Indexes[0], result.Indexes[1]
+	if payments.Table != "payments" || payments.EstimatedBytes != 0 {
+		t.Errorf("Вторым ожидали индекс payments без оценки размера: %+v", payments)
+	}
+
+	expected := ConsolidatedIndex{
+		Name:    "idx_orders_user_id_status",
+		Table:   "orders",
+		Columns: []string{"user_id", "status"},
+		Include: []string{"amount"},
+		DDL:     "CREATE INDEX CONCURRENTLY idx_orders_user_id_status ON orders (user_id, status) INCLUDE (amount);",
+		Queries: []string{"q2", "q1", "q3"},
+		Weight:  45,
+		// 12 байт заголовка + 4+7+8=19 байт, выровненные до 24, на 10^6 строк / 0.9
+		EstimatedBytes: 40000000,
+		WriteOverhead:  25000,
+		Merged:         []string{"(user_id)", "(user_id) INCLUDE (amount)"},
+	}
+	if !reflect.DeepEqual(orders, expected) {
+		t.Errorf("Неверный сводный индекс:\nожидали %+v\nполучили %+v", expected, orders)
+	}
+
+	if len(result.Covered) != 1 || result.Covered[0].Index != "users_email_key" || result.Covered[0].Candidate != "(email)" {
+		t.Errorf("Ожидали, что (email) покрыт users_email_key: %+v", result.Covered)
+	}
+}
+
+func TestConsolidateIndexesMergeMatchesExisting(t *testing.T) {
+	engine := NewEngine()
+
+	// Слияние дало бы (user_id, status) INCLUDE (amount) — существующий
+	// индекс. Он покрывает и каждое предложение по отдельности, поэтому
+	// все они отбрасываются до слияния.
+	result := engine.ConsolidateIndexes(ConsolidationInput{
+		Candidates: []IndexCandidate{
+			{Table: "orders", Columns: []string{"user_id"}, Queries: []string{"q1"}},
+			{Table: "orders", Columns: []string{"user_id", "status"}, Queries: []string{"q2"}},
+			{Table: "orders", Columns: []string{"user_id"}, Include: []string{"amount"}, Queries: []string{"q3"}},
+			{Table: "Order Items", Columns: []string{"OrderId"}, Queries: []string{"q4"}},
+		},
+		Existing: []ExistingIndex{
+			{Name: "orders_user_status_idx", Table: "orders", Columns: []string{"user_id", "status"}, Include: []string{"amount"}},
+		},
+	})
+
+	if len(result.Covered) != 3 {
+		t.Fatalf("Ожидали три покрытых предложения: %+v", result.Covered)
+	}
+	for _, covered := range result.Covered {
+		if covered.Index != "orders_user_status_idx" {
+			t.Errorf("Предложение %s покрыто не тем индексом: %s", covered.Candidate, covered.Index)
+		}
+	}
+	if len(result.Indexes) != 1 || result.Indexes[0].DDL != `CREATE INDEX CONCURRENTLY "idx_Order Items_OrderId" ON "Order Items" ("OrderId");` {
+		t.Errorf("Ожидали только индекс по Order Items с кавычками: %+v", result.Indexes)
+	}
+}
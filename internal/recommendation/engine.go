@@ -1,8 +1,7 @@
 package recommendation
 
 import (
-	"fmt"
-	//"strings"
+	"sql-optimizer/internal/i18n"
 )
 
 // Engine генерирует рекомендации на основе анализа
@@ -12,9 +11,10 @@ func NewEngine() *Engine {
 	return &Engine{}
 }
 
-// GenerateRecommendations генерирует рекомендации на основе проблемных операций
-func (e *Engine) GenerateRecommendations(result *AnalysisResult) []string {
-	var recommendations []string
+// GenerateRecommendations генерирует рекомендации на основе проблемных операций.
+// Тексты на нужном языке получаются через i18n.FormatAll.
+func (e *Engine) GenerateRecommendations(result *AnalysisResult) []i18n.Message {
+	var recommendations []i18n.Message
 
 	// Добавляем общие рекомендации
 	recommendations = append(recommendations, e.generateGeneralRecommendations(result)...)
@@ -29,23 +29,23 @@ func (e *Engine) GenerateRecommendations(result *AnalysisResult) []string {
 }
 
 // generateGeneralRecommendations общие рекомендации
-func (e *Engine) generateGeneralRecommendations(result *AnalysisResult) []string {
-	var recs []string
+func (e *Engine) generateGeneralRecommendations(result *AnalysisResult) []i18n.Message {
+	var recs []i18n.Message
 
 	if result.TotalCost > 10000 {
-		recs = append(recs, "Общая стоимость запроса очень высока. Рассмотрите рефакторинг запроса или добавление индексов.")
+		recs = append(recs, i18n.New(i18n.EngineHighTotalCost))
 	}
 
 	if result.TotalActualTime != nil && *result.TotalActualTime > 1000 {
-		recs = append(recs, "Общее время выполнения превышает 1 секунду. Оптимизация необходима.")
+		recs = append(recs, i18n.New(i18n.EngineSlowExecution))
 	}
 
 	return recs
 }
 
 // generateSpecificRecommendations специфические рекомендации для типов операций
-func (e *Engine) generateSpecificRecommendations(problem ProblematicOperation) []string {
-	var recs []string
+func (e *Engine) generateSpecificRecommendations(problem ProblematicOperation) []i18n.Message {
+	var recs []i18n.Message
 
 	switch problem.NodeType {
 	case "Seq Scan":
@@ -57,36 +57,36 @@ func (e *Engine) generateSpecificRecommendations(problem ProblematicOperation) [
 	case "Nested Loop":
 		recs = append(recs, e.handleNestedLoop(problem))
 	default:
-		recs = append(recs, fmt.Sprintf("Операция %s имеет высокую стоимость. Рассмотрите оптимизацию.", problem.NodeType))
+		recs = append(recs, i18n.New(i18n.EngineExpensiveOperation, "node_type", problem.NodeType))
 	}
 
 	return recs
 }
 
-func (e *Engine) handleSeqScan(problem ProblematicOperation) string {
-	return "Sequential Scan обнаружен. Добавьте индексы на поля, используемые в условиях фильтрации."
+func (e *Engine) handleSeqScan(problem ProblematicOperation) i18n.Message {
+	return i18n.New(i18n.EngineSeqScan)
 }
 
-func (e *Engine) handleSort(problem ProblematicOperation) string {
-	return "Обнаружена операция сортировки. Используйте индексы для предварительной сортировки данных."
+func (e *Engine) handleSort(problem ProblematicOperation) i18n.Message {
+	return i18n.New(i18n.EngineSort)
 }
 
-func (e *Engine) handleHashJoin(problem ProblematicOperation) string {
-	return "Hash Join обнаружен. Убедитесь, что обе таблицы имеют индексы на полях соединения."
+func (e *Engine) handleHashJoin(problem ProblematicOperation) i18n.Message {
+	return i18n.New(i18n.EngineHashJoin)
 }
 
-func (e *Engine) handleNestedLoop(problem ProblematicOperation) string {
-	return "Nested Loop обнаружен. Рассмотрите изменение условий соединения или добавление индексов."
+func (e *Engine) handleNestedLoop(problem ProblematicOperation) i18n.Message {
+	return i18n.New(i18n.EngineNestedLoop)
 }
 
 // removeDuplicates убирает дублирующиеся рекомендации
-func (e *Engine) removeDuplicates(recommendations []string) []string {
+func (e *Engine) removeDuplicates(recommendations []i18n.Message) []i18n.Message {
 	seen := make(map[string]bool)
-	var unique []string
+	var unique []i18n.Message
 
 	for _, rec := range recommendations {
-		if !seen[rec] {
-			seen[rec] = true
+		if !seen[rec.Key()] {
+			seen[rec.Key()] = true
 			unique = append(unique, rec)
 		}
 	}
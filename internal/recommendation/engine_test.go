@@ -4,6 +4,8 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+
+	"sql-optimizer/internal/i18n"
 )
 
 func TestGenerateRecommendations(t *testing.T) {
@@ -17,15 +19,15 @@ func TestGenerateRecommendations(t *testing.T) {
 	testCases := []struct {
 		name           string
 		analysisResult *AnalysisResult
-		expectedRecs   []string
+		expectedIDs    []string
 	}{
 		{
 			name: "Общая рекомендация по высокой стоимости",
 			analysisResult: &AnalysisResult{
 				TotalCost: 15000,
 			},
-			expectedRecs: []string{
-				"Общая стоимость запроса очень высока. Рассмотрите рефакторинг запроса или добавление индексов.",
+			expectedIDs: []string{
+				i18n.EngineHighTotalCost,
 			},
 		},
 		{
@@ -33,8 +35,8 @@ func TestGenerateRecommendations(t *testing.T) {
 			analysisResult: &AnalysisResult{
 				TotalActualTime: float64Ptr(1200),
 			},
-			expectedRecs: []string{
-				"Общее время выполнения превышает 1 секунду. Оптимизация необходима.",
+			expectedIDs: []string{
+				i18n.EngineSlowExecution,
 			},
 		},
 		{
@@ -44,8 +46,8 @@ func TestGenerateRecommendations(t *testing.T) {
 					{NodeType: "Seq Scan"},
 				},
 			},
-			expectedRecs: []string{
-				"Sequential Scan обнаружен. Добавьте индексы на поля, используемые в условиях фильтрации.",
+			expectedIDs: []string{
+				i18n.EngineSeqScan,
 			},
 		},
 		{
@@ -55,8 +57,8 @@ func TestGenerateRecommendations(t *testing.T) {
 					{NodeType: "Sort"},
 				},
 			},
-			expectedRecs: []string{
-				"Обнаружена операция сортировки. Используйте индексы для предварительной сортировки данных.",
+			expectedIDs: []string{
+				i18n.EngineSort,
 			},
 		},
 		{
@@ -67,9 +69,9 @@ func TestGenerateRecommendations(t *testing.T) {
 					{NodeType: "Nested Loop"},
 				},
 			},
-			expectedRecs: []string{
-				"Nested Loop обнаружен. Рассмотрите изменение условий соединения или добавление индексов.",
-				"Общая стоимость запроса очень высока. Рассмотрите рефакторинг запроса или добавление индексов.",
+			expectedIDs: []string{
+				i18n.EngineNestedLoop,
+				i18n.EngineHighTotalCost,
 			},
 		},
 		{
@@ -80,8 +82,8 @@ func TestGenerateRecommendations(t *testing.T) {
 					{NodeType: "Seq Scan"},
 				},
 			},
-			expectedRecs: []string{
-				"Sequential Scan обнаружен. Добавьте индексы на поля, используемые в условиях фильтрации.",
+			expectedIDs: []string{
+				i18n.EngineSeqScan,
 			},
 		},
 		{
@@ -91,22 +93,25 @@ func TestGenerateRecommendations(t *testing.T) {
 					{NodeType: "Unknown Operation"},
 				},
 			},
-			expectedRecs: []string{
-				"Операция Unknown Operation имеет высокую стоимость. Рассмотрите оптимизацию.",
+			expectedIDs: []string{
+				i18n.EngineExpensiveOperation,
 			},
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			recs := engine.GenerateRecommendations(tc.analysisResult)
+			var ids []string
+			for _, rec := range engine.GenerateRecommendations(tc.analysisResult) {
+				ids = append(ids, rec.ID)
+			}
 
 			// Сортируем оба слайса, так как порядок не гарантирован
-			sort.Strings(recs)
-			sort.Strings(tc.expectedRecs)
+			sort.Strings(ids)
+			sort.Strings(tc.expectedIDs)
 
-			if !reflect.DeepEqual(recs, tc.expectedRecs) {
-				t.Errorf("Рекомендации не соответствуют ожидаемым.\nОжидали: %v\nПолучили: %v", tc.expectedRecs, recs)
+			if !reflect.DeepEqual(ids, tc.expectedIDs) {
+				t.Errorf("Рекомендации не соответствуют ожидаемым.\nОжидали: %v\nПолучили: %v", tc.expectedIDs, ids)
 			}
 		})
 	}
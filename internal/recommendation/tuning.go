@@ -0,0 +1,340 @@
+package recommendation
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"sql-optimizer/internal/i18n"
+)
+
+// Хранилище данных сервера
+const (
+	StorageSSD = "ssd"
+	StorageHDD = "hdd"
+)
+
+// jitShortQuery — запрос быстрее этого (мс) не окупает JIT-компиляцию
+const jitShortQuery = 100
+
+// Hardware — сведения о сервере, которые сообщает пользователь; нулевые
+// поля означают, что сведений нет, и зависящие от них параметры не
+// рекомендуются
+type Hardware struct {
+	MemoryMB int64  `json:"memory_mb"`
+	CPUs     int    `json:"cpus"`
+	Storage  string `json:"storage"` // StorageSSD, StorageHDD или пусто
+}
+
+// Setting — значение параметра сервера в единицах Unit, как в pg_settings
+type Setting struct {
+	Value string
+	Unit  string // B, kB, 8kB, MB...
+}
+
+// PlanRoot — стоимость и фактическое время одного плана
+type PlanRoot struct {
+	TotalCost  float64
+	ActualTime *float64 // мс; nil для плана без ANALYZE
+}
+
+// PlanStats — что показали просмотренные планы
+type PlanStats struct {
+	Plans int `json:"plans"`
+	// DiskSorts и HashSpills — сортировки и хеш-таблицы, не поместившиеся в work_mem
+	DiskSorts  int `json:"disk_sorts"`
+	HashSpills int `json:"hash_spills"`
+	// MaxSpillBytes — оценка памяти, которой не хватило крупнейшему из них
+	MaxSpillBytes int64 `json:"max_spill_bytes"`
+	// ShortOfWorkers — узлы Gather, получившие меньше процессов, чем планировалось
+	ShortOfWorkers int        `json:"short_of_workers"`
+	Roots          []PlanRoot `json:"-"`
+}
+
+// TuningInput — параметры сервера, сведения о железе и статистика планов
+type TuningInput struct {
+	Settings map[string]Setting
+	Hardware Hardware
+	Plans    PlanStats
+}
+
+// SettingRecommendation — рекомендуемое значение параметра с обоснованием
+type SettingRecommendation struct {
+	Name        string       `json:"name"`
+	Current     string       `json:"current"`
+	Recommended string       `json:"recommended"`
+	Message     i18n.Message `json:"message"`
+	Rationale   string       `json:"rationale"`
+	// Command — как применить: ALTER SYSTEM для сервера или SET для сеанса
+	Command string `json:"command"`
+	Restart bool   `json:"restart"` // вступает в силу только после перезапуска
+}
+
+// TuningReport — рекомендации по параметрам сервера
+type TuningReport struct {
+	Hardware        Hardware                `json:"hardware"`
+	Plans           PlanStats               `json:"plans"`
+	Recommendations []SettingRecommendation `json:"recommendations"`
+}
+
+// TuneSettings рекомендует значения work_mem, shared_buffers,
+// effective_cache_size, random_page_cost, параметров параллельности и
+// порогов JIT. Память делится по общепринятым долям: четверть на
+// shared_buffers, три четверти — оценка кэша, work_mem — остаток на
+// max_connections соединений по три операции. Планы служат доказательствами:
+// сбросы на диск, недополученные параллельные процессы и короткие запросы
+// с JIT-компиляцией. Параметры, которые уже близки к рекомендуемым,
+// в отчет не попадают.
+func (e *Engine) TuneSettings(input TuningInput) TuningReport {
+	t := tuner{input: input, report: TuningReport{
+		Hardware: input.Hardware, Plans: input.Plans, Recommendations: []SettingRecommendation{},
+	}}
+	t.memory()
+	t.randomPageCost()
+	t.parallelism()
+	t.jit()
+	t.report.Localize(i18n.DefaultLocale)
+	return t.report
+}
+
+// Localize формирует обоснования на языке locale
+func (r *TuningReport) Localize(locale string) {
+	for i := range r.Recommendations {
+		r.Recommendations[i].Rationale = r.Recommendations[i].Message.Format(locale)
+	}
+}
+
+type tuner struct {
+	input  TuningInput
+	report TuningReport
+}
+
+// add добавляет рекомендацию применить значение на весь сервер
+func (t *tuner) add(name, current, recommended string, restart bool, message string, args ...string) {
+	args = append([]string{"current", current, "recommended", recommended}, args...)
+	t.report.Recommendations = append(t.report.Recommendations, SettingRecommendation{
+		Name:        name,
+		Current:     current,
+		Recommended: recommended,
+		Message:     i18n.New(message, args...),
+		Command:     fmt.Sprintf("ALTER SYSTEM SET %s = '%s';", name, recommended),
+		Restart:     restart,
+	})
+}
+
+func (t *tuner) memory() {
+	ram := t.input.Hardware.MemoryMB << 20
+	if ram <= 0 {
+		return
+	}
+	memory := formatMemory(ram)
+
+	sharedBuffers := roundMemory(ram / 4)
+	if current, ok := t.bytes("shared_buffers"); ok && far(current, sharedBuffers) {
+		t.add("shared_buffers", formatMemory(current), formatMemory(sharedBuffers), true,
+			i18n.TuningSharedBuffers, "memory", memory)
+	}
+
+	cache := roundMemory(ram * 3 / 4)
+	if current, ok := t.bytes("effective_cache_size"); ok && far(current, cache) {
+		t.add("effective_cache_size", formatMemory(current), formatMemory(cache), false,
+			i18n.TuningEffectiveCacheSize, "memory", memory)
+	}
+
+	connections := t.int("max_connections", 100)
+	workMem := max(roundMemory((ram-sharedBuffers)/(connections*3)), 4<<20)
+	current, ok := t.bytes("work_mem")
+	if !ok {
+		return
+	}
+	stats := t.input.Plans
+	spills := stats.DiskSorts + stats.HashSpills
+	needed := roundMemory(stats.MaxSpillBytes)
+	switch {
+	case spills > 0 && current < workMem:
+		t.add("work_mem", formatMemory(current), formatMemory(workMem), false, i18n.TuningWorkMemSpills,
+			"spills", strconv.Itoa(spills), "needed", formatMemory(max(needed, 1<<20)))
+	case far(current, workMem):
+		t.add("work_mem", formatMemory(current), formatMemory(workMem), false, i18n.TuningWorkMem,
+			"connections", strconv.FormatInt(connections, 10), "memory", memory)
+	}
+	if spills > 0 && needed > workMem && needed > current {
+		// Общий work_mem такого размера опасен: его может занять каждый узел
+		// каждого соединения. Крупным запросам его поднимают в сеансе.
+		t.report.Recommendations = append(t.report.Recommendations, SettingRecommendation{
+			Name:        "work_mem",
+			Current:     formatMemory(current),
+			Recommended: formatMemory(needed),
+			Message: i18n.New(i18n.TuningWorkMemSession, "current", formatMemory(current),
+				"recommended", formatMemory(needed), "spills", strconv.Itoa(spills), "safe", formatMemory(workMem)),
+			Command: fmt.Sprintf("SET work_mem = '%s';", formatMemory(needed)),
+		})
+	}
+}
+
+func (t *tuner) randomPageCost() {
+	current, err := strconv.ParseFloat(t.input.Settings["random_page_cost"].Value, 64)
+	if err != nil {
+		return
+	}
+	switch {
+	case t.input.Hardware.Storage == StorageSSD && current > 1.5:
+		t.add("random_page_cost", formatFloat(current), "1.1", false, i18n.TuningRandomPageCostSSD)
+	case t.input.Hardware.Storage == StorageHDD && current < 2:
+		t.add("random_page_cost", formatFloat(current), "4", false, i18n.TuningRandomPageCostHDD)
+	}
+}
+
+func (t *tuner) parallelism() {
+	cpus := int64(t.input.Hardware.CPUs)
+	if cpus > 0 {
+		perGather := min(max(cpus/2, 0), 4)
+		if current := t.int("max_parallel_workers_per_gather", -1); current >= 0 && current != perGather {
+			t.add("max_parallel_workers_per_gather", strconv.FormatInt(current, 10), strconv.FormatInt(perGather, 10),
+				false, i18n.TuningParallelPerGather, "cpus", strconv.FormatInt(cpus, 10))
+		}
+	}
+
+	short := t.input.Plans.ShortOfWorkers
+	if short == 0 {
+		return
+	}
+	processes := t.int("max_worker_processes", 8)
+	target := cpus
+	if target <= 0 {
+		target = processes
+	}
+	if current := t.int("max_parallel_workers", -1); current >= 0 && current < target {
+		t.add("max_parallel_workers", strconv.FormatInt(current, 10), strconv.FormatInt(target, 10), false,
+			i18n.TuningParallelWorkers, "count", strconv.Itoa(short))
+	}
+	if processes < target {
+		t.add("max_worker_processes", strconv.FormatInt(processes, 10), strconv.FormatInt(target, 10), true,
+			i18n.TuningWorkerProcesses, "count", strconv.Itoa(short))
+	}
+}
+
+// jit поднимает пороги JIT выше стоимости запросов, которые выполнялись
+// быстрее jitShortQuery, но из-за стоимости компилировались
+func (t *tuner) jit() {
+	if t.input.Settings["jit"].Value != "on" {
+		return
+	}
+	above, err := strconv.ParseFloat(t.input.Settings["jit_above_cost"].Value, 64)
+	if err != nil || above < 0 {
+		return
+	}
+
+	count, maxCost := 0, 0.0
+	for _, root := range t.input.Plans.Roots {
+		if root.ActualTime != nil && *root.ActualTime < jitShortQuery && root.TotalCost >= above {
+			count++
+			maxCost = math.Max(maxCost, root.TotalCost)
+		}
+	}
+	if count == 0 {
+		return
+	}
+
+	threshold := roundCost(maxCost * 2)
+	t.add("jit_above_cost", formatFloat(above), formatFloat(threshold), false, i18n.TuningJIT,
+		"name", "jit_above_cost", "count", strconv.Itoa(count))
+	// Встраивание и оптимизация дороже самой компиляции: их пороги не ниже
+	for _, name := range []string{"jit_inline_above_cost", "jit_optimize_above_cost"} {
+		current, err := strconv.ParseFloat(t.input.Settings[name].Value, 64)
+		if err == nil && current >= 0 && current < threshold*5 {
+			t.add(name, formatFloat(current), formatFloat(threshold*5), false, i18n.TuningJIT,
+				"name", name, "count", strconv.Itoa(count))
+		}
+	}
+}
+
+// bytes возвращает параметр памяти в байтах
+func (t *tuner) bytes(name string) (int64, bool) {
+	setting, ok := t.input.Settings[name]
+	if !ok {
+		return 0, false
+	}
+	value, err := strconv.ParseInt(setting.Value, 10, 64)
+	if err != nil || value < 0 {
+		return 0, false
+	}
+	return value * unitBytes(setting.Unit), true
+}
+
+// int возвращает целый параметр или def
+func (t *tuner) int(name string, def int64) int64 {
+	if value, err := strconv.ParseInt(t.input.Settings[name].Value, 10, 64); err == nil {
+		return value
+	}
+	return def
+}
+
+// unitBytes переводит единицу pg_settings в байты: B, kB, 8kB, MB, 16MB...
+func unitBytes(unit string) int64 {
+	digits := strings.TrimRight(unit, "BkMGT")
+	multiplier := int64(1)
+	if digits != "" {
+		if n, err := strconv.ParseInt(digits, 10, 64); err == nil {
+			multiplier = n
+		}
+	}
+	switch strings.TrimPrefix(unit, digits) {
+	case "kB":
+		return multiplier << 10
+	case "MB":
+		return multiplier << 20
+	case "GB":
+		return multiplier << 30
+	case "TB":
+		return multiplier << 40
+	}
+	return multiplier
+}
+
+// far сообщает, отличается ли текущее значение от рекомендуемого больше
+// чем на четверть
+func far(current, recommended int64) bool {
+	return float64(current) < float64(recommended)*0.8 || float64(current) > float64(recommended)*1.25
+}
+
+// roundMemory округляет объем вниз до мегабайта, а больше гигабайта — до
+// 256 МБ, чтобы рекомендация читалась
+func roundMemory(n int64) int64 {
+	if n >= 1<<30 {
+		return n / (256 << 20) * (256 << 20)
+	}
+	return n / (1 << 20) * (1 << 20)
+}
+
+// formatMemory выводит объем в формате параметров PostgreSQL: 4GB, 512MB, 64kB
+func formatMemory(n int64) string {
+	switch {
+	case n >= 1<<30 && n%(1<<30) == 0:
+		return fmt.Sprintf("%dGB", n>>30)
+	case n >= 1<<20 && n%(1<<20) == 0:
+		return fmt.Sprintf("%dMB", n>>20)
+	case n%(1<<10) == 0:
+		return fmt.Sprintf("%dkB", n>>10)
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+// roundCost округляет стоимость вверх до 1, 2 или 5 в старшем разряде
+func roundCost(cost float64) float64 {
+	if cost <= 0 {
+		return 0
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(cost)))
+	for _, step := range []float64{1, 2, 5, 10} {
+		if cost <= step*magnitude {
+			return step * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,89 @@
+package recommendation
+
+import "testing"
+
+func TestTuneSettings(t *testing.T) {
+	short, slow := 40.0, 5000.0
+	input := TuningInput{
+		Settings: map[string]Setting{
+			"shared_buffers":                  {Value: "16384", Unit: "8kB"},  // 128MB
+			"effective_cache_size":            {Value: "524288", Unit: "8kB"}, // 4GB
+			"work_mem":                        {Value: "4096", Unit: "kB"},
+			"max_connections":                 {Value: "100"},
+			"random_page_cost":                {Value: "4"},
+			"max_parallel_workers_per_gather": {Value: "2"},
+			"max_parallel_workers":            {Value: "8"},
+			"max_worker_processes":            {Value: "8"},
+			"jit":                             {Value: "on"},
+			"jit_above_cost":                  {Value: "100000"},
+			"jit_inline_above_cost":           {Value: "500000"},
+			"jit_optimize_above_cost":         {Value: "500000"},
+		},
+		Hardware: Hardware{MemoryMB: 16 << 10, CPUs: 16, Storage: StorageSSD},
+		Plans: PlanStats{
+			Plans: 3, DiskSorts: 2, MaxSpillBytes: 100 << 20, ShortOfWorkers: 1,
+			Roots: []PlanRoot{{TotalCost: 180000, ActualTime: &short}, {TotalCost: 900000, ActualTime: &slow}, {TotalCost: 50}},
+		},
+	}
+
+	report := NewEngine().TuneSettings(input)
+	type rec struct{ recommended, command string }
+	got := make(map[string][]rec)
+	for _, r := range report.Recommendations {
+		got[r.Name] = append(got[r.Name], rec{r.Recommended, r.Command})
+		if r.Rationale == "" {
+			t.Errorf("У рекомендации %s нет обоснования", r.Name)
+		}
+	}
+
+	want := map[string][]rec{
+		"shared_buffers":       {{"4GB", "ALTER SYSTEM SET shared_buffers = '4GB';"}},
+		"effective_cache_size": {{"12GB", "ALTER SYSTEM SET effective_cache_size = '12GB';"}},
+		// (16GB − 4GB) / (100 × 3) ≈ 40MB на сервер, 100MB — только в сеансе
+		"work_mem":                        {{"40MB", "ALTER SYSTEM SET work_mem = '40MB';"}, {"100MB", "SET work_mem = '100MB';"}},
+		"random_page_cost":                {{"1.1", "ALTER SYSTEM SET random_page_cost = '1.1';"}},
+		"max_parallel_workers_per_gather": {{"4", "ALTER SYSTEM SET max_parallel_workers_per_gather = '4';"}},
+		"max_parallel_workers":            {{"16", "ALTER SYSTEM SET max_parallel_workers = '16';"}},
+		"max_worker_processes":            {{"16", "ALTER SYSTEM SET max_worker_processes = '16';"}},
+		// Запрос стоимостью 180000 выполнился за 40 мс: порог выше удвоенной стоимости
+		"jit_above_cost":          {{"500000", "ALTER SYSTEM SET jit_above_cost = '500000';"}},
+		"jit_inline_above_cost":   {{"2500000", "ALTER SYSTEM SET jit_inline_above_cost = '2500000';"}},
+		"jit_optimize_above_cost": {{"2500000", "ALTER SYSTEM SET jit_optimize_above_cost = '2500000';"}},
+	}
+	if len(got) != len(want) {
+		t.Errorf("Ожидали рекомендации для %d параметров, получили %+v", len(want), got)
+	}
+	for name, recs := range want {
+		if len(got[name]) != len(recs) {
+			t.Errorf("%s: ожидали %+v, получили %+v", name, recs, got[name])
+			continue
+		}
+		for i := range recs {
+			if got[name][i] != recs[i] {
+				t.Errorf("%s: ожидали %+v, получили %+v", name, recs[i], got[name][i])
+			}
+		}
+	}
+	for _, r := range report.Recommendations {
+		if r.Restart != (r.Name == "shared_buffers" || r.Name == "max_worker_processes") {
+			t.Errorf("Неверный признак перезапуска для %s", r.Name)
+		}
+	}
+
+	report.Localize("en")
+	if report.Recommendations[0].Rationale != "shared_buffers = 128MB: a quarter of server memory (16GB) is the usual page cache size — 4GB; the rest stays with the OS cache and working memory. Changing it requires a restart" {
+		t.Errorf("Неверное обоснование на английском: %q", report.Recommendations[0].Rationale)
+	}
+}
+
+func TestTuneSettingsWithoutHardware(t *testing.T) {
+	input := TuningInput{Settings: map[string]Setting{
+		"shared_buffers":   {Value: "16384", Unit: "8kB"},
+		"random_page_cost": {Value: "4"},
+		"work_mem":         {Value: "4096", Unit: "kB"},
+	}}
+	report := NewEngine().TuneSettings(input)
+	if len(report.Recommendations) != 0 {
+		t.Errorf("Без сведений о железе и планов рекомендаций быть не должно: %+v", report.Recommendations)
+	}
+}
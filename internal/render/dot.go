@@ -0,0 +1,45 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"sql-optimizer/internal/analyzer"
+)
+
+// DOT записывает план в формате Graphviz: цвет узла отражает долю
+// собственного времени, толщина ребра — число переданных строк.
+// Ребра направлены от дочернего узла к родителю, по движению данных.
+func DOT(w io.Writer, plan []analyzer.PlanNode) error {
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintln(b, "digraph plan {")
+	fmt.Fprintln(b, "  rankdir=BT;")
+	fmt.Fprintln(b, `  node [shape=box, style="rounded,filled", fontname="Helvetica", fontsize=10];`)
+	fmt.Fprintln(b, `  edge [fontname="Helvetica", fontsize=9, color="#7f8c8d"];`)
+
+	ids := make(map[*analyzer.AnnotatedNode]int)
+	nodes := analyzer.Flatten(analyzer.Annotate(plan))
+	for i, node := range nodes {
+		ids[node] = i
+		label := strings.Join([]string{dotEscape(NodeLabel(node.Node)), timeLabel(node), rowsLabel(node)}, `\n`)
+		fmt.Fprintf(b, "  n%d [label=\"%s\", fillcolor=%q];\n", i, label, heatColor(node.Share))
+	}
+	for _, node := range nodes {
+		for _, child := range node.Children {
+			rows := outputRows(child)
+			fmt.Fprintf(b, "  n%d -> n%d [penwidth=%.1f, label=\"%.0f\"];\n",
+				ids[child], ids[node], edgeWidth(rows), rows)
+		}
+	}
+
+	fmt.Fprintln(b, "}")
+	return b.Flush()
+}
+
+// dotEscape экранирует строку для использования внутри кавычек DOT
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
@@ -0,0 +1,123 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"sql-optimizer/internal/analyzer"
+)
+
+// Размеры flame graph в пикселях
+const (
+	flameWidth       = 1200.0
+	flameFrameHeight = 18.0
+	flameMinWidth    = 0.5 // более узкие кадры не рисуются
+)
+
+// Folded записывает собственное время узлов в формате свернутых стеков
+// (folded stacks), который понимают flamegraph.pl, speedscope и
+// inferno: путь от корня через ";" и время в микросекундах.
+// Для планов без ANALYZE вместо времени используется стоимость.
+func Folded(w io.Writer, plan []analyzer.PlanNode) error {
+	b := bufio.NewWriter(w)
+
+	var walk func(node *analyzer.AnnotatedNode, path []string)
+	walk = func(node *analyzer.AnnotatedNode, path []string) {
+		path = append(path, frameName(node))
+		if value := flameValue(node); value > 0 {
+			fmt.Fprintf(b, "%s %d\n", strings.Join(path, ";"), int64(math.Round(value*1000)))
+		}
+		for _, child := range node.Children {
+			walk(child, path)
+		}
+	}
+	for _, root := range analyzer.Annotate(plan) {
+		walk(root, nil)
+	}
+
+	return b.Flush()
+}
+
+// FlameGraph записывает flame graph в формате SVG: ширина кадра
+// пропорциональна времени узла вместе с потомками, корень плана внизу,
+// цвет отражает долю собственного времени узла
+func FlameGraph(w io.Writer, plan []analyzer.PlanNode) error {
+	roots := analyzer.Annotate(plan)
+
+	var total float64
+	maxDepth := 0
+	for _, node := range analyzer.Flatten(roots) {
+		maxDepth = max(maxDepth, node.Depth)
+	}
+	for _, root := range roots {
+		total += flameTotal(root)
+	}
+	height := 2*margin + float64(maxDepth+1)*flameFrameHeight
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" font-family="Helvetica, Arial, sans-serif" font-size="11">`+"\n",
+		flameWidth+2*margin, height, flameWidth+2*margin, height)
+	fmt.Fprintf(b, `<rect width="100%%" height="100%%" fill="#ffffff"/>`+"\n")
+
+	var draw func(node *analyzer.AnnotatedNode, x float64)
+	draw = func(node *analyzer.AnnotatedNode, x float64) {
+		width := flameTotal(node) / total * flameWidth
+		if width < flameMinWidth {
+			return
+		}
+		y := height - margin - float64(node.Depth+1)*flameFrameHeight
+		name := NodeLabel(node.Node)
+		fmt.Fprintf(b, `<g><title>%s — %s</title>`, escape(name), escape(timeLabel(node)))
+		fmt.Fprintf(b, `<rect x="%.2f" y="%.1f" width="%.2f" height="%.1f" fill="%s" stroke="#ffffff"/>`,
+			margin+x, y, width, flameFrameHeight-1, heatColor(node.Share))
+		// Подпись помещается, если на символ приходится около 7 пикселей
+		if chars := int(width / 7); chars >= 3 {
+			fmt.Fprintf(b, `<text x="%.2f" y="%.1f">%s</text>`, margin+x+3, y+flameFrameHeight-5, escape(truncate(name, chars)))
+		}
+		fmt.Fprintln(b, `</g>`)
+
+		for _, child := range node.Children {
+			draw(child, x)
+			x += flameTotal(child) / total * flameWidth
+		}
+	}
+
+	if total > 0 {
+		x := 0.0
+		for _, root := range roots {
+			draw(root, x)
+			x += flameTotal(root) / total * flameWidth
+		}
+	}
+
+	fmt.Fprintln(b, `</svg>`)
+	return b.Flush()
+}
+
+// flameValue возвращает собственное время узла или, без ANALYZE, стоимость
+func flameValue(node *analyzer.AnnotatedNode) float64 {
+	if node.Timed() {
+		return node.ExclusiveTime
+	}
+	return node.ExclusiveCost
+}
+
+// flameTotal возвращает ширину кадра: собственное значение плюс кадры
+// потомков. Так кадр никогда не уже суммы дочерних, даже если время
+// потомков из-за параллельных воркеров больше времени родителя.
+func flameTotal(node *analyzer.AnnotatedNode) float64 {
+	total := flameValue(node)
+	for _, child := range node.Children {
+		total += flameTotal(child)
+	}
+	return total
+}
+
+// frameName возвращает имя кадра без разделителя стека. Пробелы
+// допустимы: значение отделяется по последнему пробелу в строке.
+func frameName(node *analyzer.AnnotatedNode) string {
+	return strings.NewReplacer(";", ",", "\n", " ").Replace(NodeLabel(node.Node))
+}
@@ -0,0 +1,62 @@
+package render
+
+import (
+	"fmt"
+	"math"
+
+	"sql-optimizer/internal/analyzer"
+)
+
+// NodeLabel возвращает подпись узла в стиле текстового EXPLAIN
+func NodeLabel(node *analyzer.PlanNode) string {
+	label := node.NodeType
+	if node.IndexName != "" {
+		label += " using " + node.IndexName
+	}
+	if node.RelationName != "" {
+		label += " on " + node.RelationName
+	}
+	return label
+}
+
+// timeLabel возвращает собственное время узла или, без ANALYZE, его стоимость
+func timeLabel(node *analyzer.AnnotatedNode) string {
+	if node.Timed() {
+		return fmt.Sprintf("%.3f мс (%.1f%%)", node.ExclusiveTime, node.Share)
+	}
+	return fmt.Sprintf("стоимость %.2f (%.1f%%)", node.ExclusiveCost, node.Share)
+}
+
+// rowsLabel возвращает фактическое число строк и оценку планировщика
+func rowsLabel(node *analyzer.AnnotatedNode) string {
+	if node.Node.ActualRows == nil {
+		return fmt.Sprintf("строки ~%d", node.Node.PlanRows)
+	}
+	return fmt.Sprintf("строки %d / %d", *node.Node.ActualRows, node.Node.PlanRows)
+}
+
+// outputRows возвращает число строк, которое узел передал родителю:
+// фактическое с учетом циклов или оценку, если план без ANALYZE
+func outputRows(node *analyzer.AnnotatedNode) float64 {
+	if node.Node.ActualRows == nil {
+		return float64(node.Node.PlanRows)
+	}
+	return float64(*node.Node.ActualRows) * float64(node.Loops())
+}
+
+// heatColor переводит долю времени узла в цвет от светло-желтого до красного
+func heatColor(share float64) string {
+	t := math.Sqrt(math.Max(0, math.Min(share, 100)) / 100)
+	from := [3]float64{0xfe, 0xf9, 0xe7}
+	to := [3]float64{0xe7, 0x4c, 0x3c}
+	var c [3]int
+	for i := range c {
+		c[i] = int(math.Round(from[i] + (to[i]-from[i])*t))
+	}
+	return fmt.Sprintf("#%02x%02x%02x", c[0], c[1], c[2])
+}
+
+// edgeWidth переводит число строк в толщину ребра по логарифмической шкале
+func edgeWidth(rows float64) float64 {
+	return math.Min(1+math.Log10(rows+1), 8)
+}
@@ -0,0 +1,177 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"sql-optimizer/internal/analyzer"
+)
+
+const testPlanJSON = `[{"Plan": {
+	"Node Type": "Hash Join", "Total Cost": 300, "Plan Rows": 100,
+	"Actual Total Time": 50, "Actual Rows": 1000, "Actual Loops": 1,
+	"Plans": [
+		{"Node Type": "Seq Scan", "Relation Name": "orders", "Total Cost": 200, "Plan Rows": 5000,
+		 "Actual Total Time": 30, "Actual Rows": 50, "Actual Loops": 1},
+		{"Node Type": "Index Scan", "Index Name": "users_pkey", "Relation Name": "users", "Total Cost": 8,
+		 "Plan Rows": 1, "Actual Total Time": 0.5, "Actual Rows": 1, "Actual Loops": 20}
+	]
+}}]`
+
+func testPlan(t *testing.T) []analyzer.PlanNode {
+	plan, err := analyzer.ParseExplainJSON(testPlanJSON)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	return plan
+}
+
+func TestDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := DOT(&buf, testPlan(t)); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	out := buf.String()
+
+	expected := []string{
+		"digraph plan {",
+		`n1 [label="Seq Scan on orders\n30.000 мс (60.0%)\nстроки 50 / 5000"`,
+		`n1 -> n0 [penwidth=2.7, label="50"];`,
+		`n2 -> n0 [penwidth=2.3, label="20"];`,
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(out, fragment) {
+			t.Errorf("В DOT нет фрагмента %q:\n%s", fragment, out)
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SVG(&buf, testPlan(t)); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	assertValidXML(t, buf.Bytes())
+
+	placed, width, height := layout(analyzer.Annotate(testPlan(t)))
+	if len(placed) != 3 {
+		t.Fatalf("Ожидали 3 узла, получили %d", len(placed))
+	}
+	root, left, right := placed[2], placed[0], placed[1]
+	if root.x != (left.x+right.x)/2 || root.y >= left.y || left.y != right.y {
+		t.Errorf("Корень должен быть по центру над потомками: %+v %+v %+v", root, left, right)
+	}
+	if width != 2*margin+2*boxWidth+gapX || height != 2*margin+2*boxHeight+gapY {
+		t.Errorf("Неверный размер схемы: %.0fx%.0f", width, height)
+	}
+}
+
+func TestFolded(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Folded(&buf, testPlan(t)); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	expected := "Hash Join 10000\n" +
+		"Hash Join;Seq Scan on orders 30000\n" +
+		"Hash Join;Index Scan using users_pkey on users 10000\n"
+	if buf.String() != expected {
+		t.Errorf("Ожидали:\n%s\nПолучили:\n%s", expected, buf.String())
+	}
+}
+
+func TestFlameGraph(t *testing.T) {
+	var buf bytes.Buffer
+	if err := FlameGraph(&buf, testPlan(t)); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	assertValidXML(t, buf.Bytes())
+
+	// Seq Scan занимает 60% ширины и лежит над корнем
+	if !strings.Contains(buf.String(), `<rect x="20.00" y="20.0" width="720.00"`) {
+		t.Errorf("Не найден кадр Seq Scan ожидаемой ширины:\n%s", buf.String())
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Timeline(&buf, testPlan(t)); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	assertValidXML(t, buf.Bytes())
+
+	for _, fragment := range []string{"Index Scan using users_pkey on users ×20", "0.000–30.000", "50.00 мс"} {
+		if !strings.Contains(buf.String(), fragment) {
+			t.Errorf("На диаграмме нет фрагмента %q", fragment)
+		}
+	}
+
+	plan, _ := analyzer.ParseExplainJSON(`{"Plan": {"Node Type": "Seq Scan", "Total Cost": 10, "Plan Rows": 5}}`)
+	if err := Timeline(&buf, plan); err != ErrNoTiming {
+		t.Errorf("Ожидали ErrNoTiming для плана без ANALYZE, получили %v", err)
+	}
+}
+
+func TestTimelineDeepPlan(t *testing.T) {
+	seconds := 1.0
+	leaf := analyzer.PlanNode{NodeType: "Seq Scan", RelationName: "orders", ActualTotalTime: &seconds}
+	node := leaf
+	for i := 0; i < 25; i++ {
+		node = analyzer.PlanNode{NodeType: "Nested Loop", ActualTotalTime: &seconds, Plans: []analyzer.PlanNode{node, leaf}}
+	}
+
+	var buf bytes.Buffer
+	if err := Timeline(&buf, []analyzer.PlanNode{node}); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	assertValidXML(t, buf.Bytes())
+}
+
+func TestTruncate(t *testing.T) {
+	for _, tt := range []struct {
+		limit int
+		want  string
+	}{{10, "Nested Loop"[:9] + "…"}, {1, "…"}, {0, "…"}, {-4, "…"}, {20, "Nested Loop"}} {
+		if got := truncate("Nested Loop", tt.limit); got != tt.want {
+			t.Errorf("truncate(%d) = %q, ожидалось %q", tt.limit, got, tt.want)
+		}
+	}
+}
+
+// assertValidXML проверяет, что документ разбирается как XML
+func assertValidXML(t *testing.T, data []byte) {
+	t.Helper()
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		if _, err := decoder.Token(); err == io.EOF {
+			return
+		} else if err != nil {
+			t.Fatalf("Некорректный SVG: %v", err)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Text(&buf, testPlan(t), TextOptions{}); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	expected := "собств., мс     %  строки  оценка  ошибка  циклы  чтения/кеш  узел\n" +
+		"     10.000  20.0    1000     100   ↑10.0      1              Hash Join\n" +
+		"     30.000  60.0      50    5000  ↓100.0      1              ├─ Seq Scan on orders\n" +
+		"     10.000  20.0       1       1             20              └─ Index Scan using users_pkey on users\n"
+	if buf.String() != expected {
+		t.Errorf("Ожидали:\n%s\nПолучили:\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	Text(&buf, testPlan(t), TextOptions{Color: true})
+	if !strings.Contains(buf.String(), colorRed+"     30.000") {
+		t.Errorf("Дорогой узел должен подсвечиваться красным:\n%q", buf.String())
+	}
+}
@@ -0,0 +1,131 @@
+package render
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"sql-optimizer/internal/analyzer"
+)
+
+// Размеры элементов схемы в пикселях
+const (
+	boxWidth    = 230.0
+	boxHeight   = 56.0
+	gapX        = 24.0
+	gapY        = 60.0
+	margin      = 20.0
+	labelLength = 34 // максимальная длина подписи узла в символах
+)
+
+// placedNode — узел плана с координатами левого верхнего угла
+type placedNode struct {
+	node *analyzer.AnnotatedNode
+	x, y float64
+}
+
+// SVG записывает план в виде SVG-схемы. Раскладка дерева выполняется
+// на Go, поэтому Graphviz не нужен: листья занимают колонки слева
+// направо, родитель центрируется над своими потомками. Цвет узла
+// отражает долю собственного времени, толщина ребра — число строк.
+func SVG(w io.Writer, plan []analyzer.PlanNode) error {
+	placed, width, height := layout(analyzer.Annotate(plan))
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" font-family="Helvetica, Arial, sans-serif" font-size="11">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(b, `<rect width="100%%" height="100%%" fill="#ffffff"/>`+"\n")
+
+	// Сначала ребра, чтобы узлы рисовались поверх них
+	positions := make(map[*analyzer.AnnotatedNode]placedNode, len(placed))
+	for _, p := range placed {
+		positions[p.node] = p
+	}
+	for _, parent := range placed {
+		for _, child := range parent.node.Children {
+			c := positions[child]
+			x1, y1 := c.x+boxWidth/2, c.y
+			x2, y2 := parent.x+boxWidth/2, parent.y+boxHeight
+			rows := outputRows(child)
+			fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#7f8c8d" stroke-width="%.1f"/>`+"\n",
+				x1, y1, x2, y2, edgeWidth(rows))
+			fmt.Fprintf(b, `<text x="%.1f" y="%.1f" fill="#7f8c8d" font-size="9">%.0f</text>`+"\n",
+				(x1+x2)/2+4, (y1+y2)/2, rows)
+		}
+	}
+
+	for _, p := range placed {
+		label := NodeLabel(p.node.Node)
+		fmt.Fprintf(b, `<g><title>%s</title>`, escape(label))
+		fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.0f" height="%.0f" rx="6" fill="%s" stroke="#95a5a6"/>`,
+			p.x, p.y, boxWidth, boxHeight, heatColor(p.node.Share))
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f" font-weight="bold">%s</text>`, p.x+8, p.y+17, escape(truncate(label, labelLength)))
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f">%s</text>`, p.x+8, p.y+32, escape(timeLabel(p.node)))
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f">%s</text>`, p.x+8, p.y+47, escape(rowsLabel(p.node)))
+		fmt.Fprintln(b, `</g>`)
+	}
+
+	fmt.Fprintln(b, `</svg>`)
+	return b.Flush()
+}
+
+// layout расставляет узлы и возвращает их вместе с размерами схемы
+func layout(roots []*analyzer.AnnotatedNode) ([]placedNode, float64, float64) {
+	var placed []placedNode
+	column := 0
+	maxDepth := 0
+
+	// place возвращает x-координату центра колонки узла
+	var place func(node *analyzer.AnnotatedNode) float64
+	place = func(node *analyzer.AnnotatedNode) float64 {
+		maxDepth = max(maxDepth, node.Depth)
+
+		var x float64
+		if len(node.Children) == 0 {
+			x = margin + float64(column)*(boxWidth+gapX)
+			column++
+		} else {
+			first := place(node.Children[0])
+			last := first
+			for _, child := range node.Children[1:] {
+				last = place(child)
+			}
+			x = (first + last) / 2
+		}
+
+		placed = append(placed, placedNode{
+			node: node,
+			x:    x,
+			y:    margin + float64(node.Depth)*(boxHeight+gapY),
+		})
+		return x
+	}
+	for _, root := range roots {
+		place(root)
+	}
+
+	width := 2*margin + float64(max(column, 1))*(boxWidth+gapX) - gapX
+	height := 2*margin + float64(maxDepth+1)*(boxHeight+gapY) - gapY
+	return placed, width, height
+}
+
+// truncate обрезает строку до limit символов с многоточием
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	if limit <= 1 {
+		return "…"
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+// escape экранирует текст для вставки в XML
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
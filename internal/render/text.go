@@ -0,0 +1,160 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"sql-optimizer/internal/analyzer"
+)
+
+// ANSI-коды цветов для вывода в терминал
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorYellow = "\033[33m"
+	colorDim    = "\033[2m"
+)
+
+// Пороги доли собственного времени для подсветки узла
+const (
+	hotShare  = 50.0
+	warmShare = 10.0
+)
+
+// TextOptions — настройки текстового вывода плана
+type TextOptions struct {
+	Color bool // подсвечивать дорогие узлы и ошибки оценки ANSI-цветами
+}
+
+// textRow — строка таблицы до выравнивания столбцов
+type textRow struct {
+	cells       []string
+	share       float64
+	misestimate float64 // во сколько раз ошиблась оценка строк
+	tree        string
+}
+
+// Text записывает план в виде выровненной таблицы с деревом узлов в духе
+// explain.depesz.com: собственное время, доля, фактические строки против
+// оценки с кратностью ошибки, циклы и чтения буферов (с диска / из кеша).
+func Text(w io.Writer, plan []analyzer.PlanNode, opts TextOptions) error {
+	header := []string{"собств., мс", "%", "строки", "оценка", "ошибка", "циклы", "чтения/кеш"}
+	rows := []textRow{}
+
+	var walk func(node *analyzer.AnnotatedNode, prefix string, last bool)
+	walk = func(node *analyzer.AnnotatedNode, prefix string, last bool) {
+		branch, childPrefix := "", ""
+		if node.Depth > 0 {
+			branch, childPrefix = prefix+"├─ ", prefix+"│  "
+			if last {
+				branch, childPrefix = prefix+"└─ ", prefix+"   "
+			}
+		}
+		rows = append(rows, newTextRow(node, branch))
+		for i, child := range node.Children {
+			walk(child, childPrefix, i == len(node.Children)-1)
+		}
+	}
+	for _, root := range analyzer.Annotate(plan) {
+		walk(root, "", true)
+	}
+
+	widths := make([]int, len(header))
+	for i, title := range header {
+		widths[i] = len([]rune(title))
+	}
+	for _, row := range rows {
+		for i, cell := range row.cells {
+			widths[i] = max(widths[i], len([]rune(cell)))
+		}
+	}
+
+	b := bufio.NewWriter(w)
+	line := func(cells []string) {
+		for i, cell := range cells {
+			fmt.Fprintf(b, "%s%s  ", strings.Repeat(" ", widths[i]-len([]rune(cell))), cell)
+		}
+	}
+
+	line(header)
+	fmt.Fprintln(b, "узел")
+	for _, row := range rows {
+		color := ""
+		if opts.Color {
+			switch {
+			case row.share >= hotShare || row.misestimate >= analyzer.MisestimateThreshold*analyzer.MisestimateThreshold:
+				color = colorRed
+			case row.share >= warmShare || row.misestimate >= analyzer.MisestimateThreshold:
+				color = colorYellow
+			case row.share < 1:
+				color = colorDim
+			}
+		}
+		b.WriteString(color)
+		line(row.cells)
+		b.WriteString(row.tree)
+		if color != "" {
+			b.WriteString(colorReset)
+		}
+		b.WriteString("\n")
+	}
+
+	return b.Flush()
+}
+
+// newTextRow готовит ячейки строки таблицы для узла
+func newTextRow(node *analyzer.AnnotatedNode, branch string) textRow {
+	row := textRow{share: node.Share, tree: branch + NodeLabel(node.Node)}
+	if node.Node.Filter != "" {
+		row.tree += "  [" + node.Node.Filter + "]"
+	}
+
+	exclusive := fmt.Sprintf("~%.2f", node.ExclusiveCost)
+	if node.Timed() {
+		exclusive = fmt.Sprintf("%.3f", node.ExclusiveTime)
+	}
+
+	actual, misestimate := "—", ""
+	if node.Node.ActualRows != nil {
+		actual = fmt.Sprint(*node.Node.ActualRows)
+		factor, under := node.MisestimateFactor()
+		row.misestimate = factor
+		if factor >= 2 {
+			direction := "↓"
+			if under {
+				direction = "↑"
+			}
+			misestimate = fmt.Sprintf("%s%.1f", direction, factor)
+		}
+	}
+
+	buffers := ""
+	if node.Node.SharedReadBlocks > 0 || node.Node.SharedHitBlocks > 0 {
+		buffers = fmt.Sprintf("%d/%d", node.Node.SharedReadBlocks, node.Node.SharedHitBlocks)
+	}
+
+	row.cells = []string{
+		exclusive,
+		fmt.Sprintf("%.1f", node.Share),
+		actual,
+		fmt.Sprint(node.Node.PlanRows),
+		misestimate,
+		fmt.Sprint(node.Loops()),
+		buffers,
+	}
+	return row
+}
+
+// IsTerminal сообщает, подключен ли w к терминалу, чтобы решить,
+// выводить ли цвета
+func IsTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
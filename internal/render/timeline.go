@@ -0,0 +1,94 @@
+package render
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"sql-optimizer/internal/analyzer"
+)
+
+// Размеры временной диаграммы в пикселях
+const (
+	timelineLabelWidth = 300.0
+	timelineChartWidth = 800.0
+	timelineValueWidth = 90.0 // место для подписи времени справа от полосы
+	timelineRowHeight  = 20.0
+	timelineAxisHeight = 24.0
+	timelineTicks      = 5
+)
+
+// ErrNoTiming возвращается, если план получен без ANALYZE и фактического
+// времени узлов нет
+var ErrNoTiming = errors.New("в плане нет фактического времени: нужен EXPLAIN ANALYZE")
+
+// Timeline записывает временную диаграмму (Gantt) выполнения узлов в SVG.
+// Для каждого узла тонкая линия идет от начала запроса до выдачи первой
+// строки (Actual Startup Time), полоса — от первой до последней строки
+// (Actual Total Time). Для узлов с несколькими циклами PostgreSQL
+// сообщает среднее время одного цикла, такие узлы помечаются "×N".
+func Timeline(w io.Writer, plan []analyzer.PlanNode) error {
+	nodes := analyzer.Flatten(analyzer.Annotate(plan))
+
+	var end float64
+	for _, node := range nodes {
+		if node.Timed() {
+			end = math.Max(end, *node.Node.ActualTotalTime)
+		}
+	}
+	if end == 0 {
+		return ErrNoTiming
+	}
+
+	width := 2*margin + timelineLabelWidth + timelineChartWidth + timelineValueWidth
+	height := 2*margin + timelineAxisHeight + float64(len(nodes))*timelineRowHeight
+	scale := timelineChartWidth / end
+	chartX := margin + timelineLabelWidth
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" font-family="Helvetica, Arial, sans-serif" font-size="11">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(b, `<rect width="100%%" height="100%%" fill="#ffffff"/>`+"\n")
+
+	// Ось времени с делениями
+	for i := 0; i <= timelineTicks; i++ {
+		t := end * float64(i) / timelineTicks
+		x := chartX + t*scale
+		fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ecf0f1"/>`,
+			x, margin+timelineAxisHeight-4, x, height-margin)
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f" fill="#7f8c8d" text-anchor="middle">%.2f мс</text>`+"\n",
+			x, margin+12, t)
+	}
+
+	for i, node := range nodes {
+		y := margin + timelineAxisHeight + float64(i)*timelineRowHeight
+		label := NodeLabel(node.Node)
+		if node.Loops() > 1 {
+			label += fmt.Sprintf(" ×%d", node.Loops())
+		}
+		fmt.Fprintf(b, `<g><title>%s</title>`, escape(label))
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f">%s</text>`,
+			margin+float64(node.Depth)*10, y+timelineRowHeight-6, escape(truncate(label, max(44-node.Depth*2, 8))))
+
+		if node.Timed() {
+			startup := 0.0
+			if node.Node.ActualStartupTime != nil {
+				startup = *node.Node.ActualStartupTime
+			}
+			total := *node.Node.ActualTotalTime
+			mid := y + timelineRowHeight/2
+			fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#95a5a6"/>`,
+				chartX, mid, chartX+startup*scale, mid)
+			fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s" stroke="#7f8c8d"/>`,
+				chartX+startup*scale, y+3, math.Max((total-startup)*scale, 1), timelineRowHeight-6, heatColor(node.Share))
+			fmt.Fprintf(b, `<text x="%.1f" y="%.1f" fill="#7f8c8d" font-size="9">%.3f–%.3f</text>`,
+				chartX+total*scale+4, y+timelineRowHeight-6, startup, total)
+		}
+		fmt.Fprintln(b, `</g>`)
+	}
+
+	fmt.Fprintln(b, `</svg>`)
+	return b.Flush()
+}
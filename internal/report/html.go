@@ -0,0 +1,213 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/render"
+)
+
+// htmlNode — представление узла плана для шаблона
+type htmlNode struct {
+	Label       string
+	Details     []string
+	Time        string
+	Share       float64
+	Rows        string
+	Misestimate string
+	Severe      bool
+	Children    []htmlNode
+}
+
+// htmlAnalysis — представление анализа одного запроса для шаблона
+type htmlAnalysis struct {
+	Title    string
+	Query    string
+	Err      string
+	Cost     float64  // стоимость корня плана, см. Analysis.Cost
+	Time     *float64 // время корня плана, см. Analysis.Time
+	Result   *analyzer.AnalysisResult
+	Problems []htmlProblem
+	Roots    []htmlNode
+}
+
+// htmlText — заголовки отчета, уже переведенные на язык отчета
+type htmlText struct {
+	Title           string
+	Generated       string
+	Error           string
+	Cost            string
+	Time            string
+	ProblemCount    string
+	Plan            string
+	Recommendations string
+	Warnings        string
+}
+
+type htmlProblem struct {
+	analyzer.ProblematicOperation
+	DDL string
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"bar": func(share float64) template.CSS {
+		return template.CSS(fmt.Sprintf("width: %.1f%%", share))
+	},
+	"time": formatTime,
+}).Parse(`<!DOCTYPE html>
+<html lang="{{.Locale}}">
+<head>
+<meta charset="UTF-8">
+<title>{{.Text.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; margin: 2em auto; max-width: 1100px; color: #2c3e50; }
+h1 { border-bottom: 2px solid #3498db; padding-bottom: .3em; }
+pre { background: #f4f6f7; padding: 1em; overflow-x: auto; border-radius: 4px; }
+.summary td, .summary th { padding: .3em 1em; text-align: left; }
+.error { color: #c0392b; }
+.problem { border-left: 4px solid #e67e22; padding: .2em .8em; margin: .5em 0; }
+.problem.high { border-color: #c0392b; }
+.problem.low { border-color: #95a5a6; }
+details.node { margin-left: 1.2em; }
+details.node > summary { cursor: pointer; padding: 2px 0; list-style-position: outside; }
+.leaf { margin-left: 1.2em; padding: 2px 0 2px 1em; }
+.label { font-weight: 600; }
+.meta { color: #7f8c8d; font-size: .9em; margin-left: .5em; }
+.bar { display: inline-block; width: 120px; height: .7em; background: #ecf0f1; margin-right: .5em; vertical-align: middle; }
+.bar span { display: block; height: 100%; background: #e74c3c; }
+.misestimate { background: #fdebd0; padding: 0 .3em; border-radius: 3px; }
+.misestimate.severe { background: #f5b7b1; }
+.node-details { color: #7f8c8d; font-size: .85em; margin: 0 0 .2em 2em; }
+</style>
+</head>
+<body>
+<h1>{{.Text.Title}}</h1>
+<p class="meta">{{.Text.Generated}}</p>
+{{range .Analyses}}
+<section>
+<h2>{{.Title}}</h2>
+{{if .Query}}<pre>{{.Query}}</pre>{{end}}
+{{if .Err}}<p class="error">{{$.Text.Error}}: {{.Err}}</p>{{else}}
+<table class="summary">
+<tr><th>{{$.Text.Cost}}</th><td>{{printf "%.2f" .Cost}}</td></tr>
+<tr><th>{{$.Text.Time}}</th><td>{{time .Time}}</td></tr>
+<tr><th>{{$.Text.ProblemCount}}</th><td>{{len .Result.ProblematicOperations}}</td></tr>
+</table>
+{{if .Roots}}<h3>{{$.Text.Plan}}</h3>
+{{range .Roots}}{{template "node" .}}{{end}}{{end}}
+{{if or .Problems .Result.Recommendations}}<h3>{{$.Text.Recommendations}}</h3>
+{{range .Problems}}<div class="problem {{.Severity}}"><strong>{{.NodeType}}</strong>: {{.Description}}. {{.Recommendation}}{{if .DDL}}<pre>{{.DDL}}</pre>{{end}}</div>
+{{end}}{{if .Result.Recommendations}}<ul>{{range .Result.Recommendations}}<li>{{.}}</li>{{end}}</ul>{{end}}{{end}}
+{{if .Result.Warnings}}<h3>{{$.Text.Warnings}}</h3>
+<ul>{{range .Result.Warnings}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{end}}
+</section>
+{{end}}
+</body>
+</html>
+{{define "line"}}<span class="bar"><span style="{{bar .Share}}"></span></span><span class="label">{{.Label}}</span><span class="meta">{{.Time}} · {{printf "%.1f" .Share}}% · {{.Rows}}</span>{{if .Misestimate}} <span class="misestimate{{if .Severe}} severe{{end}}">{{.Misestimate}}</span>{{end}}{{end}}
+{{define "node"}}{{if .Children}}<details class="node" open><summary>{{template "line" .}}</summary>
+{{range .Details}}<div class="node-details">{{.}}</div>{{end}}{{range .Children}}{{template "node" .}}{{end}}</details>
+{{else}}<div class="leaf">{{template "line" .}}{{range .Details}}<div class="node-details">{{.}}</div>{{end}}</div>
+{{end}}{{end}}`))
+
+// HTML записывает самодостаточный HTML-файл без внешних ресурсов:
+// сворачиваемое дерево плана с полосами собственного времени узлов,
+// выделением ошибок оценки строк, рекомендациями и предупреждениями.
+// Тексты отчета и атрибут lang берутся из locale.
+func HTML(w io.Writer, analyses []Analysis, locale string) error {
+	data := struct {
+		Locale   string
+		Text     htmlText
+		Analyses []htmlAnalysis
+	}{Locale: locale, Text: newHTMLText(locale)}
+
+	for _, a := range analyses {
+		view := htmlAnalysis{Title: queryTitle(a), Query: strings.TrimSpace(a.Query), Cost: a.Cost(), Time: a.Time(), Result: a.Result}
+		if a.Err != nil {
+			view.Err = a.Err.Error()
+		} else if a.Result == nil {
+			view.Err = text(locale, i18n.ReportNoResult)
+		}
+		if a.Result != nil {
+			for _, problem := range a.Result.ProblematicOperations {
+				p := htmlProblem{ProblematicOperation: problem}
+				if problem.Index != nil {
+					p.DDL = problem.Index.DDL()
+				}
+				view.Problems = append(view.Problems, p)
+			}
+		}
+		for _, root := range analyzer.Annotate(a.Plan) {
+			view.Roots = append(view.Roots, newHTMLNode(root, locale))
+		}
+		data.Analyses = append(data.Analyses, view)
+	}
+
+	return htmlTemplate.Execute(w, data)
+}
+
+// newHTMLText готовит заголовки отчета на языке locale
+func newHTMLText(locale string) htmlText {
+	return htmlText{
+		Title:           text(locale, i18n.ReportTitle),
+		Generated:       text(locale, i18n.ReportGenerated, "time", time.Now().Format("2006-01-02 15:04:05")),
+		Error:           text(locale, i18n.ReportError),
+		Cost:            text(locale, i18n.ReportCost),
+		Time:            text(locale, i18n.ReportTime),
+		ProblemCount:    text(locale, i18n.ReportProblemCount),
+		Plan:            text(locale, i18n.ReportPlan),
+		Recommendations: text(locale, i18n.ReportRecommendations),
+		Warnings:        text(locale, i18n.ReportWarnings),
+	}
+}
+
+// newHTMLNode рекурсивно готовит узел плана к выводу
+func newHTMLNode(node *analyzer.AnnotatedNode, locale string) htmlNode {
+	view := htmlNode{
+		Label: render.NodeLabel(node.Node),
+		Share: node.Share,
+		Time:  text(locale, i18n.ReportNodeCost, "cost", fmt.Sprintf("%.2f", node.ExclusiveCost)),
+	}
+	if node.Timed() {
+		view.Time = text(locale, i18n.ReportNodeTime, "time", fmt.Sprintf("%.3f", node.ExclusiveTime))
+	}
+	rows := fmt.Sprintf("— / %d", node.Node.PlanRows)
+	if node.Node.ActualRows != nil {
+		rows = fmt.Sprintf("%d / %d", *node.Node.ActualRows, node.Node.PlanRows)
+	}
+	view.Rows = text(locale, i18n.ReportNodeRows, "rows", rows)
+
+	if factor, under := node.MisestimateFactor(); factor >= analyzer.MisestimateThreshold {
+		direction := "↓"
+		if under {
+			direction = "↑"
+		}
+		view.Misestimate = text(locale, i18n.ReportNodeMisestimate, "direction", direction, "factor", fmt.Sprintf("%.0f", factor))
+		view.Severe = factor >= analyzer.MisestimateThreshold*analyzer.MisestimateThreshold
+	}
+
+	for _, detail := range []struct{ name, value string }{
+		{"Filter", node.Node.Filter},
+		{"Hash Cond", node.Node.HashCondition},
+		{"Join Type", node.Node.JoinType},
+	} {
+		if detail.value != "" {
+			view.Details = append(view.Details, detail.name+": "+detail.value)
+		}
+	}
+	if node.Loops() > 1 {
+		view.Details = append(view.Details, text(locale, i18n.ReportNodeLoops, "loops", strconv.Itoa(node.Loops())))
+	}
+
+	for _, child := range node.Children {
+		view.Children = append(view.Children, newHTMLNode(child, locale))
+	}
+	return view
+}
@@ -0,0 +1,96 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"sql-optimizer/internal/i18n"
+)
+
+// Структуры формата JUnit XML в объеме, который понимают CI-системы
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     float64         `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit записывает отчет в формате JUnit XML: каждый запрос — отдельный
+// тест. Тест падает при находках высокой серьезности, ошибка получения
+// плана становится ошибкой теста, остальные находки попадают в system-out.
+// Сообщение о падении пишется на языке locale.
+func JUnit(w io.Writer, analyses []Analysis, locale string) error {
+	suite := junitTestSuite{Name: "sql-optimizer", Tests: len(analyses)}
+
+	for _, a := range analyses {
+		testCase := junitTestCase{Name: queryTitle(a), ClassName: "sql-optimizer"}
+
+		switch {
+		case a.Err != nil:
+			testCase.Error = &junitMessage{Message: a.Err.Error(), Text: a.Query}
+			suite.Errors++
+
+		case a.Result != nil:
+			if t := a.Time(); t != nil {
+				testCase.Time = *t / 1000
+			}
+
+			var details strings.Builder
+			for _, problem := range a.Result.ProblematicOperations {
+				fmt.Fprintf(&details, "[%s] %s: %s. %s\n", problem.Severity, problem.NodeType,
+					problem.Description, problem.Recommendation)
+			}
+			for _, warning := range a.Result.Warnings {
+				fmt.Fprintf(&details, "[warning] %s\n", warning)
+			}
+
+			if a.Failed() {
+				testCase.Failure = &junitMessage{
+					Message: text(locale, i18n.ReportJUnitFailure, "count", strconv.Itoa(len(a.Result.ProblematicOperations))),
+					Text:    details.String(),
+				}
+				suite.Failures++
+			} else {
+				testCase.SystemOut = details.String()
+			}
+		}
+
+		suite.Time += testCase.Time
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
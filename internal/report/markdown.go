@@ -0,0 +1,117 @@
+package report
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/render"
+)
+
+// Markdown записывает отчет для комментариев к pull request и вики:
+// сводную таблицу, самые дорогие узлы, рекомендации с готовым SQL
+// и сворачиваемое дерево плана по каждому запросу. Заголовки пишутся
+// на языке locale.
+func Markdown(w io.Writer, analyses []Analysis, locale string) error {
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintf(b, "# %s\n\n", text(locale, i18n.ReportTitle))
+	fmt.Fprintf(b, "| # | %s | %s | %s | %s | %s |\n", text(locale, i18n.ReportQuery), text(locale, i18n.ReportCost),
+		text(locale, i18n.ReportTime), text(locale, i18n.ReportProblems), text(locale, i18n.ReportWarnings))
+	fmt.Fprintf(b, "|---|--------|----------:|----------:|---------:|---------------:|\n")
+	for i, a := range analyses {
+		if a.Result == nil {
+			fmt.Fprintf(b, "| %d | %s | — | — | %s | — |\n", i+1, escapeCell(queryTitle(a)), text(locale, i18n.ReportFailed))
+			continue
+		}
+		fmt.Fprintf(b, "| %d | %s | %.2f | %s | %d | %d |\n", i+1, escapeCell(queryTitle(a)),
+			a.Cost(), formatTime(a.Time()),
+			len(a.Result.ProblematicOperations), len(a.Result.Warnings))
+	}
+
+	for i, a := range analyses {
+		fmt.Fprintf(b, "\n## %d. %s\n\n", i+1, queryTitle(a))
+		if a.Query != "" {
+			fmt.Fprintf(b, "```sql\n%s\n```\n\n", strings.TrimSpace(a.Query))
+		}
+		if a.Err != nil {
+			fmt.Fprintf(b, "**%s:** %v\n", text(locale, i18n.ReportError), a.Err)
+			continue
+		}
+		writeMarkdownAnalysis(b, a, locale)
+	}
+
+	return b.Flush()
+}
+
+// writeMarkdownAnalysis записывает раздел отчета по одному запросу
+func writeMarkdownAnalysis(b *bufio.Writer, a Analysis, locale string) {
+	if a.Result != nil && a.Result.QueryFingerprint != "" {
+		fmt.Fprintf(b, "%s\n\n", text(locale, i18n.ReportFingerprints,
+			"query", "`"+a.Result.QueryFingerprint+"`", "plan", "`"+a.Result.PlanFingerprint+"`"))
+	}
+	if len(a.Plan) > 0 {
+		fmt.Fprintf(b, "### %s\n\n", text(locale, i18n.ReportWorstNodes))
+		fmt.Fprintf(b, "| %s | %s | %s | %s |\n", text(locale, i18n.ReportNode), text(locale, i18n.ReportExclusiveTime),
+			text(locale, i18n.ReportShare), text(locale, i18n.ReportRows))
+		fmt.Fprintf(b, "|------|------------------:|-----:|-----------------------:|\n")
+		for _, node := range analyzer.WorstNodes(analyzer.Annotate(a.Plan), worstNodesLimit) {
+			rows := fmt.Sprintf("— / %d", node.Node.PlanRows)
+			if node.Node.ActualRows != nil {
+				rows = fmt.Sprintf("%d / %d", *node.Node.ActualRows, node.Node.PlanRows)
+			}
+			exclusive := "—"
+			if node.Timed() {
+				exclusive = fmt.Sprintf("%.3f", node.ExclusiveTime)
+			}
+			fmt.Fprintf(b, "| %s | %s | %.1f%% | %s |\n",
+				escapeCell(render.NodeLabel(node.Node)), exclusive, node.Share, rows)
+		}
+		fmt.Fprintln(b)
+	}
+
+	if len(a.Result.ProblematicOperations) > 0 || len(a.Result.Recommendations) > 0 {
+		fmt.Fprintf(b, "### %s\n\n", text(locale, i18n.ReportRecommendations))
+		for _, problem := range a.Result.ProblematicOperations {
+			fmt.Fprintf(b, "- **%s** (%s): %s. %s\n", problem.NodeType, problem.Severity,
+				problem.Description, problem.Recommendation)
+			if problem.Index != nil {
+				fmt.Fprintf(b, "\n  ```sql\n  %s\n  ```\n", problem.Index.DDL())
+			}
+		}
+		for _, recommendation := range a.Result.Recommendations {
+			fmt.Fprintf(b, "- %s\n", recommendation)
+		}
+		fmt.Fprintln(b)
+	}
+
+	if len(a.Result.Warnings) > 0 {
+		fmt.Fprintf(b, "### %s\n\n", text(locale, i18n.ReportWarnings))
+		for _, warning := range a.Result.Warnings {
+			fmt.Fprintf(b, "- %s\n", warning)
+		}
+		fmt.Fprintln(b)
+	}
+
+	if len(a.Plan) > 0 {
+		fmt.Fprintf(b, "<details>\n<summary>%s</summary>\n\n```\n", text(locale, i18n.ReportPlan))
+		render.Text(b, a.Plan, render.TextOptions{})
+		fmt.Fprintf(b, "```\n\n</details>\n")
+	}
+}
+
+// escapeCell экранирует символы, ломающие таблицу Markdown
+func escapeCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+// formatTime форматирует необязательное время выполнения
+func formatTime(t *float64) string {
+	if t == nil {
+		return "—"
+	}
+	return fmt.Sprintf("%.3f", *t)
+}
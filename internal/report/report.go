@@ -0,0 +1,73 @@
+package report
+
+import (
+	"strings"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/i18n"
+)
+
+// worstNodesLimit — сколько самых дорогих узлов показывать в отчете
+const worstNodesLimit = 5
+
+// Analysis — анализ одного запроса, попадающий в отчет
+type Analysis struct {
+	Name   string // имя запроса в отчете, например файл:строка
+	Query  string
+	Plan   []analyzer.PlanNode
+	Result *analyzer.AnalysisResult
+	Err    error // ошибка получения или анализа плана
+}
+
+// Failed сообщает, есть ли у запроса находки высокой серьезности
+func (a Analysis) Failed() bool {
+	if a.Result == nil {
+		return false
+	}
+	for _, problem := range a.Result.ProblematicOperations {
+		if problem.Severity == "high" {
+			return true
+		}
+	}
+	return false
+}
+
+// Cost — стоимость запроса: Total Cost корневого узла плана. Стоимость
+// узла уже включает дочерние, поэтому Result.TotalCost, сумма по всем
+// узлам, для запроса не годится.
+func (a Analysis) Cost() float64 {
+	if len(a.Plan) == 0 {
+		return 0
+	}
+	return a.Plan[0].TotalCost
+}
+
+// Time — время выполнения запроса, мс: Actual Total Time корневого узла за
+// все его циклы; nil — план получен без ANALYZE
+func (a Analysis) Time() *float64 {
+	if len(a.Plan) == 0 || a.Plan[0].ActualTotalTime == nil {
+		return nil
+	}
+	total := *a.Plan[0].ActualTotalTime
+	if loops := a.Plan[0].ActualLoops; loops != nil && *loops > 1 {
+		total *= float64(*loops)
+	}
+	return &total
+}
+
+// text — текст отчета на языке locale
+func text(locale, id string, args ...string) string {
+	return i18n.New(id, args...).Format(locale)
+}
+
+// queryTitle сокращает текст запроса до одной строки для таблиц
+func queryTitle(a Analysis) string {
+	if a.Name != "" {
+		return a.Name
+	}
+	title := strings.Join(strings.Fields(a.Query), " ")
+	if len([]rune(title)) > 60 {
+		title = string([]rune(title)[:57]) + "..."
+	}
+	return title
+}
@@ -0,0 +1,176 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"sql-optimizer/internal/analyzer"
+)
+
+func testAnalyses(t *testing.T) []Analysis {
+	plan, err := analyzer.ParseExplainJSON(`[{"Plan": {
+		"Node Type": "Seq Scan", "Relation Name": "orders", "Filter": "((status)::text = 'new'::text)",
+		"Total Cost": 180.5, "Plan Rows": 10, "Actual Total Time": 12.5, "Actual Rows": 9, "Actual Loops": 1
+	}}]`)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	return []Analysis{
+		{
+			Name:   "orders.sql:1:1",
+			Query:  "SELECT * FROM orders WHERE status = 'new'",
+			Plan:   plan,
+			Result: analyzer.AnalyzeNodes(plan),
+		},
+		{
+			Name:  "broken.sql:1:1",
+			Query: "SELECT * FROM missing",
+			Err:   errors.New("relation \"missing\" does not exist"),
+		},
+	}
+}
+
+// nestedAnalysis — запрос, у корня плана которого есть дочерний узел:
+// его стоимость и время уже входят в стоимость и время корня
+func nestedAnalysis(t *testing.T) Analysis {
+	plan, err := analyzer.ParseExplainJSON(`[{"Plan": {
+		"Node Type": "Limit", "Total Cost": 200, "Plan Rows": 10, "Actual Total Time": 15, "Actual Rows": 10, "Actual Loops": 1,
+		"Plans": [{"Node Type": "Seq Scan", "Relation Name": "orders", "Total Cost": 180.5, "Plan Rows": 10,
+			"Actual Total Time": 12.5, "Actual Rows": 10, "Actual Loops": 1}]
+	}}]`)
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	return Analysis{Name: "limit.sql:1:1", Query: "SELECT * FROM orders LIMIT 10", Plan: plan, Result: analyzer.AnalyzeNodes(plan)}
+}
+
+func TestReportTotals(t *testing.T) {
+	analyses := []Analysis{nestedAnalysis(t)}
+
+	var markdown, junit, html bytes.Buffer
+	if err := Markdown(&markdown, analyses, "ru"); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if err := HTML(&html, analyses, "ru"); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if err := JUnit(&junit, analyses, "ru"); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if !strings.Contains(markdown.String(), "| 1 | limit.sql:1:1 | 200.00 | 15.000 |") {
+		t.Errorf("Стоимость и время запроса — по корню плана:\n%s", markdown.String())
+	}
+	if !strings.Contains(junit.String(), `time="0.015"`) {
+		t.Errorf("Время теста — по корню плана:\n%s", junit.String())
+	}
+	if !strings.Contains(html.String(), "<td>200.00</td>") || !strings.Contains(html.String(), "<td>15.000</td>") {
+		t.Errorf("Сводка HTML — по корню плана:\n%s", html.String())
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Markdown(&buf, testAnalyses(t), "ru"); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	out := buf.String()
+
+	expected := []string{
+		"| 1 | orders.sql:1:1 | 180.50 | 12.500 | 1 | 0 |",
+		"| 2 | broken.sql:1:1 | — | — | ошибка | — |",
+		"| Seq Scan on orders | 12.500 | 100.0% | 9 / 10 |",
+		"CREATE INDEX CONCURRENTLY idx_orders_status ON orders (status);",
+		"<summary>План выполнения</summary>",
+		"**Ошибка:** relation \"missing\" does not exist",
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(out, fragment) {
+			t.Errorf("В отчете нет фрагмента %q:\n%s", fragment, out)
+		}
+	}
+}
+
+func TestJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := JUnit(&buf, testAnalyses(t), "ru"); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	out := buf.String()
+
+	expected := []string{
+		`<testsuite name="sql-optimizer" tests="2" failures="1" errors="1"`,
+		`<testcase name="orders.sql:1:1" classname="sql-optimizer" time="0.0125">`,
+		`<failure message="проблемных операций: 1">`,
+		`<error message="relation &#34;missing&#34; does not exist">`,
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(out, fragment) {
+			t.Errorf("В отчете нет фрагмента %q:\n%s", fragment, out)
+		}
+	}
+}
+
+func TestHTML(t *testing.T) {
+	analyses := testAnalyses(t)
+	analyses[0].Query = "SELECT * FROM orders WHERE note = '<script>'"
+
+	var buf bytes.Buffer
+	if err := HTML(&buf, analyses, "ru"); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	out := buf.String()
+
+	expected := []string{
+		`<span class="label">Seq Scan on orders</span>`,
+		`style="width: 100.0%"`,
+		"CREATE INDEX CONCURRENTLY idx_orders_status ON orders (status);",
+		"&lt;script&gt;",
+		`<p class="error">Ошибка: relation &#34;missing&#34; does not exist</p>`,
+	}
+	for _, fragment := range expected {
+		if !strings.Contains(out, fragment) {
+			t.Errorf("В отчете нет фрагмента %q", fragment)
+		}
+	}
+	if strings.Contains(out, "<script") || strings.Contains(out, "http://") || strings.Contains(out, "https://") {
+		t.Errorf("Отчет не должен содержать скриптов и внешних ресурсов")
+	}
+}
+
+func TestReportLocale(t *testing.T) {
+	var markdown, html, junit bytes.Buffer
+	if err := Markdown(&markdown, testAnalyses(t), "en"); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if err := HTML(&html, testAnalyses(t), "en"); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if err := JUnit(&junit, testAnalyses(t), "en"); err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	for _, check := range []struct {
+		name, out string
+		expected  []string
+	}{
+		{"markdown", markdown.String(), []string{"# SQL Optimizer report", "| # | Query | Cost | Time, ms | Problems | Warnings |",
+			"### Most expensive nodes", "<summary>Execution plan</summary>", "**Error:** relation"}},
+		{"html", html.String(), []string{`<html lang="en">`, "<th>Cost</th>", "<h3>Execution plan</h3>",
+			"12.500 ms", "rows 9 / 10", `<p class="error">Error: relation`}},
+		{"junit", junit.String(), []string{`<failure message="problematic operations: 1">`}},
+	} {
+		for _, fragment := range check.expected {
+			if !strings.Contains(check.out, fragment) {
+				t.Errorf("%s: в отчете нет фрагмента %q:\n%s", check.name, fragment, check.out)
+			}
+		}
+		for _, russian := range []string{"Отчет", "Стоимость", "План выполнения", "Ошибка", "проблемных"} {
+			if strings.Contains(check.out, russian) {
+				t.Errorf("%s: в английском отчете остался текст %q", check.name, russian)
+			}
+		}
+	}
+}
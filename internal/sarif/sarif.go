@@ -0,0 +1,185 @@
+package sarif
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/sqlsource"
+)
+
+const (
+	Version   = "2.1.0"
+	SchemaURI = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName  = "sql-optimizer"
+
+	queryFingerprintKey = "queryFingerprint/v1"
+)
+
+// Entry связывает оператор из SQL-файла с результатом его анализа
+type Entry struct {
+	Statement sqlsource.Statement
+	Result    *analyzer.AnalysisResult
+}
+
+// Log — корневой объект SARIF-отчета
+type Log struct {
+	Version string `json:"version"`
+	Schema  string `json:"$schema"`
+	Runs    []Run  `json:"runs"`
+}
+
+type Run struct {
+	Tool       Tool     `json:"tool"`
+	ColumnKind string   `json:"columnKind"`
+	Results    []Result `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name  string `json:"name"`
+	Rules []Rule `json:"rules"`
+}
+
+type Rule struct {
+	ID               string  `json:"id"`
+	ShortDescription Message `json:"shortDescription"`
+}
+
+type Result struct {
+	RuleID    string     `json:"ruleId"`
+	Level     string     `json:"level"`
+	Message   Message    `json:"message"`
+	Locations []Location `json:"locations"`
+	// PartialFingerprints позволяют системам анализа кода узнавать уже
+	// известные находки при сравнении с базовой линией, даже если запрос
+	// переехал в другую строку файла
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           Region           `json:"region"`
+}
+
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type Region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+// Build формирует SARIF-отчет по проблемным операциям всех операторов;
+// описания правил берутся из каталога сообщений на языке locale
+func Build(entries []Entry, locale string) *Log {
+	run := Run{
+		Tool:       Tool{Driver: Driver{Name: toolName}},
+		ColumnKind: "unicodeCodePoints",
+		Results:    []Result{},
+	}
+
+	usedRules := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.Result == nil {
+			continue
+		}
+		for _, problem := range entry.Result.ProblematicOperations {
+			usedRules[problem.RuleID] = true
+
+			line, column := locate(entry.Statement, problem)
+			run.Results = append(run.Results, Result{
+				RuleID:  problem.RuleID,
+				Level:   level(problem.Severity),
+				Message: Message{Text: problem.Description + ". " + problem.Recommendation},
+				Locations: []Location{{
+					PhysicalLocation: PhysicalLocation{
+						ArtifactLocation: ArtifactLocation{URI: filepath.ToSlash(entry.Statement.File)},
+						Region:           Region{StartLine: line, StartColumn: column},
+					},
+				}},
+				PartialFingerprints: partialFingerprints(entry.Result, problem),
+			})
+		}
+	}
+
+	for id := range usedRules {
+		description := i18n.Format(locale, "rule."+id, nil)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, Rule{ID: id, ShortDescription: Message{Text: description}})
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+		return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+	})
+
+	return &Log{Version: Version, Schema: SchemaURI, Runs: []Run{run}}
+}
+
+// Write записывает отчет в формате JSON
+func Write(w io.Writer, log *Log) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(log)
+}
+
+// locate находит фрагмент SQL, к которому относится находка: условие WHERE
+// для фильтров, ссылку на таблицу, ORDER BY для сортировки, JOIN для
+// соединений. Если ничего не найдено, находка указывает на начало оператора.
+func locate(statement sqlsource.Statement, problem analyzer.ProblematicOperation) (int, int) {
+	offset := -1
+	if problem.Filter != "" {
+		offset = statement.LocatePredicate(analyzer.FilterColumns(problem.Filter))
+	}
+	if offset < 0 && problem.Relation != "" {
+		offset = statement.LocateTable(problem.Relation)
+	}
+	if offset < 0 && problem.RuleID == analyzer.RuleSort {
+		offset = statement.FindKeyword("order by")
+	}
+	if offset < 0 && problem.RuleID == analyzer.RuleExpensiveJoin {
+		offset = statement.FindKeyword("join")
+	}
+	if offset < 0 {
+		return statement.Line, statement.Column
+	}
+	return statement.Position(offset)
+}
+
+// partialFingerprints строит отпечаток находки из отпечатка запроса
+// и таблицы, к которой она относится
+func partialFingerprints(result *analyzer.AnalysisResult, problem analyzer.ProblematicOperation) map[string]string {
+	if result.QueryFingerprint == "" {
+		return nil
+	}
+	value := result.QueryFingerprint
+	if problem.Relation != "" {
+		value += "/" + problem.Relation
+	}
+	return map[string]string{queryFingerprintKey: value}
+}
+
+// level переводит серьезность находки в уровень SARIF
+func level(severity string) string {
+	switch severity {
+	case "high":
+		return "error"
+	case "medium":
+		return "warning"
+	default:
+		return "note"
+	}
+}
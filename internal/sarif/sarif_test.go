@@ -0,0 +1,71 @@
+package sarif
+
+import (
+	"testing"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/sqlsource"
+)
+
+func TestBuild(t *testing.T) {
+	src := "\n\nSELECT *\nFROM orders\nWHERE amount > 10 AND status = 'new'\nORDER BY id;"
+	statement := sqlsource.Split("queries/orders.sql", src)[0]
+
+	result := &analyzer.AnalysisResult{
+		QueryFingerprint: "0123456789abcdef",
+		ProblematicOperations: []analyzer.ProblematicOperation{
+			{
+				RuleID:         analyzer.RuleSeqScan,
+				NodeType:       "Seq Scan",
+				Relation:       "orders",
+				Filter:         "((status)::text = 'new'::text)",
+				Description:    "Sequential Scan на таблице orders",
+				Recommendation: "Добавить индекс на используемые в WHERE поля",
+				Severity:       "high",
+			},
+			{
+				RuleID:   analyzer.RuleSort,
+				NodeType: "Sort",
+				Severity: "medium",
+			},
+		},
+	}
+
+	log := Build([]Entry{{Statement: statement, Result: result}}, "en")
+	if log.Version != Version || len(log.Runs) != 1 {
+		t.Fatalf("Неверная структура отчета: %+v", log)
+	}
+
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 2 || run.Tool.Driver.Rules[0].ID != analyzer.RuleSeqScan ||
+		run.Tool.Driver.Rules[0].ShortDescription.Text != "Sequential table scan" {
+		t.Errorf("Неверный список правил: %+v", run.Tool.Driver.Rules)
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("Ожидали 2 результата, получили %d", len(run.Results))
+	}
+
+	testCases := []struct {
+		level  string
+		line   int
+		column int
+	}{
+		{"error", 5, 23},
+		{"warning", 6, 1},
+	}
+
+	for i, tc := range testCases {
+		got := run.Results[i]
+		region := got.Locations[0].PhysicalLocation.Region
+		if got.Level != tc.level || region.StartLine != tc.line || region.StartColumn != tc.column {
+			t.Errorf("Результат %d: ожидали %s в %d:%d, получили %s в %d:%d",
+				i, tc.level, tc.line, tc.column, got.Level, region.StartLine, region.StartColumn)
+		}
+		if i == 0 && got.PartialFingerprints[queryFingerprintKey] != "0123456789abcdef/orders" {
+			t.Errorf("Неверный отпечаток находки: %v", got.PartialFingerprints)
+		}
+		if uri := got.Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "queries/orders.sql" {
+			t.Errorf("Неверный URI: %s", uri)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Package sqlscan делит текст SQL на фрагменты: пробелы, комментарии,
+// строковые константы, идентификаторы в кавычках и слова. На нем построены
+// разбиение SQL-файлов на операторы и нормализация запросов для отпечатков,
+// поэтому оба видят границы строк и комментариев одинаково.
+package sqlscan
+
+import "strings"
+
+// Kind — вид фрагмента текста
+type Kind int
+
+const (
+	Space       Kind = iota // пробелы и переводы строк
+	Comment                 // -- до конца строки или /* */, в том числе вложенный
+	String                  // '...', E'...', B'...', X'...', N'...', U&'...' или $tag$...$tag$
+	QuotedIdent             // идентификатор в кавычках, "" внутри — кавычка
+	Word                    // ключевое слово или идентификатор без кавычек
+	Other                   // один байт чего-то еще: числа, операторы, параметры $n
+)
+
+// Next определяет фрагмент, который начинается в позиции i, и возвращает
+// его вид и позицию после него. Незакрытые комментарий, строка или
+// идентификатор продолжаются до конца текста.
+func Next(src string, i int) (Kind, int) {
+	c := src[i]
+	switch {
+	case IsSpace(c):
+		j := i + 1
+		for j < len(src) && IsSpace(src[j]) {
+			j++
+		}
+		return Space, j
+
+	case strings.HasPrefix(src[i:], "--"):
+		end := strings.IndexByte(src[i:], '\n')
+		if end < 0 {
+			return Comment, len(src)
+		}
+		return Comment, i + end
+
+	case strings.HasPrefix(src[i:], "/*"):
+		return Comment, skipBlockComment(src, i)
+
+	case c == '\'':
+		return String, skipString(src, i, false)
+
+	case c == '"':
+		for j := i + 1; j < len(src); j++ {
+			if src[j] == '"' {
+				if j+1 < len(src) && src[j+1] == '"' {
+					j++
+					continue
+				}
+				return QuotedIdent, j + 1
+			}
+		}
+		return QuotedIdent, len(src)
+
+	case c == '$':
+		tag, ok := dollarTag(src[i:])
+		if !ok {
+			return Other, i + 1
+		}
+		end := strings.Index(src[i+len(tag):], tag)
+		if end < 0 {
+			return String, len(src)
+		}
+		return String, i + len(tag) + end + len(tag)
+
+	case IsIdentStart(c):
+		j := i
+		for j < len(src) && IsIdentByte(src[j]) {
+			j++
+		}
+		// Строки с префиксом: E'...', B'...', X'...', N'...', U&'...'
+		word := strings.ToLower(src[i:j])
+		switch {
+		case j < len(src) && src[j] == '\'' && len(word) == 1 && strings.Contains("ebxn", word):
+			return String, skipString(src, j, word == "e")
+		case word == "u" && strings.HasPrefix(src[j:], "&'"):
+			return String, skipString(src, j+1, false)
+		}
+		return Word, j
+	}
+	return Other, i + 1
+}
+
+// skipBlockComment возвращает позицию после блочного комментария,
+// который в PostgreSQL может быть вложенным
+func skipBlockComment(src string, i int) int {
+	depth := 0
+	for i < len(src) {
+		switch {
+		case strings.HasPrefix(src[i:], "/*"):
+			depth++
+			i += 2
+		case strings.HasPrefix(src[i:], "*/"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return i
+}
+
+// skipString возвращает позицию после строки, начинающейся с кавычки
+// в позиции i; в строках E'...' обратная косая черта экранирует символ
+func skipString(src string, i int, escapes bool) int {
+	for j := i + 1; j < len(src); j++ {
+		switch {
+		case escapes && src[j] == '\\':
+			j++
+		case src[j] == '\'':
+			if j+1 < len(src) && src[j+1] == '\'' {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(src)
+}
+
+// dollarTag возвращает открывающий тег вида $$ или $name$
+func dollarTag(s string) (string, bool) {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '$' {
+			return s[:i+1], true
+		}
+		if !IsIdentByte(s[i]) || (i == 1 && IsDigit(s[i])) {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// IsSpace сообщает, является ли байт пробельным символом
+func IsSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+// IsDigit сообщает, является ли байт десятичной цифрой
+func IsDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// IsIdentStart сообщает, может ли с байта начинаться идентификатор без
+// кавычек; байты многобайтных символов UTF-8 считаются буквами
+func IsIdentStart(b byte) bool {
+	return b == '_' || b >= 0x80 || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// IsIdentByte сообщает, может ли байт продолжать идентификатор без кавычек
+func IsIdentByte(b byte) bool {
+	return IsIdentStart(b) || IsDigit(b) || b == '$'
+}
@@ -0,0 +1,41 @@
+package sqlscan
+
+import "testing"
+
+func TestNext(t *testing.T) {
+	src := "SELECT E'it\\'s;', $tag$ ; $tag$, \"odd\"\"name\" /* a /* b */ c */ -- конец\n$1"
+
+	type fragment struct {
+		kind Kind
+		text string
+	}
+	want := []fragment{
+		{Word, "SELECT"}, {Space, " "}, {String, "E'it\\'s;'"}, {Other, ","}, {Space, " "},
+		{String, "$tag$ ; $tag$"}, {Other, ","}, {Space, " "}, {QuotedIdent, "\"odd\"\"name\""}, {Space, " "},
+		{Comment, "/* a /* b */ c */"}, {Space, " "}, {Comment, "-- конец"}, {Space, "\n"},
+		{Other, "$"}, {Other, "1"},
+	}
+
+	var got []fragment
+	for i := 0; i < len(src); {
+		kind, end := Next(src, i)
+		got = append(got, fragment{kind, src[i:end]})
+		i = end
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Ожидали %d фрагментов, получили %d: %q", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Фрагмент %d: ожидали %q, получили %q", i, want[i], got[i])
+		}
+	}
+}
+
+func TestNextUnterminated(t *testing.T) {
+	for _, src := range []string{"'abc", "\"abc", "/* abc", "$$ abc"} {
+		if _, end := Next(src, 0); end != len(src) {
+			t.Errorf("%q: незакрытый фрагмент должен идти до конца текста, конец %d", src, end)
+		}
+	}
+}
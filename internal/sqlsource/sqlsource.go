@@ -0,0 +1,219 @@
+package sqlsource
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"sql-optimizer/internal/sqlscan"
+)
+
+// Statement — один SQL-оператор из файла вместе с позицией его начала
+type Statement struct {
+	File   string
+	Text   string
+	Line   int // строка начала оператора, с 1
+	Column int // столбец начала оператора в символах, с 1
+
+	// masked совпадает с Text по длине, но комментарии и строковые
+	// литералы в нем заменены пробелами — по нему ведется поиск
+	masked string
+}
+
+// ParseFile читает файл и разбивает его на операторы
+func ParseFile(path string) ([]Statement, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения файла %s: %v", path, err)
+	}
+	return Split(path, string(data)), nil
+}
+
+// Split разбивает текст на операторы по ";" с учетом комментариев,
+// строк, идентификаторов в кавычках и dollar-quoted блоков.
+// Пустые операторы и операторы из одних комментариев пропускаются.
+func Split(file, src string) []Statement {
+	masked := mask(src)
+
+	var statements []Statement
+	start := 0
+	for i := 0; i <= len(masked); i++ {
+		if i < len(masked) && masked[i] != ';' {
+			continue
+		}
+
+		// Начало оператора — первый значимый символ после комментариев
+		begin := start
+		for begin < i && sqlscan.IsSpace(masked[begin]) {
+			begin++
+		}
+		if begin < i {
+			text := strings.TrimRightFunc(src[begin:i], unicode.IsSpace)
+			line, col := position(src, 1, 1, begin)
+			statements = append(statements, Statement{
+				File:   file,
+				Text:   text,
+				Line:   line,
+				Column: col,
+				masked: masked[begin : begin+len(text)],
+			})
+		}
+		start = i + 1
+	}
+
+	return statements
+}
+
+// Position переводит байтовое смещение внутри оператора в строку и столбец файла
+func (s Statement) Position(offset int) (line, column int) {
+	return position(s.Text, s.Line, s.Column, offset)
+}
+
+// FindIdentifier ищет первое вхождение идентификатора name (без учета
+// регистра, в том числе в кавычках) начиная со смещения from.
+// Возвращает -1, если идентификатор не найден.
+func (s Statement) FindIdentifier(name string, from int) int {
+	if name == "" {
+		return -1
+	}
+	text := strings.ToLower(s.masked)
+	needle := strings.ToLower(name)
+
+	for from < len(text) {
+		idx := strings.Index(text[from:], needle)
+		if idx < 0 {
+			return -1
+		}
+		idx += from
+		end := idx + len(needle)
+
+		before := idx == 0 || !sqlscan.IsIdentByte(text[idx-1])
+		after := end >= len(text) || !sqlscan.IsIdentByte(text[end])
+		if before && after {
+			return idx
+		}
+		from = idx + 1
+	}
+
+	return -1
+}
+
+// FindKeyword ищет ключевое слово или последовательность слов
+// (например "order by") с произвольными пробелами между ними.
+// Возвращает -1, если ничего не найдено.
+func (s Statement) FindKeyword(keyword string) int {
+	words := strings.Fields(keyword)
+	if len(words) == 0 {
+		return -1
+	}
+
+	for from := 0; ; {
+		idx := s.FindIdentifier(words[0], from)
+		if idx < 0 {
+			return -1
+		}
+		pos := idx + len(words[0])
+		matched := true
+		for _, word := range words[1:] {
+			for pos < len(s.masked) && sqlscan.IsSpace(s.masked[pos]) {
+				pos++
+			}
+			if s.FindIdentifier(word, pos) != pos {
+				matched = false
+				break
+			}
+			pos += len(word)
+		}
+		if matched {
+			return idx
+		}
+		from = idx + 1
+	}
+}
+
+// LocateTable возвращает смещение ссылки на таблицу: сначала ищется
+// вхождение после FROM, затем в любом месте оператора
+func (s Statement) LocateTable(name string) int {
+	if from := s.FindKeyword("from"); from >= 0 {
+		if idx := s.FindIdentifier(name, from); idx >= 0 {
+			return idx
+		}
+	}
+	return s.FindIdentifier(name, 0)
+}
+
+// LocatePredicate возвращает смещение первого из столбцов условия,
+// встретившегося после WHERE. Если столбцы не найдены, возвращается
+// позиция самого WHERE либо -1.
+func (s Statement) LocatePredicate(columns []string) int {
+	where := s.FindKeyword("where")
+	if where < 0 {
+		return -1
+	}
+
+	best := -1
+	for _, column := range columns {
+		if idx := s.FindIdentifier(column, where); idx >= 0 && (best < 0 || idx < best) {
+			best = idx
+		}
+	}
+	if best < 0 {
+		return where
+	}
+	return best
+}
+
+// mask заменяет пробелами комментарии и строковые литералы, сохраняя
+// переводы строк и длину текста, чтобы смещения оставались верными
+func mask(src string) string {
+	out := []byte(src)
+	blank := func(from, to int) {
+		for i := from; i < to && i < len(out); i++ {
+			if out[i] != '\n' {
+				out[i] = ' '
+			}
+		}
+	}
+
+	for i := 0; i < len(src); {
+		kind, end := sqlscan.Next(src, i)
+		switch kind {
+		case sqlscan.Comment, sqlscan.String:
+			blank(i, end)
+
+		case sqlscan.QuotedIdent:
+			// Идентификатор в кавычках оставляем, убираем только сами кавычки
+			// и точки с запятой, чтобы они не разделяли операторы
+			blank(i, i+1)
+			if end-1 > i && src[end-1] == '"' {
+				blank(end-1, end)
+			}
+			for j := i + 1; j < end; j++ {
+				if src[j] == ';' {
+					out[j] = ' '
+				}
+			}
+		}
+		i = end
+	}
+
+	return string(out)
+}
+
+// position отсчитывает строку и столбец (в символах) от начала text,
+// которому соответствуют line и column
+func position(text string, line, column, offset int) (int, int) {
+	for i := 0; i < offset && i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if r == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+		i += size
+	}
+	return line, column
+}
@@ -0,0 +1,76 @@
+package sqlsource
+
+import (
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	src := "-- отчет по заказам\n" +
+		"SELECT * FROM orders WHERE status = 'a;b';\n" +
+		"\n" +
+		"/* пустой */ ;\n" +
+		"  SELECT $$;$$, \"odd;name\"\n" +
+		"  FROM users;\n" +
+		"SELECT E'it\\'s; fine';"
+
+	statements := Split("q.sql", src)
+	if len(statements) != 3 {
+		t.Fatalf("Ожидали 3 оператора, получили %d: %+v", len(statements), statements)
+	}
+
+	testCases := []struct {
+		text   string
+		line   int
+		column int
+	}{
+		{"SELECT * FROM orders WHERE status = 'a;b'", 2, 1},
+		{"SELECT $$;$$, \"odd;name\"\n  FROM users", 5, 3},
+		// В строке E'...' обратная косая черта экранирует кавычку
+		{"SELECT E'it\\'s; fine'", 7, 1},
+	}
+
+	for i, tc := range testCases {
+		got := statements[i]
+		if got.Text != tc.text || got.Line != tc.line || got.Column != tc.column {
+			t.Errorf("Оператор %d: ожидали %q в %d:%d, получили %q в %d:%d",
+				i, tc.text, tc.line, tc.column, got.Text, got.Line, got.Column)
+		}
+	}
+}
+
+func TestLocate(t *testing.T) {
+	src := "SELECT u.name -- orders здесь не считается\n" +
+		"FROM users u\n" +
+		"JOIN orders o ON o.user_id = u.id\n" +
+		"WHERE o.status = 'orders' AND o.amount > 100\n" +
+		"ORDER BY u.name;"
+	statement := Split("q.sql", src)[0]
+
+	testCases := []struct {
+		name   string
+		offset int
+		line   int
+		column int
+	}{
+		{"Таблица после FROM", statement.LocateTable("orders"), 3, 6},
+		{"Условие WHERE", statement.LocatePredicate([]string{"amount", "status"}), 4, 9},
+		{"WHERE без известных столбцов", statement.LocatePredicate([]string{"missing"}), 4, 1},
+		{"Ключевое слово из двух слов", statement.FindKeyword("order by"), 5, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.offset < 0 {
+				t.Fatalf("Фрагмент не найден")
+			}
+			line, column := statement.Position(tc.offset)
+			if line != tc.line || column != tc.column {
+				t.Errorf("Ожидали %d:%d, получили %d:%d", tc.line, tc.column, line, column)
+			}
+		})
+	}
+
+	if idx := statement.FindIdentifier("missing", 0); idx != -1 {
+		t.Errorf("Ожидали -1 для отсутствующего идентификатора, получили %d", idx)
+	}
+}
@@ -0,0 +1,413 @@
+package statistics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+	"sql-optimizer/internal/quote"
+)
+
+// Причины рекомендации расширенной статистики
+const (
+	ReasonFilter = "filter" // условие на несколько столбцов одной таблицы
+	ReasonGroup  = "group"  // группировка по нескольким столбцам
+)
+
+// Виды расширенной статистики
+const (
+	KindDependencies = "dependencies"
+	KindNDistinct    = "ndistinct"
+	KindMCV          = "mcv"
+)
+
+// kindCodes — коды видов статистики в pg_statistic_ext.stxkind
+var kindCodes = map[string]string{KindDependencies: "d", KindNDistinct: "f", KindMCV: "m"}
+
+// qualified — ссылка на столбец с префиксом отношения: o.city
+var qualified = regexp.MustCompile(`\b([a-z_][a-z0-9_$]*)\.([a-z_][a-z0-9_$]*)`)
+
+// plainColumn — ключ группировки, который является столбцом, а не выражением
+var plainColumn = regexp.MustCompile(`^(?:([a-z_][a-z0-9_$]*)\.)?([a-z_][a-z0-9_$]*)$`)
+
+// ExtendedSource — источник статистики столбцов; его реализует
+// *postgres.Client
+type ExtendedSource interface {
+	ColumnStats(ctx context.Context, table string, columns []string) ([]postgres.ColumnStats, error)
+	ExtendedStatistics(ctx context.Context) ([]postgres.ExtendedStatistic, error)
+	ExplainWithStatistics(ctx context.Context, query string, ddl []string, analyze []postgres.AnalyzeTarget, timeout time.Duration) (string, error)
+}
+
+// Candidate — узел плана, оценку которого может исправить расширенная
+// статистика
+type Candidate struct {
+	Table     string   `json:"table"`
+	Alias     string   `json:"alias,omitempty"`
+	Columns   []string `json:"columns"`
+	Kinds     []string `json:"kinds"`
+	Reason    string   `json:"reason"`
+	NodeType  string   `json:"node_type"`
+	Condition string   `json:"condition"` // условие или ключ группировки
+	Planned   int      `json:"planned_rows"`
+	Actual    int      `json:"actual_rows"`
+	Factor    float64  `json:"factor"` // во сколько раз ошиблась оценка
+
+	groupKey []string
+}
+
+// ExtendedRecommendation — предлагаемый объект расширенной статистики
+type ExtendedRecommendation struct {
+	Candidate
+	Stats   []postgres.ColumnStats `json:"column_stats"`
+	Name    string                 `json:"name"`
+	DDL     string                 `json:"ddl"`
+	Analyze string                 `json:"analyze"`
+	// Estimate — оценка строк узла после CREATE STATISTICS и ANALYZE;
+	// nil, если проверка не выполнялась или узел не найден в новом плане
+	Estimate    *int         `json:"estimate_after,omitempty"`
+	Improved    *bool        `json:"improved,omitempty"`
+	VerifyError string       `json:"verify_error,omitempty"`
+	Message     i18n.Message `json:"message"`
+	Description string       `json:"description"`
+	// Result — вывод проверки
+	Result            *i18n.Message `json:"result,omitempty"`
+	ResultDescription string        `json:"result_description,omitempty"`
+}
+
+// ExtendedReport — рекомендации расширенной статистики для запроса
+type ExtendedReport struct {
+	Recommendations []ExtendedRecommendation `json:"recommendations"`
+}
+
+// AdviseExtended находит в плане, полученном с ANALYZE, ошибки оценок
+// по нескольким столбцам и предлагает объекты расширенной статистики
+func AdviseExtended(ctx context.Context, src ExtendedSource, query string, plan []analyzer.PlanNode, opts Options) (*ExtendedReport, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	report := &ExtendedReport{Recommendations: []ExtendedRecommendation{}}
+	candidates := Candidates(plan)
+	if len(candidates) == 0 {
+		return report, nil
+	}
+
+	existing, err := src.ExtendedStatistics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range candidates {
+		c.Kinds = missingKinds(c, existing)
+		if len(c.Kinds) == 0 {
+			continue
+		}
+		stats, err := src.ColumnStats(ctx, c.Table, c.Columns)
+		if err != nil {
+			return nil, err
+		}
+		rec := recommend(c, stats)
+		if opts.Verify {
+			verify(ctx, src, query, &rec, opts.Timeout)
+		}
+		report.Recommendations = append(report.Recommendations, rec)
+	}
+	report.Localize(i18n.DefaultLocale)
+	return report, nil
+}
+
+// Localize формирует тексты рекомендаций на языке locale
+func (r *ExtendedReport) Localize(locale string) {
+	for i := range r.Recommendations {
+		rec := &r.Recommendations[i]
+		rec.Description = rec.Message.Format(locale)
+		if rec.Result != nil {
+			rec.ResultDescription = rec.Result.Format(locale)
+		}
+	}
+}
+
+// Candidates находит узлы, оценка строк которых ошиблась больше чем
+// в analyzer.MisestimateThreshold раз: сканирования с условием на
+// несколько столбцов таблицы и группировки по нескольким столбцам.
+// Одинаковые сочетания столбцов объединяются.
+func Candidates(plan []analyzer.PlanNode) []Candidate {
+	var candidates []Candidate
+	index := make(map[string]int)
+	add := func(c Candidate) {
+		sort.Strings(c.Columns)
+		key := c.Table + "|" + strings.Join(c.Columns, ",")
+		if i, ok := index[key]; ok {
+			for _, kind := range c.Kinds {
+				if !slices.Contains(candidates[i].Kinds, kind) {
+					candidates[i].Kinds = append(candidates[i].Kinds, kind)
+				}
+			}
+			return
+		}
+		index[key] = len(candidates)
+		candidates = append(candidates, c)
+	}
+
+	for _, node := range analyzer.Flatten(analyzer.Annotate(plan)) {
+		factor, _ := node.MisestimateFactor()
+		if factor < analyzer.MisestimateThreshold || node.Node.ActualRows == nil {
+			continue
+		}
+		base := Candidate{
+			NodeType: node.Node.NodeType,
+			Planned:  node.Node.PlanRows,
+			Actual:   *node.Node.ActualRows,
+			Factor:   factor,
+		}
+		if c, ok := filterCandidate(node.Node, base); ok {
+			add(c)
+		}
+		for _, c := range groupCandidates(node.Node, base) {
+			add(c)
+		}
+	}
+	return candidates
+}
+
+// filterCandidate проверяет условие сканирования таблицы
+func filterCandidate(node *analyzer.PlanNode, c Candidate) (Candidate, bool) {
+	if node.RelationName == "" {
+		return c, false
+	}
+	var conditions []string
+	for _, cond := range []string{node.IndexCondition, node.Filter} {
+		if cond != "" {
+			conditions = append(conditions, cond)
+		}
+	}
+	condition := strings.Join(conditions, " AND ")
+	alias := node.Alias
+	if alias == "" {
+		alias = node.RelationName
+	}
+	columns := ownColumns(condition, alias)
+	if len(columns) < 2 {
+		return c, false
+	}
+	c.Table, c.Alias, c.Columns, c.Condition = node.RelationName, alias, columns, condition
+	c.Reason, c.Kinds = ReasonFilter, []string{KindDependencies, KindMCV}
+	return c, true
+}
+
+// ownColumns возвращает столбцы условия, относящиеся к отношению alias:
+// в условии соединения встречаются и столбцы других таблиц
+func ownColumns(condition, alias string) []string {
+	own := qualified.ReplaceAllStringFunc(condition, func(ref string) string {
+		parts := qualified.FindStringSubmatch(ref)
+		if parts[1] == alias {
+			return parts[2]
+		}
+		return "''"
+	})
+	return analyzer.FilterColumns(own)
+}
+
+// groupCandidates проверяет ключ группировки: столбцы группируются по
+// таблицам, и для каждой таблицы с двумя и более столбцами получается
+// отдельный кандидат
+func groupCandidates(node *analyzer.PlanNode, c Candidate) []Candidate {
+	if len(node.GroupKey) < 2 {
+		return nil
+	}
+	relations := make(map[string]string) // псевдоним → таблица
+	collectRelations(node, relations)
+
+	byTable := make(map[string][]string)
+	var tables []string
+	for _, key := range node.GroupKey {
+		parts := plainColumn.FindStringSubmatch(strings.Trim(key, "()"))
+		if parts == nil {
+			continue
+		}
+		table := relations[parts[1]]
+		if parts[1] == "" && len(relations) == 1 {
+			for _, t := range relations {
+				table = t
+			}
+		}
+		if table == "" {
+			continue
+		}
+		if _, ok := byTable[table]; !ok {
+			tables = append(tables, table)
+		}
+		byTable[table] = append(byTable[table], parts[2])
+	}
+
+	var candidates []Candidate
+	for _, table := range tables {
+		if len(byTable[table]) < 2 {
+			continue
+		}
+		g := c
+		g.Table, g.Columns, g.Condition = table, byTable[table], strings.Join(node.GroupKey, ", ")
+		g.Reason, g.Kinds, g.groupKey = ReasonGroup, []string{KindNDistinct}, node.GroupKey
+		candidates = append(candidates, g)
+	}
+	return candidates
+}
+
+// collectRelations собирает таблицы, которые читает поддерево
+func collectRelations(node *analyzer.PlanNode, relations map[string]string) {
+	if node.RelationName != "" {
+		alias := node.Alias
+		if alias == "" {
+			alias = node.RelationName
+		}
+		relations[alias] = node.RelationName
+	}
+	for i := range node.Plans {
+		collectRelations(&node.Plans[i], relations)
+	}
+}
+
+// missingKinds возвращает виды статистики кандидата, которых еще нет
+// ни в одном объекте статистики таблицы по этим столбцам
+func missingKinds(c Candidate, existing []postgres.ExtendedStatistic) []string {
+	var missing []string
+	for _, kind := range c.Kinds {
+		covered := false
+		for _, s := range existing {
+			if s.Table == c.Table && slices.Contains(s.Kinds, kindCodes[kind]) && containsAll(s.Columns, c.Columns) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			missing = append(missing, kind)
+		}
+	}
+	return missing
+}
+
+// recommend составляет команду и обоснование для кандидата
+func recommend(c Candidate, stats []postgres.ColumnStats) ExtendedRecommendation {
+	columns := make([]string, len(c.Columns))
+	for i, column := range c.Columns {
+		columns[i] = quote.Ident(column)
+	}
+	rec := ExtendedRecommendation{
+		Candidate: c,
+		Stats:     stats,
+		Name:      "stat_" + c.Table + "_" + strings.Join(c.Columns, "_"),
+		Analyze:   fmt.Sprintf("ANALYZE %s;", quote.Ident(c.Table)),
+	}
+	if rec.Stats == nil {
+		rec.Stats = []postgres.ColumnStats{}
+	}
+	rec.DDL = fmt.Sprintf("CREATE STATISTICS %s (%s) ON %s FROM %s;",
+		quote.Ident(rec.Name), strings.Join(c.Kinds, ", "), strings.Join(columns, ", "), quote.Ident(c.Table))
+
+	args := []string{
+		"table", c.Table,
+		"columns", strings.Join(c.Columns, ", "),
+		"factor", strconv.FormatFloat(c.Factor, 'f', 0, 64),
+		"planned", strconv.Itoa(c.Planned),
+		"actual", strconv.Itoa(c.Actual),
+	}
+	if c.Reason == ReasonGroup {
+		rec.Message = i18n.New(i18n.StatisticsExtendedGroup, append(args, "distinct", distinctProduct(c.Columns, stats))...)
+	} else {
+		rec.Message = i18n.New(i18n.StatisticsExtendedFilter, args...)
+	}
+	return rec
+}
+
+// distinctProduct записывает числа различных значений столбцов, которые
+// планировщик перемножает, оценивая число групп: "city 1200 × zip 30000"
+func distinctProduct(columns []string, stats []postgres.ColumnStats) string {
+	var parts []string
+	for _, column := range columns {
+		for _, s := range stats {
+			if s.Column == column {
+				parts = append(parts, column+" "+strconv.FormatFloat(math.Round(s.Distinct), 'f', 0, 64))
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return "n_distinct"
+	}
+	return strings.Join(parts, " × ")
+}
+
+// verify создает статистику в откатываемой транзакции и сравнивает новую
+// оценку узла с фактическим числом строк. ANALYZE собирает только столбцы
+// рекомендации: этого достаточно для нового объекта статистики, а счетчик
+// n_mod_since_analyze таблицы не обнуляется
+func verify(ctx context.Context, src ExtendedSource, query string, rec *ExtendedRecommendation, timeout time.Duration) {
+	analyze := []postgres.AnalyzeTarget{{Table: rec.Table, Columns: rec.Columns}}
+	planJSON, err := src.ExplainWithStatistics(ctx, query, []string{rec.DDL}, analyze, timeout)
+	if err != nil {
+		rec.VerifyError = err.Error()
+		return
+	}
+	plan, err := analyzer.ParseExplainJSON(planJSON)
+	if err != nil {
+		rec.VerifyError = "не удалось разобрать план: " + err.Error()
+		return
+	}
+	estimate, ok := findEstimate(plan, rec.Candidate)
+	if !ok {
+		return
+	}
+	improved := estimateError(estimate, rec.Actual) < estimateError(rec.Planned, rec.Actual)
+	rec.Estimate, rec.Improved = &estimate, &improved
+
+	id := i18n.StatisticsExtendedUnchanged
+	if improved {
+		id = i18n.StatisticsExtendedImproved
+	}
+	result := i18n.New(id, "after", strconv.Itoa(estimate), "actual", strconv.Itoa(rec.Actual), "planned", strconv.Itoa(rec.Planned))
+	rec.Result = &result
+}
+
+// findEstimate находит в новом плане узел кандидата: сканирование того же
+// отношения или группировку с тем же ключом
+func findEstimate(plan []analyzer.PlanNode, c Candidate) (int, bool) {
+	for _, node := range analyzer.Flatten(analyzer.Annotate(plan)) {
+		n := node.Node
+		switch c.Reason {
+		case ReasonFilter:
+			alias := n.Alias
+			if alias == "" {
+				alias = n.RelationName
+			}
+			if n.RelationName == c.Table && alias == c.Alias {
+				return n.PlanRows, true
+			}
+		case ReasonGroup:
+			if strings.Join(n.GroupKey, ", ") == strings.Join(c.groupKey, ", ") {
+				return n.PlanRows, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// estimateError возвращает, во сколько раз оценка отличается от факта
+func estimateError(estimate, actual int) float64 {
+	ratio := float64(max(estimate, 1)) / float64(max(actual, 1))
+	return max(ratio, 1/ratio)
+}
+
+func containsAll(list, items []string) bool {
+	for _, item := range items {
+		if !slices.Contains(list, item) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,140 @@
+package statistics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/postgres"
+)
+
+func intPtr(i int) *int { return &i }
+
+func floatPtr(f float64) *float64 { return &f }
+
+// extendedPlan — группировка заказов по региону и каналу поверх соединения
+// с адресами, отфильтрованными по городу и индексу
+func extendedPlan() []analyzer.PlanNode {
+	return []analyzer.PlanNode{{
+		NodeType: "Aggregate", GroupKey: []string{"o.region", "o.channel"}, PlanRows: 40000, ActualRows: intPtr(60),
+		ActualTotalTime: floatPtr(90), ActualLoops: intPtr(1),
+		Plans: []analyzer.PlanNode{{
+			NodeType: "Nested Loop", PlanRows: 40000, ActualRows: intPtr(40000), ActualLoops: intPtr(1),
+			ActualTotalTime: floatPtr(80),
+			Plans: []analyzer.PlanNode{
+				{NodeType: "Seq Scan", RelationName: "addresses", Alias: "a",
+					Filter: "((a.city = 'Москва'::text) AND (a.zip = '101000'::text))", PlanRows: 3, ActualRows: intPtr(800),
+					ActualTotalTime: floatPtr(20), ActualLoops: intPtr(1)},
+				{NodeType: "Index Scan", RelationName: "orders", Alias: "o", IndexName: "orders_address_id_idx",
+					IndexCondition: "(o.address_id = a.id)", PlanRows: 50, ActualRows: intPtr(50),
+					ActualTotalTime: floatPtr(0.05), ActualLoops: intPtr(800)},
+			},
+		}},
+	}}
+}
+
+type fakeSource struct {
+	existing []postgres.ExtendedStatistic
+	states   []postgres.AnalyzeState
+	plan     string
+	ddl      []string
+	analyzed []postgres.AnalyzeTarget
+}
+
+func (f *fakeSource) ColumnStats(ctx context.Context, table string, columns []string) ([]postgres.ColumnStats, error) {
+	var stats []postgres.ColumnStats
+	for i, column := range columns {
+		stats = append(stats, postgres.ColumnStats{Table: table, Column: column, Distinct: float64(200 * (i + 1))})
+	}
+	return stats, nil
+}
+
+func (f *fakeSource) ExtendedStatistics(ctx context.Context) ([]postgres.ExtendedStatistic, error) {
+	return f.existing, nil
+}
+
+func (f *fakeSource) ExplainWithStatistics(ctx context.Context, query string, ddl []string, analyze []postgres.AnalyzeTarget, timeout time.Duration) (string, error) {
+	f.ddl = append(f.ddl, ddl...)
+	f.analyzed = append(f.analyzed, analyze...)
+	// как в PostgreSQL, откат не отменяет того, что ANALYZE записывает в
+	// pg_stat_user_tables, а счетчик изменений обнуляет только ANALYZE
+	// всей таблицы
+	now := time.Now()
+	for _, target := range analyze {
+		for i := range f.states {
+			if f.states[i].Table == target.Table {
+				f.states[i].LastAnalyze = &now
+				if len(target.Columns) == 0 {
+					f.states[i].ModifiedSinceAnalyze = 0
+				}
+			}
+		}
+	}
+	return f.plan, nil
+}
+
+func TestCandidates(t *testing.T) {
+	candidates := Candidates(extendedPlan())
+	if len(candidates) != 2 {
+		t.Fatalf("кандидатов %d, ожидалось 2: %+v", len(candidates), candidates)
+	}
+
+	group, filter := candidates[0], candidates[1]
+	if group.Reason != ReasonGroup || group.Table != "orders" || strings.Join(group.Columns, ",") != "channel,region" {
+		t.Errorf("группировка: %+v", group)
+	}
+	if filter.Reason != ReasonFilter || filter.Table != "addresses" || strings.Join(filter.Columns, ",") != "city,zip" {
+		t.Errorf("фильтр: %+v", filter)
+	}
+	if filter.Planned != 3 || filter.Actual != 800 || strings.Join(filter.Kinds, ",") != "dependencies,mcv" {
+		t.Errorf("оценка фильтра: %+v", filter)
+	}
+}
+
+func TestAdviseExtended(t *testing.T) {
+	src := &fakeSource{
+		existing: []postgres.ExtendedStatistic{
+			{Table: "addresses", Name: "addresses_city_zip", Columns: []string{"zip", "city"}, Kinds: []string{"d"}},
+		},
+		plan: `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "addresses", "Alias": "a", "Plan Rows": 750, "Total Cost": 10}}]`,
+	}
+	report, err := AdviseExtended(context.Background(), src, "SELECT ...", extendedPlan(), Options{Verify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Recommendations) != 2 {
+		t.Fatalf("рекомендаций %d", len(report.Recommendations))
+	}
+
+	group := report.Recommendations[0]
+	if group.DDL != "CREATE STATISTICS stat_orders_channel_region (ndistinct) ON channel, region FROM orders;" {
+		t.Errorf("команда: %s", group.DDL)
+	}
+	if !strings.Contains(group.Description, "channel 200 × region 400") {
+		t.Errorf("описание: %s", group.Description)
+	}
+	if group.Estimate != nil {
+		t.Errorf("группировки нет в новом плане, а оценка найдена: %d", *group.Estimate)
+	}
+
+	// зависимости уже собраны, предлагается только MCV
+	filter := report.Recommendations[1]
+	if filter.DDL != "CREATE STATISTICS stat_addresses_city_zip (mcv) ON city, zip FROM addresses;" || filter.Analyze != "ANALYZE addresses;" {
+		t.Errorf("команды: %s %s", filter.DDL, filter.Analyze)
+	}
+	if filter.Improved == nil || !*filter.Improved || *filter.Estimate != 750 {
+		t.Errorf("проверка: %+v", filter)
+	}
+	if !strings.Contains(filter.ResultDescription, "750") {
+		t.Errorf("вывод проверки: %s", filter.ResultDescription)
+	}
+	if len(src.ddl) != 2 {
+		t.Errorf("выполнено команд %d", len(src.ddl))
+	}
+	// ANALYZE всей таблицы обнулил бы n_mod_since_analyze
+	if len(src.analyzed) != 2 || src.analyzed[1].Command() != "ANALYZE addresses (city, zip)" {
+		t.Errorf("ANALYZE выполнен для %+v", src.analyzed)
+	}
+}
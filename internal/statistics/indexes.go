@@ -0,0 +1,157 @@
+package statistics
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strconv"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+	"sql-optimizer/internal/recommendation"
+)
+
+// BRINCorrelation — корреляция физического порядка строк с порядком
+// значений столбца, начиная с которой вместо B-tree предлагается BRIN:
+// строки добавляются в конец таблицы по времени и почти не изменяются
+const BRINCorrelation = 0.95
+
+// PartialMaxFraction — наибольшая доля строк таблицы со значением из
+// условия, при которой вместо полного индекса предлагается частичный
+const PartialMaxFraction = 0.05
+
+// ColumnSource — источник статистики столбцов; его реализует *postgres.Client
+type ColumnSource interface {
+	ColumnStats(ctx context.Context, table string, columns []string) ([]postgres.ColumnStats, error)
+}
+
+// RefineIndexes уточняет предложенные анализатором индексы по pg_stats:
+//   - B-tree по столбцу, который условие сравнивает с моментом времени,
+//     заменяется компактным BRIN, если строки лежат в порядке значений
+//     столбца;
+//   - если условие выбирает редкое значение столбца по most_common_vals
+//     и most_common_freqs, полный индекс заменяется частичным с тем же
+//     условием в WHERE.
+//
+// Тексты нужно сформировать заново через Localize результата.
+func RefineIndexes(ctx context.Context, src ColumnSource, result *analyzer.AnalysisResult) error {
+	for i := range result.ProblematicOperations {
+		problem := &result.ProblematicOperations[i]
+		index := problem.Index
+		// Уточняются только обычные B-tree по столбцам условия
+		if index == nil || index.Kind != "" || !index.Plain() {
+			continue
+		}
+		brin := len(index.Columns) == 1 && slices.Contains(analyzer.TimeRangeColumns(problem.Filter), index.Columns[0])
+		var equalities []analyzer.Equality
+		for _, eq := range analyzer.Equalities(problem.Filter) {
+			if slices.Contains(index.Columns, eq.Column) {
+				equalities = append(equalities, eq)
+			}
+		}
+		if !brin && len(equalities) == 0 {
+			continue
+		}
+
+		stats, err := src.ColumnStats(ctx, index.Table, index.Columns)
+		if err != nil {
+			return err
+		}
+		byColumn := make(map[string]postgres.ColumnStats)
+		for _, s := range stats {
+			byColumn[s.Column] = s
+		}
+		if brin && refineBRIN(problem, byColumn[index.Columns[0]]) {
+			continue
+		}
+		refinePartial(problem, equalities, byColumn)
+	}
+	return nil
+}
+
+// refineBRIN предлагает BRIN вместо B-tree по физически упорядоченному столбцу
+func refineBRIN(problem *analyzer.ProblematicOperation, stats postgres.ColumnStats) bool {
+	if stats.Correlation == nil || math.Abs(*stats.Correlation) < BRINCorrelation {
+		return false
+	}
+	index := problem.Index
+	brin := analyzer.IndexCandidate{Table: index.Table, Columns: index.Columns, Kind: analyzer.IndexBRIN, Method: "brin"}
+	problem.Index = &brin
+	problem.RecommendationMessage = i18n.New(i18n.IndexTypeBRIN,
+		"table", brin.Table, "column", brin.Columns[0],
+		"correlation", strconv.FormatFloat(*stats.Correlation, 'f', 2, 64), "ddl", brin.DDL())
+	return true
+}
+
+// refinePartial предлагает частичный индекс по первому равенству, которое
+// выбирает не больше PartialMaxFraction строк. Ключом частичного индекса
+// остаются прочие столбцы полного, а если их нет — сам столбец условия.
+func refinePartial(problem *analyzer.ProblematicOperation, equalities []analyzer.Equality, byColumn map[string]postgres.ColumnStats) {
+	index := problem.Index
+	for _, eq := range equalities {
+		stats, ok := byColumn[eq.Column]
+		if !ok || stats.Rows <= 0 {
+			continue
+		}
+		fraction, ok := valueFraction(stats, eq.Values)
+		if !ok || fraction > PartialMaxFraction {
+			continue
+		}
+
+		var key []string
+		for _, column := range index.Columns {
+			if column != eq.Column {
+				key = append(key, column)
+			}
+		}
+		if len(key) == 0 {
+			key = []string{eq.Column}
+		}
+		partial := analyzer.IndexCandidate{Table: index.Table, Columns: key, Kind: analyzer.IndexPartial, Where: eq.Predicate}
+
+		widths := make(map[string]int)
+		for column, s := range byColumn {
+			widths[column] = s.Width
+		}
+		full := recommendation.EstimateIndexSize(recommendation.TableStats{Rows: int64(stats.Rows), ColumnWidths: widths}, index.Columns)
+		size := recommendation.EstimateIndexSize(recommendation.TableStats{Rows: int64(math.Ceil(stats.Rows * fraction)), ColumnWidths: widths}, key)
+		ratio := float64(full) / math.Max(float64(size), 1)
+
+		problem.Index = &partial
+		problem.RecommendationMessage = i18n.New(i18n.IndexTypePartial,
+			"table", partial.Table, "predicate", eq.Predicate,
+			"percent", strconv.FormatFloat(fraction*100, 'f', 2, 64),
+			"partial", postgres.SizePretty(size), "full", postgres.SizePretty(full),
+			"ratio", strconv.FormatFloat(ratio, 'f', 0, 64), "ddl", partial.DDL())
+		return
+	}
+}
+
+// valueFraction оценивает долю строк со значениями values по частым
+// значениям столбца. Значения, которого нет среди частых, не больше
+// остатка таблицы, не покрытого частыми значениями и NULL; оценка
+// возможна, только если этот остаток сам мал.
+func valueFraction(stats postgres.ColumnStats, values []string) (float64, bool) {
+	covered := stats.NullFrac
+	for _, freq := range stats.CommonFreqs {
+		covered += freq
+	}
+	fraction, uncommon := 0.0, false
+	for _, value := range values {
+		i := slices.Index(stats.CommonVals, value)
+		if i < 0 || i >= len(stats.CommonFreqs) {
+			uncommon = true
+			continue
+		}
+		fraction += stats.CommonFreqs[i]
+	}
+	if uncommon {
+		rest := math.Max(1-covered, 0)
+		if rest > PartialMaxFraction {
+			return 0, false
+		}
+		fraction += rest
+	}
+	return fraction, true
+}
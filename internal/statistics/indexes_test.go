@@ -0,0 +1,93 @@
+package statistics
+
+import (
+	"context"
+	"testing"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/postgres"
+)
+
+type columnSource map[string]postgres.ColumnStats
+
+func (s columnSource) ColumnStats(ctx context.Context, table string, columns []string) ([]postgres.ColumnStats, error) {
+	var stats []postgres.ColumnStats
+	for _, column := range columns {
+		if st, ok := s[table+"."+column]; ok {
+			stats = append(stats, st)
+		}
+	}
+	return stats, nil
+}
+
+func TestRefineIndexesBRIN(t *testing.T) {
+	src := columnSource{
+		"events.created_at": {Table: "events", Column: "created_at", Correlation: floatPtr(0.998)},
+		"orders.created_at": {Table: "orders", Column: "created_at", Correlation: floatPtr(0.31)},
+	}
+	result := analyzer.AnalyzeNodes([]analyzer.PlanNode{
+		{NodeType: "Seq Scan", RelationName: "events", TotalCost: 5000,
+			Filter: "(created_at >= '2026-01-01 00:00:00+00'::timestamp with time zone)"},
+		{NodeType: "Seq Scan", RelationName: "orders", TotalCost: 5000,
+			Filter: "(created_at >= '2026-01-01 00:00:00+00'::timestamp with time zone)"},
+	})
+	if err := RefineIndexes(context.Background(), src, result); err != nil {
+		t.Fatal(err)
+	}
+	result.Localize("ru")
+
+	events, orders := result.ProblematicOperations[0], result.ProblematicOperations[1]
+	if events.Index.DDL() != "CREATE INDEX CONCURRENTLY idx_events_created_at_brin ON events USING brin (created_at);" {
+		t.Errorf("events: %s", events.Index.DDL())
+	}
+	if events.RecommendationMessage.Args["correlation"] != "1.00" || events.Recommendation == "" {
+		t.Errorf("рекомендация: %+v", events.RecommendationMessage)
+	}
+	if !orders.Index.Plain() {
+		t.Errorf("orders: корреляция низкая, а предложен %s", orders.Index.DDL())
+	}
+}
+
+func TestRefineIndexesPartial(t *testing.T) {
+	src := columnSource{
+		"orders.status": {Table: "orders", Column: "status", Width: 9, Rows: 1000000,
+			CommonVals: []string{"completed", "cancelled", "pending"}, CommonFreqs: []float64{0.98, 0.015, 0.004}},
+		"orders.customer_id": {Table: "orders", Column: "customer_id", Width: 8, Rows: 1000000, Distinct: 50000},
+	}
+	plan := func(filter string) []analyzer.PlanNode {
+		return []analyzer.PlanNode{{NodeType: "Seq Scan", RelationName: "orders", TotalCost: 20000, Filter: filter}}
+	}
+
+	tests := []struct {
+		name   string
+		filter string
+		ddl    string
+	}{
+		{"редкое значение", "((status)::text = 'pending'::text)",
+			"CREATE INDEX CONCURRENTLY idx_orders_status_partial ON orders (status) WHERE status = 'pending';"},
+		{"редкое значение и другой столбец", "((customer_id = 42) AND ((status)::text = 'pending'::text))",
+			"CREATE INDEX CONCURRENTLY idx_orders_customer_id_partial ON orders (customer_id) WHERE status = 'pending';"},
+		{"значение вне частых", "((status)::text = 'refunded'::text)",
+			"CREATE INDEX CONCURRENTLY idx_orders_status_partial ON orders (status) WHERE status = 'refunded';"},
+		{"частое значение", "((status)::text = 'completed'::text)",
+			"CREATE INDEX CONCURRENTLY idx_orders_status ON orders (status);"},
+		{"нет частых значений", "(customer_id = 42)",
+			"CREATE INDEX CONCURRENTLY idx_orders_customer_id ON orders (customer_id);"},
+	}
+	for _, tt := range tests {
+		result := analyzer.AnalyzeNodes(plan(tt.filter))
+		if err := RefineIndexes(context.Background(), src, result); err != nil {
+			t.Fatal(err)
+		}
+		if ddl := result.ProblematicOperations[0].Index.DDL(); ddl != tt.ddl {
+			t.Errorf("%s: %s", tt.name, ddl)
+		}
+	}
+
+	result := analyzer.AnalyzeNodes(plan("((status)::text = 'pending'::text)"))
+	RefineIndexes(context.Background(), src, result)
+	args := result.ProblematicOperations[0].RecommendationMessage.Args
+	if args["percent"] != "0.40" || args["ratio"] != "250" {
+		t.Errorf("оценка размера: %v", args)
+	}
+}
@@ -0,0 +1,320 @@
+package statistics
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/i18n"
+	"sql-optimizer/internal/postgres"
+	"sql-optimizer/internal/quote"
+)
+
+// Порог устаревания статистики — как autovacuum_analyze_threshold
+// и autovacuum_analyze_scale_factor по умолчанию: статистика устарела,
+// если с последнего ANALYZE изменено больше StaleThreshold строк плюс
+// StaleScaleFactor от числа живых строк
+const (
+	StaleThreshold   = 50
+	StaleScaleFactor = 0.1
+)
+
+// StaleSource — источник состояния статистики таблиц; его реализует
+// *postgres.Client
+type StaleSource interface {
+	AnalyzeStates(ctx context.Context, tables []string) ([]postgres.AnalyzeState, error)
+	ExplainWithStatistics(ctx context.Context, query string, ddl []string, analyze []postgres.AnalyzeTarget, timeout time.Duration) (string, error)
+}
+
+// TableState — состояние статистики таблицы из плана
+type TableState struct {
+	postgres.AnalyzeState
+	Stale   bool   `json:"stale"`
+	Command string `json:"command,omitempty"` // ANALYZE для устаревшей статистики
+}
+
+// Estimate — сканирование таблицы, оценка строк которого ошиблась
+// больше чем в analyzer.MisestimateThreshold раз
+type Estimate struct {
+	Table    string  `json:"table"`
+	Alias    string  `json:"alias"`
+	NodeType string  `json:"node_type"`
+	Planned  int     `json:"planned_rows"`
+	Actual   int     `json:"actual_rows"`
+	Factor   float64 `json:"factor"`
+	// Stale — статистика таблицы устарела, и ошибку, вероятно, исправит ANALYZE
+	Stale   bool   `json:"stale"`
+	Command string `json:"command,omitempty"`
+	// EstimateAfter и Fixed заполняет Recheck: оценка после ANALYZE и то,
+	// исправилась ли ошибка
+	EstimateAfter     *int          `json:"estimate_after,omitempty"`
+	Fixed             *bool         `json:"fixed,omitempty"`
+	Message           i18n.Message  `json:"message"`
+	Description       string        `json:"description"`
+	Result            *i18n.Message `json:"result,omitempty"`
+	ResultDescription string        `json:"result_description,omitempty"`
+
+	state   postgres.AnalyzeState
+	columns []string // столбцы условия сканирования
+}
+
+// StaleReport — состояние статистики таблиц плана и ошибки оценок
+type StaleReport struct {
+	Tables    []TableState `json:"tables"`
+	Estimates []Estimate   `json:"estimates"`
+	// RecheckError — почему не удалось проверить оценки после ANALYZE
+	RecheckError string `json:"recheck_error,omitempty"`
+}
+
+// IsStale сообщает, устарела ли статистика таблицы: ANALYZE не выполнялся
+// ни разу или с тех пор изменено больше строк, чем допускает порог
+func IsStale(state postgres.AnalyzeState) bool {
+	if state.LastAnalyze == nil && state.LastAutoanalyze == nil {
+		return state.LiveTuples > 0 || state.ModifiedSinceAnalyze > 0
+	}
+	return float64(state.ModifiedSinceAnalyze) > StaleThreshold+StaleScaleFactor*float64(state.LiveTuples)
+}
+
+// NeedsStaleCheck сообщает, есть ли в плане ошибки оценок строк таблиц,
+// для которых стоит проверить свежесть статистики
+func NeedsStaleCheck(plan []analyzer.PlanNode) bool {
+	return len(misestimates(plan)) > 0
+}
+
+// LoadStaleReport читает состояние статистики всех таблиц плана
+func LoadStaleReport(ctx context.Context, src StaleSource, plan []analyzer.PlanNode) (*StaleReport, error) {
+	relations := make(map[string]string)
+	for i := range plan {
+		collectRelations(&plan[i], relations)
+	}
+	seen := make(map[string]bool)
+	var tables []string
+	for _, table := range relations {
+		if !seen[table] {
+			seen[table] = true
+			tables = append(tables, table)
+		}
+	}
+	sort.Strings(tables)
+	if len(tables) == 0 {
+		return CheckStale(plan, nil), nil
+	}
+
+	states, err := src.AnalyzeStates(ctx, tables)
+	if err != nil {
+		return nil, err
+	}
+	return CheckStale(plan, states), nil
+}
+
+// CheckStale отмечает ошибки оценок плана, статистика таблиц которых устарела
+func CheckStale(plan []analyzer.PlanNode, states []postgres.AnalyzeState) *StaleReport {
+	report := &StaleReport{Tables: []TableState{}, Estimates: []Estimate{}}
+	byTable := make(map[string]TableState)
+	for _, state := range states {
+		table := TableState{AnalyzeState: state, Stale: IsStale(state)}
+		if table.Stale {
+			table.Command = analyzeCommand(state.Table)
+		}
+		byTable[state.Table] = table
+		report.Tables = append(report.Tables, table)
+	}
+
+	for _, e := range misestimates(plan) {
+		table, ok := byTable[e.Table]
+		if !ok {
+			continue
+		}
+		e.state, e.Stale, e.Command = table.AnalyzeState, table.Stale, table.Command
+		e.Message = estimateMessage(e)
+		report.Estimates = append(report.Estimates, e)
+	}
+	report.Localize(i18n.DefaultLocale)
+	return report
+}
+
+// Recheck собирает статистику таблиц с устаревшей статистикой в
+// откатываемой транзакции, строит план заново и проверяет, исправились ли
+// оценки. Ошибка проверки записывается в RecheckError.
+//
+// ANALYZE собирает только столбцы условий сканирований с ошибкой оценки:
+// ANALYZE всей таблицы обнулил бы n_mod_since_analyze в обход отката, и
+// следующая проверка сочла бы статистику свежей, хотя она не собиралась.
+// Со списком столбцов счетчик сохраняется, но last_analyze обновляется,
+// поэтому таблица, для которой ANALYZE не выполнялся ни разу, после
+// проверки оценивается по числу изменений. Сканирования без условий не
+// проверяются: для ANALYZE нужен хотя бы один столбец.
+func (r *StaleReport) Recheck(ctx context.Context, src StaleSource, query string, timeout time.Duration) {
+	columns := make(map[string][]string)
+	for _, e := range r.Estimates {
+		if !e.Stale {
+			continue
+		}
+		for _, column := range e.columns {
+			if !slices.Contains(columns[e.Table], column) {
+				columns[e.Table] = append(columns[e.Table], column)
+			}
+		}
+	}
+	var analyze []postgres.AnalyzeTarget
+	for _, table := range r.Tables {
+		if table.Stale && len(columns[table.Table]) > 0 {
+			analyze = append(analyze, postgres.AnalyzeTarget{Table: table.Table, Columns: columns[table.Table]})
+		}
+	}
+	if len(analyze) == 0 {
+		return
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	planJSON, err := src.ExplainWithStatistics(ctx, query, nil, analyze, timeout)
+	if err != nil {
+		r.RecheckError = err.Error()
+		return
+	}
+	plan, err := analyzer.ParseExplainJSON(planJSON)
+	if err != nil {
+		r.RecheckError = "не удалось разобрать план: " + err.Error()
+		return
+	}
+	for i := range r.Estimates {
+		e := &r.Estimates[i]
+		if !e.Stale || len(columns[e.Table]) == 0 {
+			continue
+		}
+		after, ok := findEstimate(plan, Candidate{Reason: ReasonFilter, Table: e.Table, Alias: e.Alias})
+		if !ok {
+			continue
+		}
+		fixed := estimateError(after, e.Actual) < analyzer.MisestimateThreshold
+		e.EstimateAfter, e.Fixed = &after, &fixed
+
+		id := i18n.StatisticsStaleNotFixed
+		if fixed {
+			id = i18n.StatisticsStaleFixed
+		}
+		result := i18n.New(id, "table", e.Table, "after", strconv.Itoa(after), "actual", strconv.Itoa(e.Actual))
+		e.Result = &result
+	}
+	r.Localize(i18n.DefaultLocale)
+}
+
+// Localize формирует тексты на языке locale
+func (r *StaleReport) Localize(locale string) {
+	for i := range r.Estimates {
+		e := &r.Estimates[i]
+		e.Description = e.Message.Format(locale)
+		if e.Result != nil {
+			e.ResultDescription = e.Result.Format(locale)
+		}
+	}
+}
+
+// LinkStale добавляет в результат анализа находку RuleStaleStatistics для
+// каждой таблицы с устаревшей статистикой, оценка сканирования которой
+// ошиблась. Тексты нужно сформировать заново через Localize результата.
+func LinkStale(result *analyzer.AnalysisResult, report *StaleReport) {
+	linked := make(map[string]bool)
+	for _, problem := range result.ProblematicOperations {
+		if problem.RuleID == analyzer.RuleStaleStatistics {
+			linked[problem.Relation] = true
+		}
+	}
+	for _, e := range report.Estimates {
+		if !e.Stale || linked[e.Table] {
+			continue
+		}
+		linked[e.Table] = true
+		recommendation := i18n.New(i18n.StatisticsStaleRecommendation, "command", e.Command)
+		result.ProblematicOperations = append(result.ProblematicOperations, analyzer.ProblematicOperation{
+			RuleID:                analyzer.RuleStaleStatistics,
+			NodeType:              e.NodeType,
+			Relation:              e.Table,
+			Severity:              "medium",
+			DescriptionMessage:    e.Message,
+			RecommendationMessage: recommendation,
+			Statistics: &analyzer.StatisticsLink{
+				Table:                e.Table,
+				LastAnalyze:          lastAnalyze(e.state),
+				ModifiedSinceAnalyze: e.state.ModifiedSinceAnalyze,
+				LiveTuples:           e.state.LiveTuples,
+				Command:              e.Command,
+			},
+		})
+		result.RecommendationMessages = append(result.RecommendationMessages, recommendation)
+	}
+}
+
+// misestimates находит сканирования таблиц с ошибкой оценки строк
+func misestimates(plan []analyzer.PlanNode) []Estimate {
+	var estimates []Estimate
+	for _, node := range analyzer.Flatten(analyzer.Annotate(plan)) {
+		n := node.Node
+		factor, _ := node.MisestimateFactor()
+		if n.RelationName == "" || n.ActualRows == nil || factor < analyzer.MisestimateThreshold {
+			continue
+		}
+		alias := n.Alias
+		if alias == "" {
+			alias = n.RelationName
+		}
+		var conditions []string
+		for _, cond := range []string{n.IndexCondition, n.Filter} {
+			if cond != "" {
+				conditions = append(conditions, cond)
+			}
+		}
+		estimates = append(estimates, Estimate{
+			Table:    n.RelationName,
+			Alias:    alias,
+			NodeType: n.NodeType,
+			Planned:  n.PlanRows,
+			Actual:   *n.ActualRows,
+			Factor:   factor,
+			columns:  ownColumns(strings.Join(conditions, " AND "), alias),
+		})
+	}
+	return estimates
+}
+
+// estimateMessage объясняет ошибку оценки с учетом состояния статистики
+func estimateMessage(e Estimate) i18n.Message {
+	args := []string{
+		"table", e.Table,
+		"factor", strconv.FormatFloat(e.Factor, 'f', 0, 64),
+		"planned", strconv.Itoa(e.Planned),
+		"actual", strconv.Itoa(e.Actual),
+	}
+	last := lastAnalyze(e.state)
+	switch {
+	case !e.Stale:
+		return i18n.New(i18n.StatisticsEstimateFresh, args...)
+	case last == nil:
+		return i18n.New(i18n.StatisticsStaleNever, append(args, "rows", strconv.FormatInt(e.state.LiveTuples, 10))...)
+	default:
+		return i18n.New(i18n.StatisticsStale, append(args,
+			"analyzed", last.Format("2006-01-02 15:04"),
+			"modified", strconv.FormatInt(e.state.ModifiedSinceAnalyze, 10),
+			"rows", strconv.FormatInt(e.state.LiveTuples, 10))...)
+	}
+}
+
+// lastAnalyze возвращает время последнего ANALYZE, ручного или автоматического
+func lastAnalyze(state postgres.AnalyzeState) *time.Time {
+	last := state.LastAnalyze
+	if state.LastAutoanalyze != nil && (last == nil || state.LastAutoanalyze.After(*last)) {
+		last = state.LastAutoanalyze
+	}
+	return last
+}
+
+func analyzeCommand(table string) string {
+	return fmt.Sprintf("ANALYZE %s;", quote.Ident(table))
+}
@@ -0,0 +1,133 @@
+package statistics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/postgres"
+)
+
+func (f *fakeSource) AnalyzeStates(ctx context.Context, tables []string) ([]postgres.AnalyzeState, error) {
+	return f.states, nil
+}
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+func staleStates() []postgres.AnalyzeState {
+	return []postgres.AnalyzeState{
+		{Table: "addresses", LiveTuples: 10000, ModifiedSinceAnalyze: 6000,
+			LastAutoanalyze: timePtr(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))},
+		{Table: "orders", LiveTuples: 50000, ModifiedSinceAnalyze: 100,
+			LastAnalyze: timePtr(time.Date(2026, 3, 2, 8, 30, 0, 0, time.UTC))},
+	}
+}
+
+func TestIsStale(t *testing.T) {
+	analyzed := timePtr(time.Now())
+	tests := []struct {
+		name  string
+		state postgres.AnalyzeState
+		want  bool
+	}{
+		{"свежая", postgres.AnalyzeState{LiveTuples: 1000, ModifiedSinceAnalyze: 100, LastAnalyze: analyzed}, false},
+		{"изменено больше порога", postgres.AnalyzeState{LiveTuples: 1000, ModifiedSinceAnalyze: 151, LastAutoanalyze: analyzed}, true},
+		{"ни разу не собиралась", postgres.AnalyzeState{LiveTuples: 10}, true},
+		{"пустая таблица", postgres.AnalyzeState{}, false},
+	}
+	for _, tt := range tests {
+		if got := IsStale(tt.state); got != tt.want {
+			t.Errorf("%s: IsStale = %v, ожидалось %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckStale(t *testing.T) {
+	report := CheckStale(extendedPlan(), staleStates())
+	if len(report.Tables) != 2 || !report.Tables[0].Stale || report.Tables[1].Stale {
+		t.Fatalf("таблицы: %+v", report.Tables)
+	}
+	if len(report.Estimates) != 1 {
+		t.Fatalf("ошибок оценок %d: %+v", len(report.Estimates), report.Estimates)
+	}
+	e := report.Estimates[0]
+	if e.Table != "addresses" || !e.Stale || e.Command != "ANALYZE addresses;" {
+		t.Errorf("оценка: %+v", e)
+	}
+	if !strings.Contains(e.Description, "2026-03-01 12:00") || !strings.Contains(e.Description, "6000") {
+		t.Errorf("описание: %s", e.Description)
+	}
+
+	states := staleStates()
+	states[0].LastAutoanalyze = nil
+	never := CheckStale(extendedPlan(), states).Estimates[0]
+	if !strings.Contains(never.Description, "ни разу") {
+		t.Errorf("описание без ANALYZE: %s", never.Description)
+	}
+
+	states[0].ModifiedSinceAnalyze = 0
+	states[0].LastAnalyze = timePtr(time.Now())
+	fresh := CheckStale(extendedPlan(), states).Estimates[0]
+	if fresh.Stale || fresh.Command != "" || !strings.Contains(fresh.Description, "свежая") {
+		t.Errorf("свежая статистика: %+v", fresh)
+	}
+}
+
+func TestRecheck(t *testing.T) {
+	src := &fakeSource{
+		states: staleStates(),
+		plan:   `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "addresses", "Alias": "a", "Plan Rows": 790, "Total Cost": 10}}]`,
+	}
+	report, err := Advise(context.Background(), src, "SELECT ...", extendedPlan(), Options{Verify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(src.analyzed) == 0 || src.analyzed[0].Command() != "ANALYZE addresses (city, zip)" {
+		t.Errorf("ANALYZE выполнен для %+v", src.analyzed)
+	}
+	e := report.Stale.Estimates[0]
+	if e.EstimateAfter == nil || *e.EstimateAfter != 790 || e.Fixed == nil || !*e.Fixed {
+		t.Fatalf("проверка: %+v", e)
+	}
+	if !strings.Contains(e.ResultDescription, "790") {
+		t.Errorf("вывод проверки: %s", e.ResultDescription)
+	}
+}
+
+func TestLinkStale(t *testing.T) {
+	report := CheckStale(extendedPlan(), staleStates())
+	report.Estimates = append(report.Estimates, report.Estimates[0])
+	result := &analyzer.AnalysisResult{}
+	LinkStale(result, report)
+	LinkStale(result, report)
+
+	if len(result.ProblematicOperations) != 1 {
+		t.Fatalf("находок %d", len(result.ProblematicOperations))
+	}
+	problem := result.ProblematicOperations[0]
+	if problem.RuleID != analyzer.RuleStaleStatistics || problem.Statistics == nil || problem.Statistics.ModifiedSinceAnalyze != 6000 {
+		t.Errorf("находка: %+v", problem)
+	}
+	if len(result.RecommendationMessages) != 1 {
+		t.Errorf("рекомендаций %d", len(result.RecommendationMessages))
+	}
+}
+
+func TestRecheckKeepsStaleState(t *testing.T) {
+	src := &fakeSource{
+		states: staleStates(),
+		plan:   `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "addresses", "Alias": "a", "Plan Rows": 790, "Total Cost": 10}}]`,
+	}
+	if _, err := Advise(context.Background(), src, "SELECT ...", extendedPlan(), Options{Verify: true}); err != nil {
+		t.Fatal(err)
+	}
+	report, err := LoadStaleReport(context.Background(), src, extendedPlan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Estimates) != 1 || !report.Estimates[0].Stale {
+		t.Errorf("после проверки статистика должна остаться устаревшей: %+v", report.Estimates)
+	}
+}
@@ -0,0 +1,60 @@
+// Package statistics ищет ошибки оценок планировщика, которые исправляет
+// статистика: расширенная статистика по коррелирующим столбцам и ANALYZE
+// таблиц с устаревшей статистикой.
+package statistics
+
+import (
+	"context"
+	"time"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/postgres"
+)
+
+// DefaultTimeout — statement_timeout одной проверки
+const DefaultTimeout = 30 * time.Second
+
+// Source — источник статистики; его реализует *postgres.Client
+type Source interface {
+	ExtendedSource
+	AnalyzeStates(ctx context.Context, tables []string) ([]postgres.AnalyzeState, error)
+}
+
+// Options — параметры советника
+type Options struct {
+	// Verify — проверить рекомендации в откатываемой транзакции: создать
+	// статистику или собрать ее заново командой ANALYZE и сравнить оценки
+	// нового плана с фактическими
+	Verify bool
+	// Timeout — statement_timeout проверки, по умолчанию DefaultTimeout
+	Timeout time.Duration
+}
+
+// Report — рекомендации по статистике для запроса
+type Report struct {
+	Stale    *StaleReport    `json:"stale"`
+	Extended *ExtendedReport `json:"extended"`
+}
+
+// Advise проверяет свежесть статистики таблиц плана, полученного
+// с ANALYZE, и предлагает расширенную статистику
+func Advise(ctx context.Context, src Source, query string, plan []analyzer.PlanNode, opts Options) (*Report, error) {
+	stale, err := LoadStaleReport(ctx, src, plan)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Verify {
+		stale.Recheck(ctx, src, query, opts.Timeout)
+	}
+	extended, err := AdviseExtended(ctx, src, query, plan, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Report{Stale: stale, Extended: extended}, nil
+}
+
+// Localize формирует тексты отчета на языке locale
+func (r *Report) Localize(locale string) {
+	r.Stale.Localize(locale)
+	r.Extended.Localize(locale)
+}
@@ -0,0 +1,94 @@
+// Package tuning советует значения параметров PostgreSQL: читает
+// pg_settings, берет сведения о железе от пользователя и сверяется
+// с планами, которые уже видел анализатор.
+package tuning
+
+import (
+	"context"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/history"
+	"sql-optimizer/internal/postgres"
+	"sql-optimizer/internal/recommendation"
+)
+
+// HistoryLimit — сколько последних анализов из истории просматривается
+const HistoryLimit = 500
+
+// Source — источник параметров сервера; его реализует *postgres.Client
+type Source interface {
+	Settings(ctx context.Context) (map[string]postgres.Setting, error)
+}
+
+// Options — сведения для рекомендаций
+type Options struct {
+	Hardware recommendation.Hardware
+	// Plans — планы запросов к этой базе, например из истории анализов
+	Plans [][]analyzer.PlanNode
+}
+
+// Advise читает параметры сервера и рекомендует их значения
+func Advise(ctx context.Context, src Source, opts Options) (*recommendation.TuningReport, error) {
+	settings, err := src.Settings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	input := recommendation.TuningInput{
+		Settings: make(map[string]recommendation.Setting, len(settings)),
+		Hardware: opts.Hardware,
+		Plans:    Observe(opts.Plans),
+	}
+	for name, s := range settings {
+		input.Settings[name] = recommendation.Setting{Value: s.Value, Unit: s.Unit}
+	}
+	report := recommendation.NewEngine().TuneSettings(input)
+	return &report, nil
+}
+
+// HistoryPlans возвращает планы последних анализов базы database из истории
+func HistoryPlans(store history.Store, database string) ([][]analyzer.PlanNode, error) {
+	records, err := store.List(history.Filter{Database: database, Limit: HistoryLimit})
+	if err != nil {
+		return nil, err
+	}
+	plans := make([][]analyzer.PlanNode, 0, len(records))
+	for _, record := range records {
+		if len(record.Plan) > 0 {
+			plans = append(plans, record.Plan)
+		}
+	}
+	return plans, nil
+}
+
+// Observe собирает из планов то, что говорит о параметрах сервера:
+// сортировки и хеш-таблицы, сброшенные на диск, узлы Gather без
+// запланированных процессов и стоимость с временем выполнения каждого плана
+func Observe(plans [][]analyzer.PlanNode) recommendation.PlanStats {
+	stats := recommendation.PlanStats{Plans: len(plans)}
+	for _, plan := range plans {
+		for _, root := range plan {
+			stats.Roots = append(stats.Roots, recommendation.PlanRoot{TotalCost: root.TotalCost, ActualTime: root.ActualTotalTime})
+			observeNode(root, &stats)
+		}
+	}
+	return stats
+}
+
+func observeNode(node analyzer.PlanNode, stats *recommendation.PlanStats) {
+	switch {
+	case node.SortSpaceType == "Disk":
+		// В памяти строки занимают больше, чем во временных файлах
+		stats.DiskSorts++
+		stats.MaxSpillBytes = max(stats.MaxSpillBytes, int64(node.SortSpaceUsed)<<10*2)
+	case node.NodeType == "Hash" && node.HashBatches > 1:
+		stats.HashSpills++
+		stats.MaxSpillBytes = max(stats.MaxSpillBytes, int64(node.PeakMemoryUsage)<<10*int64(node.HashBatches))
+	case (node.NodeType == "Gather" || node.NodeType == "Gather Merge") &&
+		node.WorkersLaunched != nil && *node.WorkersLaunched < node.WorkersPlanned:
+		stats.ShortOfWorkers++
+	}
+	for _, child := range node.Plans {
+		observeNode(child, stats)
+	}
+}
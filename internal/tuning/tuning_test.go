@@ -0,0 +1,49 @@
+package tuning
+
+import (
+	"context"
+	"testing"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/postgres"
+	"sql-optimizer/internal/recommendation"
+)
+
+type fakeSource map[string]postgres.Setting
+
+func (f fakeSource) Settings(ctx context.Context) (map[string]postgres.Setting, error) {
+	return f, nil
+}
+
+func TestObserve(t *testing.T) {
+	launched, elapsed := 0, 12.5
+	plans := [][]analyzer.PlanNode{
+		{{NodeType: "Gather", TotalCost: 200000, ActualTotalTime: &elapsed, WorkersPlanned: 2, WorkersLaunched: &launched,
+			Plans: []analyzer.PlanNode{{NodeType: "Sort", SortSpaceType: "Disk", SortSpaceUsed: 20480}}}},
+		{{NodeType: "Hash Join", TotalCost: 10, Plans: []analyzer.PlanNode{
+			{NodeType: "Seq Scan"},
+			{NodeType: "Hash", HashBatches: 8, PeakMemoryUsage: 4096},
+			{NodeType: "Sort", SortSpaceType: "Memory", SortSpaceUsed: 100},
+		}}},
+	}
+
+	stats := Observe(plans)
+	if stats.Plans != 2 || stats.DiskSorts != 1 || stats.HashSpills != 1 || stats.ShortOfWorkers != 1 || len(stats.Roots) != 2 {
+		t.Fatalf("Неверная статистика планов: %+v", stats)
+	}
+	// Сортировке нужно вдвое больше 20 МБ на диске, хешу — 8 пакетов по 4 МБ
+	if stats.MaxSpillBytes != 40<<20 {
+		t.Errorf("Ожидали 40 МБ, получили %d", stats.MaxSpillBytes)
+	}
+}
+
+func TestAdvise(t *testing.T) {
+	src := fakeSource{"random_page_cost": {Name: "random_page_cost", Value: "4", Source: "default"}}
+	report, err := Advise(context.Background(), src, Options{Hardware: recommendation.Hardware{Storage: recommendation.StorageSSD}})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if len(report.Recommendations) != 1 || report.Recommendations[0].Name != "random_page_cost" {
+		t.Errorf("Ожидали рекомендацию random_page_cost для SSD: %+v", report.Recommendations)
+	}
+}
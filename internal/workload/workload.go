@@ -0,0 +1,300 @@
+// Package workload анализирует нагрузку на базу целиком: ранжирует
+// операторы из pg_stat_statements, получает планы для самых тяжелых
+// и сводит находки и рекомендуемые индексы с учетом того, какую долю
+// общего времени базы занимает каждый оператор.
+package workload
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"sql-optimizer/internal/analyzer"
+	"sql-optimizer/internal/fingerprint"
+	"sql-optimizer/internal/postgres"
+	"sql-optimizer/internal/recommendation"
+)
+
+// Порядок ранжирования операторов
+const (
+	OrderTotalTime      = "total_time"
+	OrderMeanTime       = "mean_time"
+	OrderCalls          = "calls"
+	OrderSharedBlksRead = "shared_blks_read"
+)
+
+// genericPlanVersion — версия, начиная с которой EXPLAIN умеет GENERIC_PLAN
+const genericPlanVersion = 160000
+
+// Source — источник статистики и планов; его реализует *postgres.Client
+type Source interface {
+	StatStatements(ctx context.Context) ([]postgres.StatStatement, error)
+	ServerVersion(ctx context.Context) (int, error)
+	GetEstimatedPlan(ctx context.Context, query string, generic bool) (string, error)
+}
+
+// catalogSource — источник, который умеет читать каталог. Если Source его
+// не реализует, индексы сводятся без учета существующих и без оценки размера.
+type catalogSource interface {
+	Indexes(ctx context.Context) ([]postgres.Index, error)
+	Tables(ctx context.Context) ([]postgres.Table, error)
+}
+
+// Options — параметры анализа нагрузки
+type Options struct {
+	OrderBy string // один из Order*, по умолчанию OrderTotalTime
+	TopN    int    // для скольких операторов получать планы, по умолчанию 10
+	Locale  string // язык находок, по умолчанию i18n.DefaultLocale
+}
+
+// Statement — оператор нагрузки с результатом анализа его плана
+type Statement struct {
+	postgres.StatStatement
+	// Share — доля оператора в общем времени выполнения всех операторов, %
+	Share            float64                  `json:"share"`
+	QueryFingerprint string                   `json:"query_fingerprint"`
+	Result           *analyzer.AnalysisResult `json:"result,omitempty"`
+	PlanError        string                   `json:"plan_error,omitempty"`
+	Plan             []analyzer.PlanNode      `json:"-"`
+}
+
+// Finding — находка, сведенная по всем операторам нагрузки
+type Finding struct {
+	RuleID      string `json:"rule_id"`
+	NodeType    string `json:"node_type"`
+	Relation    string `json:"relation,omitempty"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+	// Weight — суммарная доля времени операторов с этой находкой, %
+	Weight     float64  `json:"weight"`
+	Statements []string `json:"statements"` // queryid операторов
+}
+
+// IndexRecommendation — индекс, который предлагался для операторов нагрузки
+type IndexRecommendation struct {
+	Index      analyzer.IndexCandidate `json:"index"`
+	DDL        string                  `json:"ddl"`
+	Weight     float64                 `json:"weight"`
+	Statements []string                `json:"statements"`
+}
+
+// Report — результат анализа нагрузки
+type Report struct {
+	TotalTime  float64               `json:"total_time"` // мс, по всем операторам
+	OrderBy    string                `json:"order_by"`
+	Statements []Statement           `json:"statements"` // первые TopN по OrderBy
+	Findings   []Finding             `json:"findings"`
+	Indexes    []IndexRecommendation `json:"indexes"`
+	// Consolidated — минимальный набор индексов, сведенный из Indexes
+	// с учетом существующих индексов
+	Consolidated recommendation.Consolidation `json:"consolidated"`
+}
+
+// Analyze читает pg_stat_statements, ранжирует операторы и анализирует
+// планы первых TopN. Запросы не выполняются: планы строятся EXPLAIN без
+// ANALYZE, а для текстов с параметрами $n — с GENERIC_PLAN, если сервер
+// его поддерживает. Операторы, для которых план получить не удалось,
+// остаются в рейтинге с причиной в PlanError.
+func Analyze(ctx context.Context, src Source, opts Options) (*Report, error) {
+	if opts.OrderBy == "" {
+		opts.OrderBy = OrderTotalTime
+	}
+	if opts.TopN <= 0 {
+		opts.TopN = 10
+	}
+	less, ok := orders[opts.OrderBy]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный порядок ранжирования: %s", opts.OrderBy)
+	}
+
+	stats, err := src.StatStatements(ctx)
+	if err != nil {
+		return nil, err
+	}
+	version, err := src.ServerVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{OrderBy: opts.OrderBy, Statements: []Statement{}, Findings: []Finding{}, Indexes: []IndexRecommendation{}}
+	for _, s := range stats {
+		report.TotalTime += s.TotalTime
+	}
+
+	sort.SliceStable(stats, func(i, j int) bool { return less(stats[i], stats[j]) })
+	for _, s := range stats[:min(opts.TopN, len(stats))] {
+		statement := Statement{StatStatement: s, QueryFingerprint: fingerprint.Query(s.Query)}
+		if report.TotalTime > 0 {
+			statement.Share = s.TotalTime / report.TotalTime * 100
+		}
+		if err := explain(ctx, src, &statement, version, opts.Locale); err != nil {
+			statement.PlanError = err.Error()
+		}
+		report.Statements = append(report.Statements, statement)
+	}
+
+	aggregate(report)
+	if err := consolidate(ctx, src, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// orders сравнивает операторы для ранжирования по убыванию показателя
+var orders = map[string]func(a, b postgres.StatStatement) bool{
+	OrderTotalTime:      func(a, b postgres.StatStatement) bool { return a.TotalTime > b.TotalTime },
+	OrderMeanTime:       func(a, b postgres.StatStatement) bool { return a.MeanTime > b.MeanTime },
+	OrderCalls:          func(a, b postgres.StatStatement) bool { return a.Calls > b.Calls },
+	OrderSharedBlksRead: func(a, b postgres.StatStatement) bool { return a.SharedBlksRead > b.SharedBlksRead },
+}
+
+var paramPattern = regexp.MustCompile(`\$\d+`)
+
+// plannable — операторы, для которых EXPLAIN строит план
+var plannable = map[string]bool{
+	"select": true, "with": true, "insert": true, "update": true,
+	"delete": true, "merge": true, "values": true, "table": true,
+}
+
+// explain получает оценочный план оператора и анализирует его
+func explain(ctx context.Context, src Source, statement *Statement, version int, locale string) error {
+	words := strings.Fields(fingerprint.Normalize(statement.Query))
+	if len(words) == 0 || !plannable[words[0]] {
+		return fmt.Errorf("для оператора этого типа план не строится")
+	}
+
+	generic := paramPattern.MatchString(statement.Query)
+	if generic && version < genericPlanVersion {
+		return fmt.Errorf("для запросов с параметрами нужен PostgreSQL 16 и выше (EXPLAIN GENERIC_PLAN)")
+	}
+
+	planJSON, err := src.GetEstimatedPlan(ctx, statement.Query, generic)
+	if err != nil {
+		return err
+	}
+	plan, err := analyzer.ParseExplainJSON(planJSON)
+	if err != nil {
+		return err
+	}
+
+	result := analyzer.AnalyzeNodes(plan)
+	if locale != "" {
+		result.Localize(locale)
+	}
+	fingerprint.Apply(result, statement.Query, plan)
+	statement.Plan = plan
+	statement.Result = result
+	return nil
+}
+
+// aggregate сводит находки и индексы проанализированных операторов.
+// Вес находки — сумма долей времени операторов, в которых она встретилась,
+// поэтому редкий, но тяжелый запрос весит больше частого легкого.
+func aggregate(report *Report) {
+	findings := make(map[string]*Finding)
+	indexes := make(map[string]*IndexRecommendation)
+	var findingOrder, indexOrder []string
+
+	for _, s := range report.Statements {
+		if s.Result == nil {
+			continue
+		}
+		id := fmt.Sprint(s.QueryID)
+		seen := make(map[string]bool)
+		for _, problem := range s.Result.ProblematicOperations {
+			key := problem.RuleID + "/" + problem.Relation
+			if !seen[key] {
+				seen[key] = true
+				f, ok := findings[key]
+				if !ok {
+					f = &Finding{
+						RuleID:      problem.RuleID,
+						NodeType:    problem.NodeType,
+						Relation:    problem.Relation,
+						Severity:    problem.Severity,
+						Description: problem.Description,
+					}
+					findings[key] = f
+					findingOrder = append(findingOrder, key)
+				}
+				f.Weight += s.Share
+				f.Statements = append(f.Statements, id)
+			}
+
+			if problem.Index == nil || seen["index/"+problem.Index.Name()] {
+				continue
+			}
+			name := problem.Index.Name()
+			seen["index/"+name] = true
+			rec, ok := indexes[name]
+			if !ok {
+				rec = &IndexRecommendation{Index: *problem.Index, DDL: problem.Index.DDL()}
+				indexes[name] = rec
+				indexOrder = append(indexOrder, name)
+			}
+			rec.Weight += s.Share
+			rec.Statements = append(rec.Statements, id)
+		}
+	}
+
+	for _, key := range findingOrder {
+		report.Findings = append(report.Findings, *findings[key])
+	}
+	for _, name := range indexOrder {
+		report.Indexes = append(report.Indexes, *indexes[name])
+	}
+	sort.SliceStable(report.Findings, func(i, j int) bool { return report.Findings[i].Weight > report.Findings[j].Weight })
+	sort.SliceStable(report.Indexes, func(i, j int) bool { return report.Indexes[i].Weight > report.Indexes[j].Weight })
+}
+
+// consolidate сводит индексы нагрузки в минимальный набор движком рекомендаций
+func consolidate(ctx context.Context, src Source, report *Report) error {
+	input := recommendation.ConsolidationInput{Tables: make(map[string]recommendation.TableStats)}
+	for _, index := range report.Indexes {
+		// Объединять по префиксу ключа можно только обычные B-tree,
+		// специализированные индексы остаются в Indexes как есть
+		if !index.Index.Plain() {
+			continue
+		}
+		input.Candidates = append(input.Candidates, recommendation.IndexCandidate{
+			Table:   index.Index.Table,
+			Columns: index.Index.Columns,
+			Include: index.Index.Include,
+			Queries: index.Statements,
+			Weight:  index.Weight,
+		})
+	}
+
+	if catalog, ok := src.(catalogSource); ok && len(input.Candidates) > 0 {
+		indexes, err := catalog.Indexes(ctx)
+		if err != nil {
+			return err
+		}
+		for _, index := range indexes {
+			// Недействительный индекс не используется планировщиком
+			if !index.Valid {
+				continue
+			}
+			input.Existing = append(input.Existing, recommendation.ExistingIndex{
+				Name: index.Name, Table: index.Table, Columns: index.Columns, Include: index.Include,
+			})
+		}
+
+		tables, err := catalog.Tables(ctx)
+		if err != nil {
+			return err
+		}
+		for _, table := range tables {
+			input.Tables[table.Name] = recommendation.TableStats{
+				Rows:         table.Rows,
+				Writes:       table.Inserts + table.Updates - table.HotUpdates,
+				ColumnWidths: table.ColumnWidths,
+			}
+		}
+	}
+
+	report.Consolidated = recommendation.NewEngine().ConsolidateIndexes(input)
+	return nil
+}
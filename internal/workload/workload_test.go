@@ -0,0 +1,145 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"sql-optimizer/internal/postgres"
+)
+
+// fakeSource отдает заданную статистику и планы по тексту запроса
+type fakeSource struct {
+	version int
+	stats   []postgres.StatStatement
+	plans   map[string]string
+	generic []bool
+}
+
+func (f *fakeSource) StatStatements(ctx context.Context) ([]postgres.StatStatement, error) {
+	return f.stats, nil
+}
+
+func (f *fakeSource) ServerVersion(ctx context.Context) (int, error) {
+	return f.version, nil
+}
+
+func (f *fakeSource) GetEstimatedPlan(ctx context.Context, query string, generic bool) (string, error) {
+	f.generic = append(f.generic, generic)
+	plan, ok := f.plans[query]
+	if !ok {
+		return "", errors.New("нет плана")
+	}
+	return plan, nil
+}
+
+func TestAnalyze(t *testing.T) {
+	ordersScan := `[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "orders",
+		"Filter": "(user_id = $1)", "Total Cost": 1800, "Plan Rows": 10}}]`
+	src := &fakeSource{
+		version: 160002,
+		stats: []postgres.StatStatement{
+			{QueryID: 1, Query: "SELECT * FROM orders WHERE user_id = $1", Calls: 1000, TotalTime: 600, MeanTime: 0.6},
+			{QueryID: 2, Query: "SELECT count(*) FROM orders WHERE user_id = $1 AND paid", Calls: 10, TotalTime: 300, MeanTime: 30},
+			{QueryID: 3, Query: "VACUUM orders", Calls: 1, TotalTime: 100, MeanTime: 100, SharedBlksRead: 5000},
+		},
+		plans: map[string]string{
+			"SELECT * FROM orders WHERE user_id = $1":                 ordersScan,
+			"SELECT count(*) FROM orders WHERE user_id = $1 AND paid": ordersScan,
+		},
+	}
+
+	report, err := Analyze(context.Background(), src, Options{TopN: 3, Locale: "en"})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+
+	if report.TotalTime != 1000 || len(report.Statements) != 3 || report.Statements[0].QueryID != 1 {
+		t.Fatalf("Неверный рейтинг: %+v", report)
+	}
+	if math.Abs(report.Statements[1].Share-30) > 1e-9 {
+		t.Errorf("Ожидали долю 30%%, получили %.2f", report.Statements[1].Share)
+	}
+	if report.Statements[2].PlanError == "" || report.Statements[2].Result != nil {
+		t.Errorf("Для VACUUM план не строится: %+v", report.Statements[2])
+	}
+	for _, generic := range src.generic {
+		if !generic {
+			t.Error("Для текстов с $n нужен GENERIC_PLAN")
+		}
+	}
+
+	if len(report.Findings) != 1 || report.Findings[0].Relation != "orders" ||
+		math.Abs(report.Findings[0].Weight-90) > 1e-9 || len(report.Findings[0].Statements) != 2 {
+		t.Errorf("Неверные сводные находки: %+v", report.Findings)
+	}
+	if report.Findings[0].Description != "Sequential Scan on table orders" {
+		t.Errorf("Находки должны быть на запрошенном языке: %q", report.Findings[0].Description)
+	}
+	if len(report.Indexes) != 1 || report.Indexes[0].DDL != "CREATE INDEX CONCURRENTLY idx_orders_user_id ON orders (user_id);" {
+		t.Errorf("Неверные сводные индексы: %+v", report.Indexes)
+	}
+
+	// На PostgreSQL до 16 план с параметрами не строится, но оператор остается в рейтинге
+	src.version = 150000
+	report, err = Analyze(context.Background(), src, Options{OrderBy: OrderSharedBlksRead, TopN: 1})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if len(report.Statements) != 1 || report.Statements[0].QueryID != 3 {
+		t.Errorf("Ожидали первым оператор с наибольшим чтением блоков: %+v", report.Statements)
+	}
+
+	report, _ = Analyze(context.Background(), src, Options{})
+	if report.Statements[0].PlanError == "" || len(report.Findings) != 0 {
+		t.Errorf("На PostgreSQL 15 запросы с $n не должны анализироваться: %+v", report.Statements[0])
+	}
+
+	if _, err := Analyze(context.Background(), src, Options{OrderBy: "rows"}); err == nil {
+		t.Error("Ожидали ошибку для неизвестного порядка")
+	}
+}
+
+// catalogFake дополнительно отдает индексы и таблицы
+type catalogFake struct {
+	fakeSource
+	indexes []postgres.Index
+}
+
+func (f *catalogFake) Indexes(ctx context.Context) ([]postgres.Index, error) {
+	return f.indexes, nil
+}
+
+func (f *catalogFake) Tables(ctx context.Context) ([]postgres.Table, error) {
+	return []postgres.Table{{Name: "orders", Rows: 1000, Inserts: 300, Updates: 200, HotUpdates: 150}}, nil
+}
+
+func TestAnalyzeConsolidatesWithCatalog(t *testing.T) {
+	src := &catalogFake{fakeSource: fakeSource{
+		version: 160000,
+		stats:   []postgres.StatStatement{{QueryID: 1, Query: "SELECT * FROM orders WHERE user_id = $1", TotalTime: 10}},
+		plans: map[string]string{"SELECT * FROM orders WHERE user_id = $1": `[{"Plan": {"Node Type": "Seq Scan",
+			"Relation Name": "orders", "Filter": "(user_id = $1)", "Total Cost": 1800, "Plan Rows": 10}}]`},
+	}}
+
+	report, err := Analyze(context.Background(), src, Options{})
+	if err != nil {
+		t.Fatalf("Неожиданная ошибка: %v", err)
+	}
+	if len(report.Consolidated.Indexes) != 1 || report.Consolidated.Indexes[0].WriteOverhead != 350 {
+		t.Errorf("Ожидали индекс по orders с 350 записями в индекс: %+v", report.Consolidated)
+	}
+
+	// Недействительный индекс не покрывает предложение, действительный — покрывает
+	src.indexes = []postgres.Index{{Name: "orders_user_id_idx", Table: "orders", Columns: []string{"user_id"}}}
+	report, _ = Analyze(context.Background(), src, Options{})
+	if len(report.Consolidated.Indexes) != 1 {
+		t.Errorf("Недействительный индекс не должен покрывать предложение: %+v", report.Consolidated)
+	}
+	src.indexes[0].Valid = true
+	report, _ = Analyze(context.Background(), src, Options{})
+	if len(report.Consolidated.Indexes) != 0 || len(report.Consolidated.Covered) != 1 {
+		t.Errorf("Ожидали, что предложение покрыто orders_user_id_idx: %+v", report.Consolidated)
+	}
+}